@@ -1,9 +1,18 @@
 package keyring
 
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
 // ArrayKeyring is a mock/non-secure backend that meets the Keyring interface.
 // It is intended to be used to aid unit testing of code that relies on the package.
+// It keeps its own copy of each item's Data, so callers may wipe what they
+// store or are given. It is safe for concurrent use.
 // NOTE: Do not use in production code.
 type ArrayKeyring struct {
+	mu    sync.RWMutex
 	items map[string]Item
 }
 
@@ -19,29 +28,74 @@ func NewArrayKeyring(initial []Item) *ArrayKeyring {
 
 // Get returns an Item matching Key.
 func (k *ArrayKeyring) Get(key string) (Item, error) {
+	return k.GetContext(context.Background(), key)
+}
+
+// GetContext returns an Item matching Key.
+func (k *ArrayKeyring) GetContext(ctx context.Context, key string) (Item, error) {
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
 	if i, ok := k.items[key]; ok {
-		return i, nil
+		i.Data = bytes.Clone(i.Data)
+		return unexpired(i)
 	}
 	return Item{}, ErrKeyNotFound
 }
 
 // Set will store an item on the mock Keyring.
 func (k *ArrayKeyring) Set(i Item) error {
+	return k.SetContext(context.Background(), i)
+}
+
+// SetContext will store an item on the mock Keyring.
+func (k *ArrayKeyring) SetContext(ctx context.Context, i Item) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
 	if k.items == nil {
 		k.items = map[string]Item{}
 	}
+	i.Data = bytes.Clone(i.Data)
 	k.items[i.Key] = i
 	return nil
 }
 
 // Remove will delete an Item from the Keyring.
 func (k *ArrayKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext will delete an Item from the Keyring, or return ErrKeyNotFound.
+func (k *ArrayKeyring) RemoveContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.items[key]; !ok {
+		return ErrKeyNotFound
+	}
 	delete(k.items, key)
 	return nil
 }
 
 // Keys provides a slice of all Item keys on the Keyring.
 func (k *ArrayKeyring) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+// KeysContext provides a slice of all Item keys on the Keyring.
+func (k *ArrayKeyring) KeysContext(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
 	keys := make([]string, 0, len(k.items))
 	for key := range k.items {
 		keys = append(keys, key)
@@ -50,6 +104,14 @@ func (k *ArrayKeyring) Keys() ([]string, error) {
 }
 
 // GetMetadata returns the non-secret parts of an Item.
-func (k *ArrayKeyring) GetMetadata(_ string) (Metadata, error) {
+func (k *ArrayKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+// GetMetadataContext returns the non-secret parts of an Item.
+func (k *ArrayKeyring) GetMetadataContext(ctx context.Context, _ string) (Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
 	return Metadata{}, ErrMetadataNeedsCredentials
 }
@@ -1,6 +1,10 @@
 package keyring
 
-import "testing"
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
 
 func TestArrayKeyringSetWhenEmpty(t *testing.T) {
 	k := &ArrayKeyring{}
@@ -23,3 +27,35 @@ func TestArrayKeyringSetWhenEmpty(t *testing.T) {
 		t.Fatalf("Key wasn't persisted: %q", foundItem.Key)
 	}
 }
+
+func TestArrayKeyringConcurrentUse(t *testing.T) {
+	k := NewArrayKeyring(nil)
+
+	var wg sync.WaitGroup
+	for n := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("llama-%d", n)
+			for range 100 {
+				if err := k.Set(Item{Key: key, Data: []byte("llamas are great")}); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := k.Get(key); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := k.Keys(); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := k.Remove(key); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
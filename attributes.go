@@ -0,0 +1,84 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// AttributeFinder is implemented by keyrings that can search their items by
+// attribute without reading each one.
+type AttributeFinder interface {
+	// Returns the keys of the items whose attributes include all of attrs
+	FindByAttributesContext(ctx context.Context, attrs map[string]string) ([]string, error)
+}
+
+// FindByAttributes returns the sorted keys of the items in k whose Attributes
+// include every name and value in attrs. An empty attrs matches every item.
+func FindByAttributes(k Keyring, attrs map[string]string) ([]string, error) {
+	return FindByAttributesContext(context.Background(), k, attrs)
+}
+
+// FindByAttributesContext is FindByAttributes with a context. Keyrings that
+// implement AttributeFinder are searched natively. Others are searched item by
+// item, using GetMetadata where the backend supports it so that items need not
+// be unlocked, and Get otherwise.
+func FindByAttributesContext(ctx context.Context, k Keyring, attrs map[string]string) ([]string, error) {
+	if f, ok := k.(AttributeFinder); ok {
+		keys, err := f.FindByAttributesContext(ctx, attrs)
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(keys)
+		return keys, nil
+	}
+
+	ck := AsContextKeyring(k)
+	keys, err := ck.KeysContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []string{}
+	for _, key := range keys {
+		itemAttrs, err := itemAttributes(ctx, ck, key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue // removed since Keys
+		} else if err != nil {
+			return nil, err
+		}
+		if matchAttributes(itemAttrs, attrs) {
+			found = append(found, key)
+		}
+	}
+	slices.Sort(found)
+	return found, nil
+}
+
+// itemAttributes returns the attributes of the item with key, from its
+// metadata if the keyring returns any.
+func itemAttributes(ctx context.Context, k ContextKeyring, key string) (map[string]string, error) {
+	md, err := k.GetMetadataContext(ctx, key)
+	switch {
+	case err == nil && md.Item != nil:
+		return md.Attributes, nil
+	case err != nil && !errors.Is(err, ErrMetadataNotSupported) && !errors.Is(err, ErrMetadataNeedsCredentials):
+		return nil, err
+	}
+
+	item, err := k.GetContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return item.Attributes, nil
+}
+
+// matchAttributes reports whether have includes every name and value in want.
+func matchAttributes(have, want map[string]string) bool {
+	for name, value := range want {
+		if v, ok := have[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
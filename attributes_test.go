@@ -0,0 +1,39 @@
+package keyring
+
+import (
+	"slices"
+	"testing"
+)
+
+var attributeTestItems = []Item{
+	{Key: "a", Data: []byte("a"), Attributes: map[string]string{"env": "prod", "team": "ops"}},
+	{Key: "b", Data: []byte("b"), Attributes: map[string]string{"env": "dev", "team": "ops"}},
+	{Key: "c", Data: []byte("c")},
+}
+
+func TestFindByAttributes(t *testing.T) {
+	testFindByAttributes(t, NewArrayKeyring(attributeTestItems))
+}
+
+// testFindByAttributes checks FindByAttributes against a keyring holding
+// attributeTestItems.
+func testFindByAttributes(t *testing.T, k Keyring) {
+	t.Helper()
+	for _, tt := range []struct {
+		attrs map[string]string
+		want  []string
+	}{
+		{map[string]string{"team": "ops"}, []string{"a", "b"}},
+		{map[string]string{"team": "ops", "env": "prod"}, []string{"a"}},
+		{map[string]string{"env": "staging"}, []string{}},
+		{nil, []string{"a", "b", "c"}},
+	} {
+		got, err := FindByAttributes(k, tt.attrs)
+		if err != nil {
+			t.Fatalf("FindByAttributes(%v): %v", tt.attrs, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("FindByAttributes(%v) = %v, want %v", tt.attrs, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package keyring
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrAuditLogTampered is returned by VerifyAuditLog when the log has been
+// edited, reordered or had records removed, or was written with another key.
+var ErrAuditLogTampered = errors.New("the audit log has been tampered with")
+
+// Outcomes of an audited operation.
+const (
+	AuditOK       = "ok"
+	AuditNotFound = "not-found"
+	AuditError    = "error"
+)
+
+// AuditRecord is a single entry in an audit log. It says who did what to
+// which key and how it went, but never holds any secret data.
+type AuditRecord struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Op      Op        `json:"op"`
+	Key     string    `json:"key,omitempty"`
+	Prefix  string    `json:"prefix,omitempty"`
+	Backend string    `json:"backend,omitempty"`
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+	PID     int       `json:"pid"`
+	UID     int       `json:"uid"`
+
+	// MAC is the hex HMAC-SHA256 of the record and the previous record's MAC,
+	// chaining each record to all those before it.
+	MAC string `json:"mac"`
+}
+
+// auditMAC returns the MAC of r, following the record whose MAC is prev.
+func auditMAC(key []byte, prev string, r AuditRecord) (string, error) {
+	r.MAC = ""
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(prev))
+	h.Write([]byte{'\n'})
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AuditLog appends AuditRecords to a JSON Lines file. Each record carries an
+// HMAC over itself and the record before it, so that VerifyAuditLog can tell
+// if records were changed or removed. Several processes may write to the same
+// log: each append is made under a lock on the file, chaining onto whatever
+// record is last at the time.
+type AuditLog struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	key  []byte
+	seq  uint64
+	prev string
+	now  func() time.Time
+}
+
+// OpenAuditLog opens the audit log at path for appending, creating it if
+// needed. key is the HMAC key, which is needed again to verify the log and
+// should be kept apart from it.
+//
+// The last record already in the log is checked with key before the log is
+// carried on from it, and an error matching ErrAuditLogTampered is returned if
+// it does not check out, so that a forged record is never extended.
+func OpenAuditLog(path string, key []byte) (*AuditLog, error) {
+	if len(key) == 0 {
+		return nil, errors.New("an audit log needs a key")
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &AuditLog{path: path, f: f, key: append([]byte(nil), key...), now: time.Now}
+	release, err := lockDir(context.Background(), path, false, 0)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	defer release()
+	if err := l.resume(); err != nil {
+		f.Close()
+		zeroBytes(l.key)
+		return nil, fmt.Errorf("reading audit log %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// resume carries on the chain from the last record in the log, checking it
+// first. The caller holds the lock on the log.
+func (l *AuditLog) resume() error {
+	tail, n, err := readAuditTail(l.f)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		l.seq, l.prev = 0, ""
+		return nil
+	}
+	last := tail[n-1]
+
+	prev := ""
+	switch {
+	case n == 2 && tail[0].Seq+1 == last.Seq:
+		prev = tail[0].MAC
+	case n == 1 && last.Seq == 1:
+	default:
+		return fmt.Errorf("%w: its last record, %d, does not follow the one before it", ErrAuditLogTampered, last.Seq)
+	}
+	mac, err := auditMAC(l.key, prev, last)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(mac), []byte(last.MAC)) {
+		return fmt.Errorf("%w: the MAC of its last record, %d, does not match", ErrAuditLogTampered, last.Seq)
+	}
+	l.seq, l.prev = last.Seq, last.MAC
+	return nil
+}
+
+// readAuditTail returns the last two records in the log file f, oldest
+// first, and how many of them there are: fewer than two if the log is that
+// short.
+func readAuditTail(f *os.File) (tail [2]AuditRecord, n int, err error) {
+	info, err := f.Stat()
+	if err != nil {
+		return tail, 0, err
+	}
+
+	// Read back from the end until the last two lines are whole, which they
+	// are once the third newline from the end has been read.
+	var buf []byte
+	for off := info.Size(); off > 0 && bytes.Count(buf, []byte{'\n'}) < 3; {
+		chunk := min(off, 4096)
+		off -= chunk
+		b := make([]byte, chunk)
+		if _, err := f.ReadAt(b, off); err != nil {
+			return tail, 0, err
+		}
+		buf = append(b, buf...)
+	}
+
+	lines := bytes.Split(bytes.TrimRight(buf, "\n"), []byte{'\n'})
+	if len(lines) == 1 && len(lines[0]) == 0 {
+		return tail, 0, nil
+	}
+	lines = lines[max(len(lines)-2, 0):]
+	for i, line := range lines {
+		if err := json.Unmarshal(line, &tail[i]); err != nil {
+			return tail, 0, fmt.Errorf("%w: not a valid record: %w", ErrAuditLogTampered, err)
+		}
+	}
+	return tail, len(lines), nil
+}
+
+// Record appends r to the log, filling in its sequence number, MAC and, if
+// they are unset, its time, PID and UID.
+func (l *AuditLog) Record(r AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return os.ErrClosed
+	}
+
+	// Another process may have appended since, so chain onto the record that
+	// is last now, not the one this process knows of.
+	release, err := lockDir(context.Background(), l.path, true, 0)
+	if err != nil {
+		return fmt.Errorf("locking audit log: %w", err)
+	}
+	defer release()
+	if err := l.resume(); err != nil {
+		return fmt.Errorf("reading audit log: %w", err)
+	}
+
+	if r.Time.IsZero() {
+		r.Time = l.now().UTC()
+	}
+	if r.PID == 0 {
+		r.PID = os.Getpid()
+	}
+	if r.UID == 0 {
+		r.UID = os.Getuid()
+	}
+	r.Seq = l.seq + 1
+
+	mac, err := auditMAC(l.key, l.prev, r)
+	if err != nil {
+		return err
+	}
+	r.MAC = mac
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	l.seq, l.prev = r.Seq, r.MAC
+	return nil
+}
+
+// Close closes the log file.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	zeroBytes(l.key)
+	return err
+}
+
+// Audit returns middleware that records every operation in l, naming backend
+// as the keyring it went to. A batch is recorded as one record for each of
+// its keys, and a listing by prefix with the prefix. An operation whose
+// record cannot be written fails, so that nothing goes unrecorded; a write
+// may then have happened without being logged.
+func Audit(l *AuditLog, backend string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			err := next(ctx, call)
+			for _, r := range auditRecords(call, backend, err) {
+				if lerr := l.Record(r); lerr != nil {
+					return errors.Join(err, lerr)
+				}
+			}
+			return err
+		}
+	}
+}
+
+// auditRecords returns the records of call, which returned err: one for each
+// key of a batch, with the outcome for that key, or else one for the call.
+func auditRecords(call *Call, backend string, err error) []AuditRecord {
+	var keys []string
+	switch call.Op {
+	case OpGetMany, OpRemoveMany:
+		keys = uniqueKeys(call.Keys)
+	case OpSetMany:
+		for _, item := range lastByKey(call.Items) {
+			keys = append(keys, item.Key)
+		}
+	}
+	if len(keys) == 0 {
+		r := AuditRecord{Op: call.Op, Key: call.Key, Backend: backend}
+		if call.Op == OpKeysWithPrefix {
+			r.Prefix = call.Prefix
+		}
+		return []AuditRecord{auditOutcome(r, err)}
+	}
+
+	var batchErr *BatchError
+	perKey := errors.As(err, &batchErr)
+	records := make([]AuditRecord, 0, len(keys))
+	for _, key := range keys {
+		keyErr := err
+		if perKey {
+			keyErr = batchErr.Errors[key]
+		} else if _, ok := call.Found[key]; ok {
+			// Read before the batch as a whole failed.
+			keyErr = nil
+		}
+		records = append(records, auditOutcome(AuditRecord{Op: call.Op, Key: key, Backend: backend}, keyErr))
+	}
+	return records
+}
+
+// auditOutcome returns r with the outcome of an operation that returned err.
+func auditOutcome(r AuditRecord, err error) AuditRecord {
+	r.Outcome = AuditOK
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		r.Outcome = AuditNotFound
+	case err != nil:
+		r.Outcome = AuditError
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// AuditLogError is returned by VerifyAuditLog for the first record that does
+// not check out. errors.Is matches ErrAuditLogTampered.
+type AuditLogError struct {
+	// Line is the line number of the record, counting from 1.
+	Line   int
+	Reason string
+}
+
+func (e *AuditLogError) Error() string {
+	return fmt.Sprintf("audit log line %d: %s", e.Line, e.Reason)
+}
+
+func (e *AuditLogError) Unwrap() error {
+	return ErrAuditLogTampered
+}
+
+// AuditAnchor pins a record that is known to be in an audit log, such as
+// the last one when the log was last verified, so that VerifyAuditLogAnchored
+// can tell if the log has been cut short since.
+type AuditAnchor struct {
+	// Seq is the record's sequence number. If it is zero, the record is
+	// identified by MAC alone.
+	Seq uint64
+
+	// MAC is the record's MAC. If it is empty, only the log's length is
+	// checked.
+	MAC string
+}
+
+// VerifyAuditLog checks the hash chain of the audit log read from r with key,
+// and returns how many records it holds. Records that were edited, reordered
+// or removed, whether from the start or the middle, are detected. Records
+// removed from the end can only be detected against a record noted earlier;
+// see VerifyAuditLogAnchored.
+func VerifyAuditLog(r io.Reader, key []byte) (int, error) {
+	last, err := VerifyAuditLogAnchored(r, key, AuditAnchor{})
+	return int(last.Seq), err
+}
+
+// VerifyAuditLogAnchored is VerifyAuditLog, and also checks that the log
+// still holds the record pinned by anchor, so that records removed from its
+// end are detected too. It returns the anchor for the last record verified,
+// to be kept for the next check.
+func VerifyAuditLogAnchored(r io.Reader, key []byte, anchor AuditAnchor) (AuditAnchor, error) {
+	var last AuditAnchor
+	found := anchor == AuditAnchor{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return last, &AuditLogError{Line: line, Reason: "not a valid record: " + err.Error()}
+		}
+		if rec.Seq != uint64(line) {
+			return last, &AuditLogError{Line: line, Reason: fmt.Sprintf("sequence number %d, expected %d", rec.Seq, line)}
+		}
+		mac, err := auditMAC(key, last.MAC, rec)
+		if err != nil {
+			return last, err
+		}
+		if !hmac.Equal([]byte(mac), []byte(rec.MAC)) {
+			return last, &AuditLogError{Line: line, Reason: "MAC does not match"}
+		}
+		last = AuditAnchor{Seq: rec.Seq, MAC: rec.MAC}
+
+		switch {
+		case anchor.Seq == rec.Seq && anchor.MAC != "" && anchor.MAC != rec.MAC:
+			return last, &AuditLogError{Line: line, Reason: "MAC differs from the expected one"}
+		case anchor.Seq == rec.Seq, anchor.Seq == 0 && anchor.MAC == rec.MAC:
+			found = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return last, err
+	}
+	if !found {
+		if anchor.Seq != 0 {
+			return last, &AuditLogError{Line: int(last.Seq) + 1, Reason: fmt.Sprintf("the log ends before the expected record %d", anchor.Seq)}
+		}
+		return last, &AuditLogError{Line: int(last.Seq) + 1, Reason: "the log ends before the expected record"}
+	}
+	return last, nil
+}
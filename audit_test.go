@@ -0,0 +1,230 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	key := []byte("audit key")
+
+	l, err := OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := Wrap(NewArrayKeyring(nil), Audit(l, "array"))
+	if err := k.Set(Item{Key: "a", Data: []byte("hunter2")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Get("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening carries on the chain.
+	l, err = OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k = Wrap(NewArrayKeyring(nil), Audit(l, "array"))
+	if _, err := k.Keys(); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Fatal("The audit log contains secret data")
+	}
+	if !bytes.Contains(data, []byte(`"outcome":"not-found"`)) {
+		t.Fatalf("The failed Get was not recorded:\n%s", data)
+	}
+	if n, err := VerifyAuditLog(bytes.NewReader(data), key); err != nil || n != 4 {
+		t.Fatalf("Expected 4 verified records, got %d, %v", n, err)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	for name, tampered := range map[string]string{
+		"edited":          strings.Replace(string(data), `"key":"missing"`, `"key":"other"`, 1),
+		"middle removed":  lines[0] + strings.Join(lines[2:], ""),
+		"start removed":   strings.Join(lines[1:], ""),
+		"records swapped": lines[1] + lines[0] + strings.Join(lines[2:], ""),
+	} {
+		if _, err := VerifyAuditLog(strings.NewReader(tampered), key); !errors.Is(err, ErrAuditLogTampered) {
+			t.Errorf("%s: expected ErrAuditLogTampered, got %v", name, err)
+		}
+	}
+	if _, err := VerifyAuditLog(bytes.NewReader(data), []byte("other key")); !errors.Is(err, ErrAuditLogTampered) {
+		t.Errorf("Wrong key: expected ErrAuditLogTampered, got %v", err)
+	}
+}
+
+func TestAuditLogSharedByWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	key := []byte("audit key")
+
+	// Two logs open on the same file stand in for two processes.
+	first, err := OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	for i, l := range []*AuditLog{first, second, first, second} {
+		if err := l.Record(AuditRecord{Op: OpGet, Key: "a", Outcome: AuditOK}); err != nil {
+			t.Fatalf("Record %d: %v", i+1, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := VerifyAuditLog(bytes.NewReader(data), key); err != nil || n != 4 {
+		t.Fatalf("Expected 4 verified records, got %d, %v", n, err)
+	}
+}
+
+func TestAuditLogTamperedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	key := []byte("audit key")
+
+	l, err := OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, k := range []string{"a", "b"} {
+		if err := l.Record(AuditRecord{Op: OpGet, Key: k, Outcome: AuditOK}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(data), `"key":"b"`, `"key":"c"`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenAuditLog(path, key); !errors.Is(err, ErrAuditLogTampered) {
+		t.Fatalf("OpenAuditLog: expected ErrAuditLogTampered, got %v", err)
+	}
+	if err := l.Record(AuditRecord{Op: OpGet, Key: "d", Outcome: AuditOK}); !errors.Is(err, ErrAuditLogTampered) {
+		t.Fatalf("Record: expected ErrAuditLogTampered, got %v", err)
+	}
+}
+
+func TestVerifyAuditLogAnchored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	key := []byte("audit key")
+
+	l, err := OpenAuditLog(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		if err := l.Record(AuditRecord{Op: OpGet, Key: k, Outcome: AuditOK}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := VerifyAuditLogAnchored(bytes.NewReader(data), key, AuditAnchor{})
+	if err != nil || last.Seq != 3 || last.MAC == "" {
+		t.Fatalf("Expected the anchor of record 3, got %+v, %v", last, err)
+	}
+	for _, anchor := range []AuditAnchor{last, {Seq: 3}, {MAC: last.MAC}, {Seq: 2}} {
+		if _, err := VerifyAuditLogAnchored(bytes.NewReader(data), key, anchor); err != nil {
+			t.Errorf("Anchor %+v: %v", anchor, err)
+		}
+	}
+
+	// Removing the last record leaves a valid chain, which only the anchor
+	// shows to be short.
+	lines := strings.SplitAfter(string(data), "\n")
+	truncated := strings.Join(lines[:2], "")
+	if n, err := VerifyAuditLog(strings.NewReader(truncated), key); err != nil || n != 2 {
+		t.Fatalf("Expected 2 verified records, got %d, %v", n, err)
+	}
+	for _, anchor := range []AuditAnchor{last, {Seq: 3}, {MAC: last.MAC}} {
+		if _, err := VerifyAuditLogAnchored(strings.NewReader(truncated), key, anchor); !errors.Is(err, ErrAuditLogTampered) {
+			t.Errorf("Anchor %+v on the truncated log: expected ErrAuditLogTampered, got %v", anchor, err)
+		}
+	}
+	if _, err := VerifyAuditLogAnchored(bytes.NewReader(data), key, AuditAnchor{Seq: 2, MAC: last.MAC}); !errors.Is(err, ErrAuditLogTampered) {
+		t.Errorf("Mismatched anchor: expected ErrAuditLogTampered, got %v", err)
+	}
+}
+
+func TestAuditBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := OpenAuditLog(path, []byte("audit key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := Wrap(NewArrayKeyring(nil), Audit(l, "array"))
+
+	if err := SetMany(k, []Item{{Key: "a", Data: []byte("a")}, {Key: "b", Data: []byte("b")}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetMany(k, []string{"a", "missing"}); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if _, err := KeysWithPrefix(k, "team/"); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []string
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var r AuditRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %s%s %s", r.Op, r.Key, r.Prefix, r.Outcome))
+	}
+	want := []string{
+		"set-many a ok",
+		"set-many b ok",
+		"get-many a ok",
+		"get-many missing not-found",
+		"keys-with-prefix team/ ok",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Expected records %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,180 @@
+package keyring
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// BatchKeyring is implemented by keyrings that can read or change several
+// items in a single pass over the backend, such as one listing of a remote
+// vault, rather than one pass per item.
+type BatchKeyring interface {
+	// Returns the items found among keys, and a *BatchError for the rest
+	GetManyContext(ctx context.Context, keys []string) (map[string]Item, error)
+	// Stores each of items, returning a *BatchError for those that failed
+	SetManyContext(ctx context.Context, items []Item) error
+	// Removes the items for keys, returning a *BatchError for those that failed
+	RemoveManyContext(ctx context.Context, keys []string) error
+}
+
+// BatchError is returned by the batch operations when some of the keys
+// failed. The others succeeded. errors.Is matches any of the individual
+// errors, so errors.Is(err, ErrKeyNotFound) reports whether any key was
+// missing.
+type BatchError struct {
+	// Errors maps each key that failed to its error.
+	Errors map[string]error
+}
+
+func (e *BatchError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d keys failed", len(keys))
+	for i, key := range keys {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s: %v", key, e.Errors[key])
+	}
+	return b.String()
+}
+
+// Unwrap returns each key's error.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// batchError returns a *BatchError for errs, or nil if it is empty.
+func batchError(errs map[string]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &BatchError{Errors: errs}
+}
+
+// lastByKey returns items with only the last of any items sharing a key, in
+// the order of their first appearance, so a batch applies each key once.
+func lastByKey(items []Item) []Item {
+	index := make(map[string]int, len(items))
+	out := make([]Item, 0, len(items))
+	for _, item := range items {
+		if i, ok := index[item.Key]; ok {
+			out[i] = item
+			continue
+		}
+		index[item.Key] = len(out)
+		out = append(out, item)
+	}
+	return out
+}
+
+// uniqueKeys returns keys without repeats, in the order of their first
+// appearance.
+func uniqueKeys(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	out := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// GetMany returns the items for keys in k. See GetManyContext.
+func GetMany(k Keyring, keys []string) (map[string]Item, error) {
+	return GetManyContext(context.Background(), k, keys)
+}
+
+// GetManyContext returns the items for keys in k, mapped by key. Keys that
+// could not be read are left out of the map and reported in a *BatchError;
+// missing ones with ErrKeyNotFound. Any other error means the batch failed as
+// a whole. Keyrings that implement BatchKeyring read the items in one pass;
+// others are read one key at a time.
+func GetManyContext(ctx context.Context, k Keyring, keys []string) (map[string]Item, error) {
+	if bk, ok := k.(BatchKeyring); ok {
+		return bk.GetManyContext(ctx, keys)
+	}
+
+	ck := AsContextKeyring(k)
+	items := make(map[string]Item, len(keys))
+	errs := map[string]error{}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+		item, err := ck.GetContext(ctx, key)
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+		items[key] = item
+	}
+	return items, batchError(errs)
+}
+
+// SetMany stores items in k. See SetManyContext.
+func SetMany(k Keyring, items []Item) error {
+	return SetManyContext(context.Background(), k, items)
+}
+
+// SetManyContext stores each of items in k. Items that could not be stored are
+// reported in a *BatchError; any other error means the batch stopped part way.
+// If several items share a key, the last of them is stored.
+func SetManyContext(ctx context.Context, k Keyring, items []Item) error {
+	if bk, ok := k.(BatchKeyring); ok {
+		return bk.SetManyContext(ctx, items)
+	}
+
+	ck := AsContextKeyring(k)
+	errs := map[string]error{}
+	for _, item := range lastByKey(items) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := ck.SetContext(ctx, item); err != nil {
+			errs[item.Key] = err
+		}
+	}
+	return batchError(errs)
+}
+
+// RemoveMany removes the items for keys from k. See RemoveManyContext.
+func RemoveMany(k Keyring, keys []string) error {
+	return RemoveManyContext(context.Background(), k, keys)
+}
+
+// RemoveManyContext removes the items for keys from k. Keys that could not be
+// removed are reported in a *BatchError, as Remove reports them; any other
+// error means the batch stopped part way.
+func RemoveManyContext(ctx context.Context, k Keyring, keys []string) error {
+	if bk, ok := k.(BatchKeyring); ok {
+		return bk.RemoveManyContext(ctx, keys)
+	}
+
+	ck := AsContextKeyring(k)
+	errs := map[string]error{}
+	for _, key := range uniqueKeys(keys) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := ck.RemoveContext(ctx, key); err != nil {
+			errs[key] = err
+		}
+	}
+	return batchError(errs)
+}
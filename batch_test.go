@@ -0,0 +1,91 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestBatchFallback(t *testing.T) {
+	k := NewArrayKeyring(nil)
+
+	err := SetMany(k, []Item{
+		{Key: "a", Data: []byte("first")},
+		{Key: "b", Data: []byte("b")},
+		{Key: "a", Data: []byte("last")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := GetMany(k, []string{"a", "b", "missing"})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors["missing"], ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound for the missing key only, got %v", err)
+	}
+	if len(items) != 2 || string(items["a"].Data) != "last" || string(items["b"].Data) != "b" {
+		t.Fatalf("Unexpected items %+v", items)
+	}
+
+	if err := RemoveMany(k, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := k.Keys(); len(keys) != 0 {
+		t.Fatalf("Expected no keys left, got %v", keys)
+	}
+}
+
+// nativeBatchKeyring counts the batches that reach it, which it applies to
+// an ArrayKeyring.
+type nativeBatchKeyring struct {
+	*ArrayKeyring
+	batches int
+}
+
+func (k *nativeBatchKeyring) GetManyContext(ctx context.Context, keys []string) (map[string]Item, error) {
+	k.batches++
+	return GetManyContext(ctx, k.ArrayKeyring, keys)
+}
+
+func (k *nativeBatchKeyring) SetManyContext(ctx context.Context, items []Item) error {
+	k.batches++
+	return SetManyContext(ctx, k.ArrayKeyring, items)
+}
+
+func (k *nativeBatchKeyring) RemoveManyContext(ctx context.Context, keys []string) error {
+	k.batches++
+	return RemoveManyContext(ctx, k.ArrayKeyring, keys)
+}
+
+func TestOpenWithLoggerKeepsBatches(t *testing.T) {
+	const backend = BackendType("native-batch")
+	inner := &nativeBatchKeyring{ArrayKeyring: NewArrayKeyring(nil)}
+	supportedBackends[backend] = func(Config) (Keyring, error) { return inner, nil }
+	t.Cleanup(func() { delete(supportedBackends, backend) })
+
+	k, err := Open(Config{
+		AllowedBackends: []BackendType{backend},
+		Logger:          slog.New(slog.DiscardHandler),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != Keyring(inner) {
+		t.Fatalf("Expected Open to return the backend itself, got %T", k)
+	}
+
+	if err := SetMany(k, []Item{{Key: "a", Data: []byte("a")}, {Key: "b", Data: []byte("b")}}); err != nil {
+		t.Fatal(err)
+	}
+	items, err := GetMany(k, []string{"a", "b"})
+	if err != nil || len(items) != 2 || string(items["b"].Data) != "b" {
+		t.Fatalf("GetMany = %+v, %v", items, err)
+	}
+	if err := RemoveMany(k, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if inner.batches != 3 {
+		t.Fatalf("Expected 3 batches to reach the backend, got %d", inner.batches)
+	}
+}
@@ -0,0 +1,400 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long a CachingKeyring keeps items when
+// CacheOptions.TTL is zero.
+const DefaultCacheTTL = 5 * time.Minute
+
+// CacheOptions configure a CachingKeyring.
+type CacheOptions struct {
+	// TTL is how long an item read from the wrapped keyring is cached. Zero
+	// means DefaultCacheTTL.
+	TTL time.Duration
+
+	// NegativeTTL is how long a Get that found no item is remembered, so
+	// that repeated lookups of a missing key are not passed on. Zero
+	// disables caching misses.
+	NegativeTTL time.Duration
+}
+
+// CacheStats counts the Gets a CachingKeyring has answered.
+type CacheStats struct {
+	// Hits are Gets answered from the cache, including cached misses.
+	Hits uint64
+	// Misses are Gets passed to the wrapped keyring.
+	Misses uint64
+}
+
+// CachingKeyring is a Keyring that keeps the items it reads from another
+// keyring in memory, so that repeated Gets of the same key do not go back to
+// a slow backend. Set and Remove are passed on and drop the cached item.
+//
+// Cached item data is kept in a SecretBytes, locked against swapping where
+// the system allows it, and is zeroed when it is evicted and on Close. Get
+// returns a copy of it, so callers may keep or clear what they are given.
+type CachingKeyring struct {
+	inner ContextKeyring
+	ttl   time.Duration
+	nttl  time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	stats   CacheStats
+	// gen is incremented by every change to the wrapped keyring, so that
+	// an item read before the change is not cached after it.
+	gen uint64
+}
+
+// cacheEntry is a cached item, or a cached miss if found is false.
+type cacheEntry struct {
+	// item is the item without its Data, which is kept in data.
+	item    Item
+	data    *SecretBytes
+	found   bool
+	expires time.Time
+}
+
+// newCacheEntry returns an entry caching a copy of item until expires.
+func newCacheEntry(item Item, expires time.Time) cacheEntry {
+	data := bytes.Clone(item.Data)
+	item.Data = nil
+	return cacheEntry{item: item, data: NewSecretBytes(data), found: true, expires: expires}
+}
+
+// cached returns the cached item with its own copy of Data.
+func (e cacheEntry) cached() Item {
+	item := e.item
+	item.Data = bytes.Clone(e.data.Bytes())
+	return item
+}
+
+// wipe zeroes the cached data.
+func (e cacheEntry) wipe() {
+	if e.data != nil {
+		e.data.Wipe()
+	}
+}
+
+// NewCachingKeyring returns a CachingKeyring over inner.
+func NewCachingKeyring(inner Keyring, opts CacheOptions) *CachingKeyring {
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &CachingKeyring{
+		inner:   AsContextKeyring(inner),
+		ttl:     ttl,
+		nttl:    opts.NegativeTTL,
+		now:     time.Now,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// Stats returns the hit and miss counts so far.
+func (c *CachingKeyring) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Get returns the item for key from the cache, or from the wrapped keyring.
+func (c *CachingKeyring) Get(key string) (Item, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+// GetContext returns the item for key from the cache, or from the wrapped
+// keyring.
+func (c *CachingKeyring) GetContext(ctx context.Context, key string) (Item, error) {
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
+	c.mu.Lock()
+	now := c.now()
+	if e, ok := c.entries[key]; ok {
+		if now.Before(e.expires) {
+			c.stats.Hits++
+			if !e.found {
+				c.mu.Unlock()
+				return Item{}, ErrKeyNotFound
+			}
+			item := e.cached()
+			c.mu.Unlock()
+			return item, nil
+		}
+		c.evict(key)
+	}
+	c.stats.Misses++
+	gen := c.gen
+	c.mu.Unlock()
+
+	item, err := c.inner.GetContext(ctx, key)
+	c.cache(gen, now, key, item, err)
+	return item, err
+}
+
+// GetManyContext returns the cached items among keys, and reads the rest from
+// the wrapped keyring in one batch if it can.
+func (c *CachingKeyring) GetManyContext(ctx context.Context, keys []string) (map[string]Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]Item, len(keys))
+	errs := map[string]error{}
+	var missing []string
+	c.mu.Lock()
+	now := c.now()
+	for _, key := range uniqueKeys(keys) {
+		if e, ok := c.entries[key]; ok {
+			if now.Before(e.expires) {
+				c.stats.Hits++
+				if e.found {
+					items[key] = e.cached()
+				} else {
+					errs[key] = ErrKeyNotFound
+				}
+				continue
+			}
+			c.evict(key)
+		}
+		c.stats.Misses++
+		missing = append(missing, key)
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return items, batchError(errs)
+	}
+	found, err := GetManyContext(ctx, c.inner, missing)
+	for key, item := range found {
+		items[key] = item
+		c.cache(gen, now, key, item, nil)
+	}
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		if err != nil {
+			return items, err
+		}
+		return items, batchError(errs)
+	}
+	for key, keyErr := range batchErr.Errors {
+		errs[key] = keyErr
+		c.cache(gen, now, key, Item{}, keyErr)
+	}
+	return items, batchError(errs)
+}
+
+// cache caches the result of reading key at now, unless the wrapped keyring
+// has changed since gen.
+func (c *CachingKeyring) cache(gen uint64, now time.Time, key string, item Item, err error) {
+	switch {
+	case err == nil:
+		expires := now.Add(c.ttl)
+		if !item.Expires.IsZero() && item.Expires.Before(expires) {
+			expires = item.Expires
+		}
+		c.store(gen, key, newCacheEntry(item, expires))
+	case errors.Is(err, ErrKeyNotFound) && c.nttl > 0:
+		c.store(gen, key, cacheEntry{expires: now.Add(c.nttl)})
+	}
+}
+
+// store caches e for key, unless the wrapped keyring has changed since gen.
+func (c *CachingKeyring) store(gen uint64, key string, e cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		e.wipe()
+		return
+	}
+	c.evict(key)
+	c.evictExpired()
+	c.entries[key] = e
+}
+
+// evict drops the entry for key, zeroing its data. c.mu must be held.
+func (c *CachingKeyring) evict(key string) {
+	if e, ok := c.entries[key]; ok {
+		e.wipe()
+		delete(c.entries, key)
+	}
+}
+
+// evictExpired drops every expired entry. c.mu must be held.
+func (c *CachingKeyring) evictExpired() {
+	now := c.now()
+	for key, e := range c.entries {
+		if !now.Before(e.expires) {
+			c.evict(key)
+		}
+	}
+}
+
+// invalidate drops the entries for keys, and stops any Get in progress from
+// caching what it read. It is called both before and after a change to the
+// wrapped keyring, as a Get may start while the change is being made.
+func (c *CachingKeyring) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, key := range keys {
+		c.evict(key)
+	}
+}
+
+// GetMetadata returns the metadata of the item from the wrapped keyring.
+// Metadata is not cached.
+func (c *CachingKeyring) GetMetadata(key string) (Metadata, error) {
+	return c.GetMetadataContext(context.Background(), key)
+}
+
+func (c *CachingKeyring) GetMetadataContext(ctx context.Context, key string) (Metadata, error) {
+	return c.inner.GetMetadataContext(ctx, key)
+}
+
+// Set stores item in the wrapped keyring. The next Get reads it back from
+// there.
+func (c *CachingKeyring) Set(item Item) error {
+	return c.SetContext(context.Background(), item)
+}
+
+func (c *CachingKeyring) SetContext(ctx context.Context, item Item) error {
+	c.invalidate(item.Key)
+	defer c.invalidate(item.Key)
+	return c.inner.SetContext(ctx, item)
+}
+
+// SetManyContext stores items in the wrapped keyring, in one batch if it can.
+func (c *CachingKeyring) SetManyContext(ctx context.Context, items []Item) error {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item.Key
+	}
+	c.invalidate(keys...)
+	defer c.invalidate(keys...)
+	return SetManyContext(ctx, c.inner, items)
+}
+
+// GetVersionedContext reads the item and its version from the wrapped
+// keyring. A version is only useful if it is current, so it is never cached.
+func (c *CachingKeyring) GetVersionedContext(ctx context.Context, key string) (Item, string, error) {
+	return GetVersionedContext(ctx, c.inner, key)
+}
+
+func (c *CachingKeyring) SetIfAbsentContext(ctx context.Context, item Item) error {
+	c.invalidate(item.Key)
+	defer c.invalidate(item.Key)
+	return SetIfAbsentContext(ctx, c.inner, item)
+}
+
+func (c *CachingKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) error {
+	c.invalidate(key)
+	defer c.invalidate(key)
+	return CompareAndSwapContext(ctx, c.inner, key, expectedVersion, item)
+}
+
+// HistoryContext lists the earlier versions of the item in the wrapped
+// keyring. Earlier versions are not cached.
+func (c *CachingKeyring) HistoryContext(ctx context.Context, key string) ([]Revision, error) {
+	return HistoryContext(ctx, c.inner, key)
+}
+
+func (c *CachingKeyring) RestoreContext(ctx context.Context, key, version string) error {
+	c.invalidate(key)
+	defer c.invalidate(key)
+	return RestoreContext(ctx, c.inner, key, version)
+}
+
+// SetMetadata replaces the metadata fields of the item in the wrapped
+// keyring, or returns ErrMetadataNotSupported if it cannot store them.
+func (c *CachingKeyring) SetMetadata(key string, fields map[string]string) error {
+	return c.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (c *CachingKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) error {
+	mk, ok := c.inner.(MetadataKeyring)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return mk.SetMetadataContext(ctx, key, fields)
+}
+
+// FindByAttributesContext searches the wrapped keyring, natively if it can.
+func (c *CachingKeyring) FindByAttributesContext(ctx context.Context, attrs map[string]string) ([]string, error) {
+	return FindByAttributesContext(ctx, c.inner, attrs)
+}
+
+// Watch watches the wrapped keyring, dropping each changed item from the
+// cache before reporting the change, so reading it again gets the new one.
+func (c *CachingKeyring) Watch(ctx context.Context) (<-chan Event, error) {
+	inner, err := Watch(ctx, c.inner)
+	if err != nil {
+		return nil, err
+	}
+	return mapEvents(ctx, inner, func(ev Event) (Event, bool) {
+		c.invalidate(ev.Key)
+		return ev, true
+	}), nil
+}
+
+// Remove removes the item from the wrapped keyring and the cache.
+func (c *CachingKeyring) Remove(key string) error {
+	return c.RemoveContext(context.Background(), key)
+}
+
+func (c *CachingKeyring) RemoveContext(ctx context.Context, key string) error {
+	c.invalidate(key)
+	defer c.invalidate(key)
+	return c.inner.RemoveContext(ctx, key)
+}
+
+// RemoveManyContext removes the items for keys from the wrapped keyring, in
+// one batch if it can, and from the cache.
+func (c *CachingKeyring) RemoveManyContext(ctx context.Context, keys []string) error {
+	c.invalidate(keys...)
+	defer c.invalidate(keys...)
+	return RemoveManyContext(ctx, c.inner, keys)
+}
+
+// Keys lists the keys in the wrapped keyring. The list is not cached.
+func (c *CachingKeyring) Keys() ([]string, error) {
+	return c.KeysContext(context.Background())
+}
+
+func (c *CachingKeyring) KeysContext(ctx context.Context) ([]string, error) {
+	return c.inner.KeysContext(ctx)
+}
+
+// KeysWithPrefixContext lists the keys in the wrapped keyring starting with
+// prefix, natively if it can. The list is not cached.
+func (c *CachingKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) ([]string, error) {
+	return KeysWithPrefixContext(ctx, c.inner, prefix)
+}
+
+// Flush drops every cached entry, zeroing the cached data. The cache can
+// still be used afterwards.
+func (c *CachingKeyring) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for key := range c.entries {
+		c.evict(key)
+	}
+}
+
+// Close zeroes and drops every cached entry. It does not close the wrapped
+// keyring.
+func (c *CachingKeyring) Close() error {
+	c.Flush()
+	return nil
+}
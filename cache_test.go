@@ -0,0 +1,146 @@
+package keyring
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingKeyring counts the Gets that reach the wrapped keyring.
+type countingKeyring struct {
+	Keyring
+	gets int
+}
+
+func (k *countingKeyring) Get(key string) (Item, error) {
+	k.gets++
+	return k.Keyring.Get(key)
+}
+
+func TestCachingKeyring(t *testing.T) {
+	inner := &countingKeyring{Keyring: NewArrayKeyring([]Item{{Key: "llamas", Data: []byte("llamas are great")}})}
+	c := NewCachingKeyring(inner, CacheOptions{TTL: time.Minute, NegativeTTL: time.Minute})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for range 3 {
+		item, err := c.Get("llamas")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(item.Data) != "llamas are great" {
+			t.Fatalf("Unexpected data %q", item.Data)
+		}
+		// Callers may clear what they are given without affecting the cache.
+		zeroBytes(item.Data)
+	}
+	for range 2 {
+		if _, err := c.Get("alpacas"); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+		}
+	}
+	if inner.gets != 2 {
+		t.Fatalf("Expected 2 Gets of the wrapped keyring, got %d", inner.gets)
+	}
+	if stats := c.Stats(); stats != (CacheStats{Hits: 3, Misses: 2}) {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Set drops the cached miss.
+	if err := c.Set(Item{Key: "alpacas", Data: []byte("alpacas too")}); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := c.Get("alpacas"); err != nil || string(item.Data) != "alpacas too" {
+		t.Fatalf("Get after Set = %q, %v", item.Data, err)
+	}
+
+	// Entries expire after the TTL.
+	now = now.Add(2 * time.Minute)
+	gets := inner.gets
+	if _, err := c.Get("llamas"); err != nil {
+		t.Fatal(err)
+	}
+	if inner.gets != gets+1 {
+		t.Fatal("Expected an expired entry to be read again")
+	}
+
+	// Remove drops the cached item.
+	if err := c.Remove("llamas"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("llamas"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound after Remove, got %v", err)
+	}
+}
+
+func TestCachingKeyringZeroesOnClose(t *testing.T) {
+	c := NewCachingKeyring(NewArrayKeyring([]Item{{Key: "llamas", Data: []byte("llamas are great")}}), CacheOptions{})
+	if _, err := c.Get("llamas"); err != nil {
+		t.Fatal(err)
+	}
+	cached := c.entries["llamas"].data.Bytes()
+	if string(cached) != "llamas are great" {
+		t.Fatalf("Unexpected cached data %q", cached)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.entries) != 0 {
+		t.Fatalf("Expected no entries after Close, got %d", len(c.entries))
+	}
+	for _, b := range cached {
+		if b != 0 {
+			t.Fatalf("Cached data was not zeroed: %q", cached)
+		}
+	}
+}
+
+func TestCachingKeyringItemExpires(t *testing.T) {
+	now := time.Now()
+	c := NewCachingKeyring(NewArrayKeyring([]Item{
+		{Key: "session", Data: []byte("token"), Expires: now.Add(time.Minute)},
+	}), CacheOptions{TTL: time.Hour})
+	c.now = func() time.Time { return now }
+
+	if _, err := c.Get("session"); err != nil {
+		t.Fatal(err)
+	}
+	if e := c.entries["session"]; !e.expires.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Expected the entry to expire with the item, at %v, not %v", now.Add(time.Minute), e.expires)
+	}
+}
+
+func TestCachingKeyringBatch(t *testing.T) {
+	inner := &nativeBatchKeyring{ArrayKeyring: NewArrayKeyring([]Item{{Key: "a", Data: []byte("a")}})}
+	c := NewCachingKeyring(inner, CacheOptions{TTL: time.Minute})
+
+	if _, err := c.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetManyContext(t.Context(), []Item{{Key: "a", Data: []byte("new a")}, {Key: "b", Data: []byte("b")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The batch dropped the cached a, and reads only what is not cached.
+	items, err := c.GetManyContext(t.Context(), []string{"a", "b"})
+	if err != nil || string(items["a"].Data) != "new a" || string(items["b"].Data) != "b" {
+		t.Fatalf("GetMany = %+v, %v", items, err)
+	}
+	if _, err := c.GetManyContext(t.Context(), []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if stats := c.Stats(); stats != (CacheStats{Hits: 2, Misses: 3}) {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	if err := c.RemoveManyContext(t.Context(), []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound after RemoveMany, got %v", err)
+	}
+	if inner.batches != 3 {
+		t.Fatalf("Expected 3 batches to reach the wrapped keyring, got %d", inner.batches)
+	}
+}
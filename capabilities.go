@@ -0,0 +1,86 @@
+package keyring
+
+// MetadataSupport describes what a backend's GetMetadata returns.
+type MetadataSupport int
+
+const (
+	// MetadataNotSupported means GetMetadata returns ErrMetadataNotSupported.
+	MetadataNotSupported MetadataSupport = iota
+	// MetadataNeedsCredentials means GetMetadata returns ErrMetadataNeedsCredentials.
+	MetadataNeedsCredentials
+	// MetadataEmpty means GetMetadata succeeds but the Metadata carries no
+	// information.
+	MetadataEmpty
+	// MetadataSupported means GetMetadata returns the item's non-secret
+	// fields, timestamps and metadata fields without unlocking the item.
+	// Backends whose whole store can be locked, like the Secret Service, may
+	// still return ErrMetadataNeedsCredentials while it is.
+	MetadataSupported
+)
+
+func (m MetadataSupport) String() string {
+	switch m {
+	case MetadataNotSupported:
+		return "not supported"
+	case MetadataNeedsCredentials:
+		return "needs credentials"
+	case MetadataEmpty:
+		return "empty"
+	case MetadataSupported:
+		return "supported"
+	default:
+		return "unknown"
+	}
+}
+
+// Capabilities describes what a backend does with the items it stores, so
+// callers can choose a backend, or warn users, before anything is lost.
+type Capabilities struct {
+	// Metadata is what GetMetadata returns.
+	Metadata MetadataSupport
+
+	// Label and Description report whether Item.Label and Item.Description
+	// are stored and returned by Get, rather than silently dropped.
+	Label       bool
+	Description bool
+
+	// KeychainFlags reports whether Item.KeychainNotTrustApplication and
+	// Item.KeychainNotSynchronizable have any effect.
+	KeychainFlags bool
+
+	// MaxItemSize is the largest Item.Data the backend accepts, in bytes.
+	// Zero means there is no practical limit.
+	MaxItemSize int
+
+	// ListRequiresUnlock reports whether Keys needs the keyring to be
+	// unlocked or the user to authenticate.
+	ListRequiresUnlock bool
+
+	// Interactive reports whether the backend may prompt the user, e.g. for
+	// a passphrase or biometrics.
+	Interactive bool
+
+	// Remote reports whether items are stored on a network service rather
+	// than on the local machine.
+	Remote bool
+
+	// Expiry reports whether the backend can expire items by itself.
+	Expiry bool
+
+	// PlaintextMetadata reports whether an item's label, description,
+	// attributes, expiry and metadata fields are stored unencrypted beside
+	// its encrypted data, so that anyone who can read the store can read
+	// them without the passphrase or key.
+	PlaintextMetadata bool
+}
+
+// backendCapabilities is filled in by each backend's init, whether or not the
+// backend turns out to be usable at runtime.
+var backendCapabilities = map[BackendType]Capabilities{}
+
+// BackendInfo returns the capabilities of a backend. ok is false if the
+// backend is not part of this build.
+func BackendInfo(backend BackendType) (caps Capabilities, ok bool) {
+	caps, ok = backendCapabilities[backend]
+	return caps, ok
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/byteness/keyring"
+)
+
+// runAudit handles the "audit" subcommand.
+func runAudit(args []string) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: keyring audit verify -key-file FILE [-expect-seq N] [-expect-mac MAC] LOG")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("audit verify", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "The file holding the audit log's HMAC key")
+	expectSeq := fs.Uint64("expect-seq", 0, "The sequence number of a record the log must still hold, such as the last one seen")
+	expectMAC := fs.String("expect-mac", "", "The MAC of a record the log must still hold, such as the last one seen")
+	_ = fs.Parse(args[1:])
+	if *keyFile == "" || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: keyring audit verify -key-file FILE [-expect-seq N] [-expect-mac MAC] LOG")
+		return 2
+	}
+
+	key, err := os.ReadFile(*keyFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	key = []byte(strings.TrimSpace(string(key)))
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	last, err := keyring.VerifyAuditLogAnchored(f, key, keyring.AuditAnchor{Seq: *expectSeq, MAC: *expectMAC})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	// The last record is what to pass to -expect-seq and -expect-mac next time.
+	fmt.Printf("%s: %d records OK, last MAC %s\n", fs.Arg(0), last.Seq, last.MAC)
+	return 0
+}
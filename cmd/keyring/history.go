@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/byteness/keyring"
+)
+
+// ringFlags are the flags of a subcommand that select the keyring it works on.
+type ringFlags struct {
+	service     *string
+	backend     *string
+	keychain    *string
+	fileDir     *string
+	fileHistory *int
+}
+
+func addRingFlags(fs *flag.FlagSet) ringFlags {
+	return ringFlags{
+		service:     fs.String("service", "example", "The keyring service to use"),
+		backend:     fs.String("backend", "", "A specific backend to use"),
+		keychain:    fs.String("keychain", "login", "The keychain to search"),
+		fileDir:     fs.String("file-dir", "", "The directory of the file backend"),
+		fileHistory: fs.Int("file-history", 0, "The number of earlier versions of each item the file backend keeps"),
+	}
+}
+
+func (f ringFlags) open() (keyring.Keyring, error) {
+	allowedBackends := keyring.AvailableBackends()
+	if *f.backend != "" {
+		if !hasBackend(*f.backend) {
+			return nil, fmt.Errorf("backend %q isn't available. Use -list-backends to see what is", *f.backend)
+		}
+		allowedBackends = []keyring.BackendType{keyring.BackendType(*f.backend)}
+	}
+	return keyring.Open(keyring.Config{
+		ServiceName:      *f.service,
+		AllowedBackends:  allowedBackends,
+		KeychainName:     *f.keychain,
+		FileDir:          *f.fileDir,
+		FilePasswordFunc: keyring.TerminalPrompt,
+		FileHistory:      *f.fileHistory,
+	})
+}
+
+// runHistory handles the "history" subcommand, which lists the earlier
+// versions of an item.
+func runHistory(args []string) int {
+	const usage = "usage: keyring history [-service NAME] [-backend NAME] [-file-dir DIR] KEY"
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	ring := addRingFlags(fs)
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	k, err := ring.open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	revisions, err := keyring.History(k, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	for _, rev := range revisions {
+		modified := "-"
+		if !rev.Modified.IsZero() {
+			modified = rev.Modified.Local().Format(time.RFC3339)
+		}
+		fmt.Printf("%s\t%s\n", rev.Version, modified)
+	}
+	return 0
+}
+
+// runRestore handles the "restore" subcommand, which brings back an earlier
+// version of an item listed by "history".
+func runRestore(args []string) int {
+	const usage = "usage: keyring restore [-service NAME] [-backend NAME] [-file-dir DIR] [-file-history N] KEY VERSION"
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	ring := addRingFlags(fs)
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	k, err := ring.open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	err = keyring.Restore(k, fs.Arg(0), fs.Arg(1))
+	if errors.Is(err, keyring.ErrRevisionNotFound) {
+		fmt.Fprintf(os.Stderr, "%s: no version %q; run \"keyring history %s\" to list them\n", fs.Arg(0), fs.Arg(1), fs.Arg(0))
+		return 1
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byteness/keyring"
+)
+
+// runLs handles the "ls" subcommand, which lists the keys in a keyring and,
+// with -stale, only those not rotated within a given age.
+func runLs(args []string) int {
+	const usage = "usage: keyring ls [-service NAME] [-backend NAME] [-file-dir DIR] [-prefix PREFIX] [-stale AGE]"
+	fs := flag.NewFlagSet("ls", flag.ExitOnError)
+	ring := addRingFlags(fs)
+	prefix := fs.String("prefix", "", "Only list the keys starting with this prefix")
+	stale := fs.String("stale", "", "Only list the items last rotated longer ago than this, such as 90d or 12h")
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	var maxAge time.Duration
+	if *stale != "" {
+		var err error
+		if maxAge, err = parseAge(*stale); err != nil {
+			fmt.Fprintf(os.Stderr, "-stale: %v\n", err)
+			return 2
+		}
+	}
+
+	k, err := ring.open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	status := 0
+	for md, err := range keyring.List(context.Background(), k, keyring.ListOptions{Prefix: *prefix}) {
+		if err != nil && md.Item == nil {
+			// Listing the keys failed.
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", md.Item.Key, err)
+			status = 1
+			continue
+		}
+		if *stale == "" {
+			fmt.Println(md.Item.Key)
+			continue
+		}
+		// An item whose age is unknown cannot be shown to be fresh.
+		rotated := keyring.LastRotated(md)
+		if rotated.IsZero() {
+			fmt.Printf("%s\t-\n", md.Item.Key)
+		} else if time.Since(rotated) > maxAge {
+			fmt.Printf("%s\t%s\n", md.Item.Key, rotated.Local().Format(time.RFC3339))
+		}
+	}
+	return status
+}
+
+// parseAge parses a duration as time.ParseDuration does, also accepting a
+// whole number of days such as "90d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -1,4 +1,13 @@
 // Command keyring is a simple CLI for inspecting keyring backends.
+//
+// "keyring audit verify -key-file FILE LOG" checks the hash chain of an audit
+// log written by keyring.AuditLog.
+//
+// "keyring ls -stale 90d" lists the items not rotated with keyring.Rotate, or
+// otherwise changed, in the last 90 days.
+//
+// "keyring history KEY" lists the earlier versions of an item that the
+// backend keeps, and "keyring restore KEY VERSION" brings one back.
 package main
 
 import (
@@ -11,6 +20,19 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "audit":
+			os.Exit(runAudit(os.Args[2:]))
+		case "ls":
+			os.Exit(runLs(os.Args[2:]))
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		case "restore":
+			os.Exit(runRestore(os.Args[2:]))
+		}
+	}
+
 	serviceName := flag.String("service", "example", "The keyring service to use")
 	keyName := flag.String("key", "example", "The key to use")
 	backend := flag.String("backend", "", "A specific backend to use")
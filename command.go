@@ -0,0 +1,42 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os/exec"
+)
+
+// runCommand runs cmd and returns its standard output. Standard error is still
+// passed through to wherever cmd.Stderr points, but is also captured so that
+// classifyStderr can map a failed run onto one of the package's sentinel
+// errors. classifyStderr returns nil when it does not recognise the output.
+func runCommand(ctx context.Context, cmd *exec.Cmd, classifyStderr func(stderr string) error) ([]byte, error) {
+	var stderr bytes.Buffer
+	if cmd.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, &stderr)
+	} else {
+		cmd.Stderr = &stderr
+	}
+
+	output, err := cmd.Output()
+	if err == nil {
+		return output, nil
+	}
+	if cerr := ctx.Err(); cerr != nil {
+		return nil, cerr
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return nil, classify(ErrBackendUnavailable, err)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if kind := classifyStderr(stderr.String()); kind != nil {
+			return nil, classify(kind, err)
+		}
+	}
+	return nil, err
+}
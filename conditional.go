@@ -0,0 +1,104 @@
+package keyring
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+)
+
+// ErrConflict is returned by SetIfAbsent when the key is already taken, and by
+// CompareAndSwap when the item has changed or been removed since its version
+// was read.
+var ErrConflict = errors.New("the item was changed by another writer")
+
+// ErrConditionalNotSupported is returned by the conditional operations for
+// keyrings that do not implement ConditionalKeyring.
+var ErrConditionalNotSupported = errors.New("the keyring backend does not support conditional writes")
+
+// ConditionalKeyring is implemented by keyrings that can make a write depend
+// on the item's current state, so that processes sharing an item do not
+// overwrite each other's changes. A version is an opaque string that changes
+// whenever the item does.
+type ConditionalKeyring interface {
+	// Returns the item for key and its current version
+	GetVersionedContext(ctx context.Context, key string) (Item, string, error)
+	// Stores item unless there already is an item for its key
+	SetIfAbsentContext(ctx context.Context, item Item) error
+	// Stores item under key if the item for key is still at expectedVersion
+	CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) error
+}
+
+// GetVersioned returns the item for key in k and its version. See
+// GetVersionedContext.
+func GetVersioned(k Keyring, key string) (Item, string, error) {
+	return GetVersionedContext(context.Background(), k, key)
+}
+
+// GetVersionedContext returns the item for key in k and its version, to pass
+// to CompareAndSwap after changing the item. It returns
+// ErrConditionalNotSupported if k does not implement ConditionalKeyring.
+func GetVersionedContext(ctx context.Context, k Keyring, key string) (Item, string, error) {
+	ck, ok := k.(ConditionalKeyring)
+	if !ok {
+		return Item{}, "", ErrConditionalNotSupported
+	}
+	return ck.GetVersionedContext(ctx, key)
+}
+
+// SetIfAbsent stores item in k unless its key is taken. See
+// SetIfAbsentContext.
+func SetIfAbsent(k Keyring, item Item) error {
+	return SetIfAbsentContext(context.Background(), k, item)
+}
+
+// SetIfAbsentContext stores item in k, or returns ErrConflict if there already
+// is an item for its key. It returns ErrConditionalNotSupported if k does not
+// implement ConditionalKeyring.
+func SetIfAbsentContext(ctx context.Context, k Keyring, item Item) error {
+	ck, ok := k.(ConditionalKeyring)
+	if !ok {
+		return ErrConditionalNotSupported
+	}
+	return ck.SetIfAbsentContext(ctx, item)
+}
+
+// CompareAndSwap stores item under key in k if the item is unchanged. See
+// CompareAndSwapContext.
+func CompareAndSwap(k Keyring, key, expectedVersion string, item Item) error {
+	return CompareAndSwapContext(context.Background(), k, key, expectedVersion, item)
+}
+
+// CompareAndSwapContext stores item under key in k if the item for key is
+// still at expectedVersion, as returned by GetVersioned, or returns
+// ErrConflict if it has since changed or been removed. It returns
+// ErrConditionalNotSupported if k does not implement ConditionalKeyring.
+func CompareAndSwapContext(ctx context.Context, k Keyring, key, expectedVersion string, item Item) error {
+	ck, ok := k.(ConditionalKeyring)
+	if !ok {
+		return ErrConditionalNotSupported
+	}
+	item.Key = key
+	return ck.CompareAndSwapContext(ctx, key, expectedVersion, item)
+}
+
+// contentVersion returns the version of an item stored encrypted in a file,
+// a hash of the ciphertext. Every write encrypts afresh, so unlike the file's
+// modification time it changes even for writes within the same clock tick.
+func contentVersion(ciphertext []byte) string {
+	sum := sha256.Sum256(ciphertext)
+	return hex.EncodeToString(sum[:16])
+}
+
+// fileVersion returns the contentVersion of the file at path, or
+// ErrKeyNotFound if there is none.
+func fileVersion(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", ErrKeyNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return contentVersion(b), nil
+}
@@ -1,6 +1,7 @@
 package keyring
 
 import (
+	"log/slog"
 	"time"
 )
 
@@ -12,6 +13,14 @@ type Config struct {
 	// ServiceName is a generic service name that is used by backends that support the concept
 	ServiceName string
 
+	// Logger receives the keyring's diagnostics, and a debug record for every
+	// operation with its backend, key, duration and error class. If it is nil,
+	// diagnostics go to the standard log package while Debug is set.
+	Logger *slog.Logger
+
+	// RedactLogKeys replaces the item keys in log records with a short hash
+	RedactLogKeys bool
+
 	// MacOSKeychainNameKeychainName is the name of the macOS keychain that is used
 	KeychainName string
 
@@ -33,6 +42,15 @@ type Config struct {
 	// FileDir is the directory that keyring files are stored in, ~/ is resolved to the users' home dir
 	FileDir string
 
+	// FileHistory is how many earlier versions of each item the file backend keeps
+	// for History and Restore. Zero keeps none.
+	FileHistory int
+
+	// LockTimeout is how long the file, pass and passage backends wait for another
+	// process's lock on their directory before failing with a *LockTimeoutError.
+	// Zero selects DefaultLockTimeout.
+	LockTimeout time.Duration
+
 	// KeyCtlScope is the scope of the kernel keyring (either "user", "session", "process" or "thread")
 	KeyCtlScope string
 
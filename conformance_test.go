@@ -0,0 +1,113 @@
+package keyring_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/byteness/keyring"
+	"github.com/byteness/keyring/keyringtest"
+)
+
+// TestConformance runs the keyringtest suite against each backend that can
+// run on Linux, skipping those this build or machine does not have.
+func TestConformance(t *testing.T) {
+	t.Run("array", func(t *testing.T) {
+		k := keyring.NewArrayKeyring(nil)
+		keyringtest.RunConformance(t, func() keyring.Keyring { return k })
+	})
+
+	t.Run(string(keyring.FileBackend), func(t *testing.T) {
+		runConformance(t, keyring.Config{
+			FileDir:          t.TempDir(),
+			FilePasswordFunc: keyring.FixedStringPrompt("no more secrets"),
+		}, keyring.FileBackend)
+	})
+
+	t.Run(string(keyring.KeyCtlBackend), func(t *testing.T) {
+		runConformance(t, keyring.Config{
+			ServiceName: "keyringtest-conformance",
+			KeyCtlScope: "process",
+		}, keyring.KeyCtlBackend)
+	})
+
+	t.Run(string(keyring.PassBackend), func(t *testing.T) {
+		requireCommands(t, "pass", "gpg")
+		// gpg-agent's socket path would be too long under the default
+		// temporary directory.
+		dir, err := os.MkdirTemp("/tmp", "keyring-conformance-*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+
+		gnupghome := filepath.Join(dir, ".gnupg")
+		if err := os.Mkdir(gnupghome, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("GNUPGHOME", gnupghome)
+		t.Setenv("GPG_AGENT_INFO", "")
+		t.Setenv("GPG_TTY", "")
+		store := filepath.Join(dir, ".password-store")
+		t.Setenv("PASSWORD_STORE_DIR", store)
+		command(t, "gpg", "--import", filepath.Join("testdata", "test-gpg.key"))
+		command(t, "gpg", "--import-ownertrust", filepath.Join("testdata", "test-ownertrust-gpg.txt"))
+		command(t, "pass", "init", "test@example.com")
+
+		runConformance(t, keyring.Config{PassDir: store, PassPrefix: "keyring"}, keyring.PassBackend)
+	})
+
+	t.Run(string(keyring.PassageBackend), func(t *testing.T) {
+		requireCommands(t, "passage", "age-keygen")
+		dir := t.TempDir()
+		identities := filepath.Join(dir, "identities")
+		command(t, "age-keygen", "--output", identities)
+		t.Setenv("PASSAGE_IDENTITIES_FILE", identities)
+
+		runConformance(t, keyring.Config{PassDir: filepath.Join(dir, "store"), PassPrefix: "keyring"}, keyring.PassageBackend)
+	})
+
+	// These need a desktop session, and may prompt to create the collection
+	// or wallet.
+	for _, backend := range []keyring.BackendType{keyring.SecretServiceBackend, keyring.KWalletBackend} {
+		t.Run(string(backend), func(t *testing.T) {
+			if os.Getenv("GITHUB_ACTIONS") != "" {
+				t.Skip("Skipping testing in CI environment")
+			}
+			runConformance(t, keyring.Config{
+				ServiceName:             "keyringtest",
+				LibSecretCollectionName: "keyringtest",
+				KWalletFolder:           "keyringtest",
+			}, backend)
+		})
+	}
+}
+
+// runConformance opens backend with cfg, skipping the test if it is not
+// available, and runs the suite against it.
+func runConformance(t *testing.T, cfg keyring.Config, backend keyring.BackendType) {
+	t.Helper()
+	cfg.AllowedBackends = []keyring.BackendType{backend}
+	k, err := keyring.Open(cfg)
+	if err != nil {
+		t.Skipf("%s is not available: %v", backend, err)
+	}
+	keyringtest.RunConformance(t, func() keyring.Keyring { return k })
+}
+
+func requireCommands(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s is not installed", name)
+		}
+	}
+}
+
+func command(t *testing.T, args ...string) {
+	t.Helper()
+	if out, err := exec.CommandContext(t.Context(), args[0], args[1:]...).CombinedOutput(); err != nil {
+		t.Fatalf("%v: %v\n%s", args, err, out)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build linux
+// +build linux
+
+package keyring
+
+import (
+	"context"
+	"errors"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// dbusError classifies an error returned by a D-Bus call to the Secret Service
+// or KWallet so that it matches the package's sentinel errors. Errors that
+// are not D-Bus errors, such as a cancelled context, are returned unchanged.
+func dbusError(err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		var dbusErrPtr *dbus.Error
+		if !errors.As(err, &dbusErrPtr) {
+			return err
+		}
+		dbusErr = *dbusErrPtr
+	}
+
+	switch dbusErr.Name {
+	case "org.freedesktop.Secret.Error.IsLocked":
+		return classify(ErrLocked, err)
+	case "org.freedesktop.Secret.Error.NoSuchObject":
+		return classify(ErrKeyNotFound, err)
+	case "org.freedesktop.DBus.Error.AccessDenied",
+		"org.freedesktop.DBus.Error.AuthFailed",
+		"org.freedesktop.DBus.Error.InteractiveAuthorizationRequired":
+		return classify(ErrAccessDenied, err)
+	case "org.freedesktop.DBus.Error.ServiceUnknown",
+		"org.freedesktop.DBus.Error.NameHasNoOwner",
+		"org.freedesktop.DBus.Error.NoServer",
+		"org.freedesktop.DBus.Error.NoReply",
+		"org.freedesktop.DBus.Error.Disconnected",
+		"org.freedesktop.DBus.Error.Spawn.ChildExited",
+		"org.freedesktop.DBus.Error.Spawn.ExecFailed":
+		return classify(ErrBackendUnavailable, err)
+	default:
+		return err
+	}
+}
+
+// subscribeSignals delivers the signals on the session bus matching opts to
+// the returned channel until stop is called.
+func subscribeSignals(ctx context.Context, opts ...dbus.MatchOption) (*dbus.Conn, <-chan *dbus.Signal, func(), error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, nil, nil, dbusError(err)
+	}
+	if err := conn.AddMatchSignalContext(ctx, opts...); err != nil {
+		return nil, nil, nil, dbusError(err)
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+	stop := func() {
+		conn.RemoveSignal(signals)
+		conn.RemoveMatchSignal(opts...)
+	}
+	return conn, signals, stop, nil
+}
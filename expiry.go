@@ -0,0 +1,73 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// expired reports whether an item that expires at t has expired.
+func expired(t time.Time) bool {
+	return !t.IsZero() && !time.Now().Before(t)
+}
+
+// unexpired returns item, or ErrKeyNotFound if it has expired. Backends pass
+// the items they read through it before returning them from Get.
+func unexpired(item Item) (Item, error) {
+	if expired(item.Expires) {
+		return Item{}, ErrKeyNotFound
+	}
+	return item, nil
+}
+
+// PurgeExpired removes the expired items from k and returns their keys.
+func PurgeExpired(k Keyring) ([]string, error) {
+	return PurgeExpiredContext(context.Background(), k)
+}
+
+// PurgeExpiredContext is PurgeExpired with a context. The expiry of each item
+// is read from its metadata where the backend supports it, so that items need
+// not be unlocked. Elsewhere an item that Keys lists but Get reports missing is
+// taken to have expired.
+func PurgeExpiredContext(ctx context.Context, k Keyring) ([]string, error) {
+	ck := AsContextKeyring(k)
+	keys, err := ck.KeysContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	purged := []string{}
+	for _, key := range keys {
+		ok, err := itemExpired(ctx, ck, key)
+		if err != nil {
+			return purged, err
+		}
+		if !ok {
+			continue
+		}
+		if err := ck.RemoveContext(ctx, key); err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return purged, err
+		}
+		purged = append(purged, key)
+	}
+	return purged, nil
+}
+
+// itemExpired reports whether the item with key has expired.
+func itemExpired(ctx context.Context, k ContextKeyring, key string) (bool, error) {
+	md, err := k.GetMetadataContext(ctx, key)
+	switch {
+	case err == nil && md.Item != nil:
+		return expired(md.Expires), nil
+	case errors.Is(err, ErrKeyNotFound):
+		return false, nil // removed since Keys
+	case err != nil && !errors.Is(err, ErrMetadataNotSupported) && !errors.Is(err, ErrMetadataNeedsCredentials):
+		return false, err
+	}
+
+	_, err = k.GetContext(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return true, nil
+	}
+	return false, err
+}
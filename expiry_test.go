@@ -0,0 +1,47 @@
+package keyring
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+var expiryTestItems = []Item{
+	{Key: "expired", Data: []byte("a"), Expires: time.Now().Add(-time.Minute)},
+	{Key: "valid", Data: []byte("b"), Expires: time.Now().Add(time.Hour)},
+	{Key: "forever", Data: []byte("c")},
+}
+
+func TestPurgeExpired(t *testing.T) {
+	testPurgeExpired(t, NewArrayKeyring(expiryTestItems))
+}
+
+// testPurgeExpired checks Get and PurgeExpired against a keyring holding
+// expiryTestItems.
+func testPurgeExpired(t *testing.T, k Keyring) {
+	t.Helper()
+	if _, err := k.Get("expired"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Get of an expired item: expected ErrKeyNotFound, got %v", err)
+	}
+	if _, err := k.Get("valid"); err != nil {
+		t.Fatalf("Get of an unexpired item: %v", err)
+	}
+
+	purged, err := PurgeExpired(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(purged, []string{"expired"}) {
+		t.Fatalf("PurgeExpired = %v, want [expired]", purged)
+	}
+
+	keys, err := k.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"forever", "valid"}) {
+		t.Fatalf("Keys after PurgeExpired = %v", keys)
+	}
+}
@@ -3,10 +3,18 @@
 package keyring
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/byteness/percent"
@@ -14,12 +22,29 @@ import (
 )
 
 func init() {
+	backendCapabilities[FileBackend] = Capabilities{
+		Metadata:    MetadataSupported,
+		Label:       true,
+		Description: true,
+		Interactive: true,
+		// The metadata is kept in the JWE protected header so that
+		// GetMetadata does not need the passphrase. The header is
+		// authenticated, but not encrypted.
+		PlaintextMetadata: true,
+	}
+
 	supportedBackends[FileBackend] = opener(func(cfg Config) (Keyring, error) {
 		return &fileKeyring{
 			dir:          cfg.FileDir,
 			passwordFunc: cfg.FilePasswordFunc,
+			history:      cfg.FileHistory,
+			lockTimeout:  cfg.LockTimeout,
+			log:          newCallLogger(cfg, FileBackend),
 		}, nil
 	})
+	backendProbes[FileBackend] = func(_ context.Context, cfg Config) error {
+		return (&fileKeyring{dir: cfg.FileDir, passwordFunc: cfg.FilePasswordFunc}).probe()
+	}
 }
 
 var filenameEscape = func(s string) string {
@@ -31,8 +56,17 @@ type fileKeyring struct {
 	dir          string
 	passwordFunc PromptFunc
 	password     string
+	history      int
+	lockTimeout  time.Duration
+	log          callLogger
 }
 
+// fileHistoryDir is the subdirectory of the keyring directory that holds the
+// earlier versions of items, in a directory per item named like its file.
+// Items are only ever stored in plain files, so it is never taken for one, and
+// filename refuses the key that would be stored under its name.
+const fileHistoryDir = ".history"
+
 func (k *fileKeyring) resolveDir() (string, error) {
 	if k.dir == "" {
 		return "", fmt.Errorf("no directory provided for file keyring")
@@ -53,6 +87,35 @@ func (k *fileKeyring) resolveDir() (string, error) {
 	return dir, err
 }
 
+// probe checks the configuration and the directory's permissions. Unlike
+// resolveDir it does not create a missing directory; Set will do that.
+func (k *fileKeyring) probe() error {
+	if k.passwordFunc == nil {
+		return errors.New("no passphrase prompt configured for file keyring")
+	}
+	if k.dir == "" {
+		return fmt.Errorf("no directory provided for file keyring")
+	}
+
+	dir, err := ExpandTilde(k.dir)
+	if err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return fileError(err)
+	case !stat.IsDir():
+		return fmt.Errorf("%s is a file, not a directory", dir)
+	case runtime.GOOS != "windows" && stat.Mode().Perm()&0077 != 0:
+		return fmt.Errorf("%s is accessible by other users (mode %#o)", dir, stat.Mode().Perm())
+	}
+	return nil
+}
+
 func (k *fileKeyring) unlock() error {
 	dir, err := k.resolveDir()
 	if err != nil {
@@ -70,103 +133,518 @@ func (k *fileKeyring) unlock() error {
 	return nil
 }
 
-func (k *fileKeyring) Get(key string) (Item, error) {
-	filename, err := k.filename(key)
+// lock takes the lock on the keyring directory, shared for reads and
+// exclusive for writes, and returns the function that releases it. Writes
+// ask for the passphrase before taking it, so that other processes are not
+// kept waiting on the prompt.
+func (k *fileKeyring) lock(ctx context.Context, exclusive bool) (func(), error) {
+	dir, err := k.resolveDir()
 	if err != nil {
-		return Item{}, err
+		return nil, err
 	}
+	return lockDir(ctx, dir, exclusive, k.lockTimeout)
+}
 
-	bytes, err := os.ReadFile(filename)
+// readItemFile returns the contents of the item file filename, read under a
+// shared lock, or ErrKeyNotFound if there is none.
+func (k *fileKeyring) readItemFile(ctx context.Context, filename string) ([]byte, error) {
+	release, err := k.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	token, err := os.ReadFile(filename)
 	if os.IsNotExist(err) {
-		return Item{}, ErrKeyNotFound
-	} else if err != nil {
+		return nil, ErrKeyNotFound
+	}
+	return token, fileError(err)
+}
+
+func (k *fileKeyring) Get(key string) (Item, error) {
+	return k.GetContext(context.Background(), key)
+}
+
+func (k *fileKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	if err := ctx.Err(); err != nil {
 		return Item{}, err
 	}
 
-	if err = k.unlock(); err != nil {
+	filename, err := k.filename(key)
+	if err != nil {
 		return Item{}, err
 	}
 
-	payload, _, err := jose.Decode(string(bytes), k.password)
+	bytes, err := k.readItemFile(ctx, filename)
 	if err != nil {
 		return Item{}, err
 	}
 
+	return k.decode(ctx, bytes)
+}
+
+// decode decrypts the item in token, the contents of an item file.
+func (k *fileKeyring) decode(ctx context.Context, token []byte) (Item, error) {
+	payload, err := k.decrypt(ctx, token)
+	if err != nil {
+		return Item{}, err
+	}
+	defer zeroBytes(payload)
+
 	var decoded Item
-	err = json.Unmarshal([]byte(payload), &decoded)
+	if err = json.Unmarshal(payload, &decoded); err != nil {
+		return Item{}, err
+	}
 
-	return decoded, err
+	return unexpired(decoded)
+}
+
+// decrypt returns the JSON encoded item in token, the contents of an item
+// file.
+func (k *fileKeyring) decrypt(ctx context.Context, token []byte) ([]byte, error) {
+	if err := k.unlock(); err != nil {
+		return nil, err
+	}
+
+	// The passphrase prompt may have taken a while.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	payload, _, err := jose.DecodeBytes(string(token), k.password)
+	if err != nil {
+		err = fileError(err)
+		if errors.Is(err, ErrWrongPassphrase) {
+			// Forget the passphrase so the next attempt prompts again.
+			k.password = ""
+		}
+		return nil, err
+	}
+	return payload, nil
 }
 
 func (k *fileKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+func (k *fileKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
+
 	filename, err := k.filename(key)
 	if err != nil {
 		return Metadata{}, err
 	}
 
+	release, err := k.lock(ctx, false)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer release()
+
 	stat, err := os.Stat(filename)
 	if os.IsNotExist(err) {
 		return Metadata{}, ErrKeyNotFound
 	} else if err != nil {
-		return Metadata{}, err
+		return Metadata{}, fileError(err)
 	}
 
-	// For the File provider, all internal data is encrypted, not just the
-	// credentials.  Thus we only have the timestamps.  Return a nil *Item.
-	//
-	// If we want to change this ... how portable are extended file attributes
-	// these days?  Would it break user expectations of the security model to
-	// leak data into those?  I'm hesitant to do so.
+	bytes, err := os.ReadFile(filename)
+	if err != nil {
+		return Metadata{}, fileError(err)
+	}
+
+	// The item itself is encrypted, but its metadata is kept in the JWE
+	// protected header, which is only authenticated on decryption and can be
+	// read without the passphrase. Capabilities.PlaintextMetadata says so.
+	rec, err := readFileHeader(bytes)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if rec.Modified.IsZero() {
+		// Written before the header carried metadata.
+		rec.Modified = stat.ModTime()
+	}
 
-	return Metadata{
-		ModificationTime: stat.ModTime(),
-	}, nil
+	return rec.metadata(key), nil
 }
 
 func (k *fileKeyring) Set(i Item) error {
+	return k.SetContext(context.Background(), i)
+}
+
+func (k *fileKeyring) SetContext(ctx context.Context, i Item) (err error) {
+	defer k.log.op(ctx, OpSet, i.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	bytes, err := json.Marshal(i)
 	if err != nil {
 		return err
 	}
+	defer zeroBytes(bytes)
 
-	if err = k.unlock(); err != nil {
+	filename, err := k.filename(i.Key)
+	if err != nil {
 		return err
 	}
 
-	token, err := jose.Encrypt(string(bytes), jose.PBES2_HS256_A128KW, jose.A256GCM, k.password,
-		jose.Headers(map[string]interface{}{
-			"created": time.Now().String(),
-		}))
+	if err := k.unlock(); err != nil {
+		return err
+	}
+	release, err := k.lock(ctx, true)
 	if err != nil {
 		return err
 	}
+	defer release()
 
-	filename, err := k.filename(i.Key)
+	// Keep the creation time and fields of the item being replaced.
+	var rec metadataRecord
+	if existing, err := os.ReadFile(filename); err == nil {
+		rec, _ = readFileHeader(existing)
+	}
+
+	return k.write(ctx, filename, bytes, rec.update(i, time.Now()))
+}
+
+// GetVersionedContext returns the item for key and its version, a hash of
+// its file.
+func (k *fileKeyring) GetVersionedContext(ctx context.Context, key string) (_ Item, _ string, err error) {
+	defer k.log.op(ctx, OpGetVersioned, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, "", err
+	}
+
+	filename, err := k.filename(key)
+	if err != nil {
+		return Item{}, "", err
+	}
+
+	token, err := k.readItemFile(ctx, filename)
+	if err != nil {
+		return Item{}, "", err
+	}
+
+	item, err := k.decode(ctx, token)
+	if err != nil {
+		return Item{}, "", err
+	}
+	return item, contentVersion(token), nil
+}
+
+// SetIfAbsentContext stores item in a new file, failing with ErrConflict if
+// the file already exists.
+func (k *fileKeyring) SetIfAbsentContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSetIfAbsent, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(bytes)
+
+	filename, err := k.filename(item.Key)
+	if err != nil {
+		return err
+	}
+
+	if err := k.unlock(); err != nil {
+		return err
+	}
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, err := os.Stat(filename); err == nil {
+		return ErrConflict
+	}
+
+	var rec metadataRecord
+	token, err := k.encrypt(ctx, bytes, rec.update(item, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	// O_EXCL also keeps out writers that do not take the lock.
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, fs.ErrExist) {
+		return ErrConflict
+	} else if err != nil {
+		return fileError(err)
+	}
+	_, err = f.Write(token)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return fileError(err)
+}
+
+// CompareAndSwapContext stores item if the file for key has not changed since
+// expectedVersion was read, checking and writing it under the lock.
+func (k *fileKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) (err error) {
+	defer k.log.op(ctx, OpCompareAndSwap, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(bytes)
+
+	filename, err := k.filename(key)
+	if err != nil {
+		return err
+	}
+
+	if err := k.unlock(); err != nil {
+		return err
+	}
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	existing, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return ErrConflict
+	} else if err != nil {
+		return fileError(err)
+	}
+	if contentVersion(existing) != expectedVersion {
+		return ErrConflict
+	}
+	rec, _ := readFileHeader(existing)
+
+	return k.write(ctx, filename, bytes, rec.update(item, time.Now()))
+}
+
+// SetMetadata replaces the item's metadata fields. They are authenticated by
+// the item's encryption, so this needs the passphrase.
+func (k *fileKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *fileKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	filename, err := k.filename(key)
+	if err != nil {
+		return err
+	}
+
+	if err := k.unlock(); err != nil {
+		return err
+	}
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	token, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return ErrKeyNotFound
+	} else if err != nil {
+		return fileError(err)
+	}
+
+	rec, err := readFileHeader(token)
+	if err != nil {
+		return err
+	}
+
+	payload, err := k.decrypt(ctx, token)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(payload)
+
+	return k.write(ctx, filename, payload, rec.withFields(fields, time.Now()))
+}
+
+// write encrypts payload with the metadata in rec as the JWE protected header
+// and stores it in filename. The caller holds the exclusive lock.
+func (k *fileKeyring) write(ctx context.Context, filename string, payload []byte, rec metadataRecord) error {
+	token, err := k.encrypt(ctx, payload, rec)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, []byte(token), 0600)
+	if err := k.rotate(filename); err != nil {
+		return err
+	}
+	return fileError(os.WriteFile(filename, token, 0600))
 }
 
+// encrypt returns the contents of an item file holding payload, with the
+// metadata in rec as the JWE protected header.
+func (k *fileKeyring) encrypt(ctx context.Context, payload []byte, rec metadataRecord) ([]byte, error) {
+	if err := k.unlock(); err != nil {
+		return nil, err
+	}
+
+	// The passphrase prompt may have taken a while.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	headers := map[string]interface{}{
+		"created":  rec.Created.Format(time.RFC3339Nano),
+		"modified": rec.Modified.Format(time.RFC3339Nano),
+	}
+	if rec.Label != "" {
+		headers["label"] = rec.Label
+	}
+	if rec.Description != "" {
+		headers["description"] = rec.Description
+	}
+	if len(rec.Fields) > 0 {
+		headers["fields"] = rec.Fields
+	}
+	if len(rec.Attributes) > 0 {
+		headers["attributes"] = rec.Attributes
+	}
+	if !rec.Expires.IsZero() {
+		headers["expires"] = rec.Expires.Format(time.RFC3339Nano)
+	}
+
+	token, err := jose.EncryptBytes(payload, jose.PBES2_HS256_A128KW, jose.A256GCM, k.password,
+		jose.Headers(headers))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(token), nil
+}
+
+// readFileHeader returns the metadata in the protected header of an item file.
+// Files written by older versions have no metadata there, and their "created"
+// header, which was rewritten by every Set, is not in RFC 3339 format; it is
+// ignored.
+func readFileHeader(token []byte) (metadataRecord, error) {
+	encoded, _, _ := strings.Cut(string(token), ".")
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return metadataRecord{}, fmt.Errorf("reading item header: %w", err)
+	}
+
+	var header struct {
+		Created     string            `json:"created"`
+		Modified    string            `json:"modified"`
+		Label       string            `json:"label"`
+		Description string            `json:"description"`
+		Fields      map[string]string `json:"fields"`
+		Attributes  map[string]string `json:"attributes"`
+		Expires     string            `json:"expires"`
+	}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return metadataRecord{}, fmt.Errorf("reading item header: %w", err)
+	}
+
+	rec := metadataRecord{
+		Label:       header.Label,
+		Description: header.Description,
+		Fields:      header.Fields,
+		Attributes:  header.Attributes,
+	}
+	rec.Created, _ = time.Parse(time.RFC3339Nano, header.Created)
+	rec.Modified, _ = time.Parse(time.RFC3339Nano, header.Modified)
+	if header.Expires != "" {
+		if rec.Expires, err = time.Parse(time.RFC3339Nano, header.Expires); err != nil {
+			return metadataRecord{}, fmt.Errorf("reading item header: %w", err)
+		}
+	}
+	return rec, nil
+}
+
+// fileError classifies an error from reading, writing or decrypting an item
+// file so that it matches the package's sentinel errors.
+func fileError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrPermission):
+		return classify(ErrAccessDenied, err)
+	case strings.Contains(err.Error(), "integrity check failed"):
+		// jose2go only reports a failed key unwrap, which is what decrypting
+		// with the wrong passphrase produces, through its error text.
+		return classify(ErrWrongPassphrase, err)
+	default:
+		return err
+	}
+}
+
+// filename returns the path of the file for key. Keys whose file would be the
+// history directory, or not in the keyring directory at all, are refused.
 func (k *fileKeyring) filename(key string) (string, error) {
 	dir, err := k.resolveDir()
 	if err != nil {
 		return "", err
 	}
 
-	return filepath.Join(dir, filenameEscape(key)), nil
+	name := filenameEscape(key)
+	switch name {
+	case "", ".", "..", fileHistoryDir:
+		return "", fmt.Errorf("the key %q cannot be stored in a file keyring", key)
+	}
+	return filepath.Join(dir, name), nil
 }
 
 func (k *fileKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *fileKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	filename, err := k.filename(key)
 	if err != nil {
 		return err
 	}
 
-	return os.Remove(filename)
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := k.rotate(filename); err != nil {
+		return err
+	}
+	if err := os.Remove(filename); os.IsNotExist(err) {
+		return ErrKeyNotFound
+	} else if err != nil {
+		return fileError(err)
+	}
+	return nil
 }
 
 func (k *fileKeyring) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+func (k *fileKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	dir, err := k.resolveDir()
 	if err != nil {
 		return nil, err
@@ -175,8 +653,187 @@ func (k *fileKeyring) Keys() ([]string, error) {
 	var keys = []string{}
 	files, _ := os.ReadDir(dir)
 	for _, f := range files {
+		if f.IsDir() {
+			continue // the history
+		}
 		keys = append(keys, filenameUnescape(f.Name()))
 	}
 
 	return keys, nil
 }
+
+// Watch reports changes to the items as their files in the directory change,
+// with inotify on Linux. Elsewhere the directory is polled.
+func (k *fileKeyring) Watch(ctx context.Context) (<-chan Event, error) {
+	dir, err := k.resolveDir()
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := watchTree(ctx, dir, false, func(name string) (string, bool) {
+		return filenameUnescape(name), true
+	})
+	if errors.Is(err, errors.ErrUnsupported) {
+		return Poll(ctx, k, DefaultPollInterval)
+	}
+	return events, fileError(err)
+}
+
+// HistoryContext returns the earlier versions of the item for key kept in the
+// keyring directory, newest first. Their times are read from the files'
+// headers, so this does not need the passphrase.
+func (k *fileKeyring) HistoryContext(ctx context.Context, key string) (_ []Revision, err error) {
+	defer k.log.op(ctx, OpHistory, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	filename, err := k.filename(key)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := k.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	dir := versionsDir(filename)
+	versions, err := readVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return nil, ErrKeyNotFound
+		}
+	}
+
+	revisions := make([]Revision, 0, len(versions))
+	for _, v := range slices.Backward(versions) {
+		rev := Revision{Version: strconv.Itoa(v)}
+		if token, err := os.ReadFile(filepath.Join(dir, rev.Version)); err == nil {
+			rec, _ := readFileHeader(token)
+			rev.Modified = rec.Modified
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, nil
+}
+
+// RestoreContext decrypts the earlier version of the item for key and writes
+// it back as the current one, keeping the version it replaces if the keyring
+// keeps history.
+func (k *fileKeyring) RestoreContext(ctx context.Context, key, version string) (err error) {
+	defer k.log.op(ctx, OpRestore, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	filename, err := k.filename(key)
+	if err != nil {
+		return err
+	}
+
+	// Versions are positive numbers, which also keeps them inside the
+	// directory.
+	if v, err := strconv.Atoi(version); err != nil || v <= 0 || strconv.Itoa(v) != version {
+		return ErrRevisionNotFound
+	}
+
+	if err := k.unlock(); err != nil {
+		return err
+	}
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	token, err := os.ReadFile(filepath.Join(versionsDir(filename), version))
+	if os.IsNotExist(err) {
+		return ErrRevisionNotFound
+	} else if err != nil {
+		return fileError(err)
+	}
+
+	rec, err := readFileHeader(token)
+	if err != nil {
+		return err
+	}
+	payload, err := k.decrypt(ctx, token)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(payload)
+
+	rec.Modified = time.Now()
+	return k.write(ctx, filename, payload, rec)
+}
+
+// rotate keeps the file at filename, if there is one, as the newest earlier
+// version of its item before it is replaced or removed, then prunes the
+// oldest versions beyond the number configured. With none configured it does
+// nothing, leaving any versions kept before alone.
+func (k *fileKeyring) rotate(filename string) error {
+	if k.history <= 0 {
+		return nil
+	}
+
+	token, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fileError(err)
+	}
+
+	dir := versionsDir(filename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fileError(err)
+	}
+	versions, err := readVersions(dir)
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1] + 1
+	}
+	if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(next)), token, 0600); err != nil {
+		return fileError(err)
+	}
+
+	versions = append(versions, next)
+	for _, v := range versions[:max(len(versions)-k.history, 0)] {
+		if err := os.Remove(filepath.Join(dir, strconv.Itoa(v))); err != nil && !os.IsNotExist(err) {
+			return fileError(err)
+		}
+	}
+	return nil
+}
+
+// versionsDir returns the directory holding the earlier versions of the item
+// stored in filename.
+func versionsDir(filename string) string {
+	return filepath.Join(filepath.Dir(filename), fileHistoryDir, filepath.Base(filename))
+}
+
+// readVersions returns the numbers of the versions kept in dir, oldest first.
+func readVersions(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fileError(err)
+	}
+
+	var versions []int
+	for _, e := range entries {
+		if v, err := strconv.Atoi(e.Name()); err == nil && v > 0 && !e.IsDir() {
+			versions = append(versions, v)
+		}
+	}
+	slices.Sort(versions)
+	return versions, nil
+}
@@ -3,8 +3,17 @@
 package keyring
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestFileKeyringSetWhenEmpty(t *testing.T) {
@@ -49,6 +58,86 @@ func TestFileKeyringGetWithSlashes(t *testing.T) {
 	}
 }
 
+func TestFileKeyringMetadata(t *testing.T) {
+	k := &fileKeyring{
+		dir:          t.TempDir(),
+		passwordFunc: FixedStringPrompt("no more secrets"),
+	}
+	item := Item{Key: "llamas", Data: []byte("llamas are great"), Label: "Llamas"}
+
+	if err := k.Set(item); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SetMetadata("llamas", map[string]string{"owner": "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	created, err := k.GetMetadata("llamas")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Storing the item again keeps its fields and creation time. The metadata
+	// is read from the header, without the passphrase.
+	if err := k.Set(item); err != nil {
+		t.Fatal(err)
+	}
+	locked := &fileKeyring{dir: k.dir}
+	md, err := locked.GetMetadata("llamas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Label != "Llamas" || md.Fields["owner"] != "ops" {
+		t.Fatalf("Unexpected metadata: %+v", md)
+	}
+	if md.CreationTime.IsZero() || !md.CreationTime.Equal(created.CreationTime) {
+		t.Fatalf("Creation time changed from %v to %v", created.CreationTime, md.CreationTime)
+	}
+	if md.ModificationTime.Before(created.ModificationTime) {
+		t.Fatalf("Modification time went back from %v to %v", created.ModificationTime, md.ModificationTime)
+	}
+
+	if err := k.SetMetadata("missing", nil); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestFileKeyringFindByAttributes(t *testing.T) {
+	k := &fileKeyring{
+		dir:          t.TempDir(),
+		passwordFunc: FixedStringPrompt("no more secrets"),
+	}
+	for _, item := range attributeTestItems {
+		if err := k.Set(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Attributes are read from the headers, without the passphrase.
+	testFindByAttributes(t, &fileKeyring{dir: k.dir})
+}
+
+func TestFileKeyringExpires(t *testing.T) {
+	k := &fileKeyring{
+		dir:          t.TempDir(),
+		passwordFunc: FixedStringPrompt("no more secrets"),
+	}
+	for _, item := range expiryTestItems {
+		if err := k.Set(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	md, err := (&fileKeyring{dir: k.dir}).GetMetadata("valid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !md.Expires.Equal(expiryTestItems[1].Expires) {
+		t.Fatalf("Expected the expiry in the header, got %v", md.Expires)
+	}
+
+	testPurgeExpired(t, k)
+}
+
 func TestFilenameWithBadChars(t *testing.T) {
 	a := `abc/.././123`
 	e := filenameEscape(a)
@@ -61,3 +150,304 @@ func TestFilenameWithBadChars(t *testing.T) {
 		t.Fatal("Unexpected filenameEscape")
 	}
 }
+
+func TestFileKeyringContextCancelled(t *testing.T) {
+	k := &fileKeyring{
+		dir: t.TempDir(),
+		passwordFunc: func(string) (string, error) {
+			t.Fatal("prompted for a passphrase after the context was cancelled")
+			return "", nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if err := k.SetContext(ctx, Item{Key: "llamas", Data: []byte("llamas are great")}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SetContext err = %v, want context.Canceled", err)
+	}
+	if _, err := k.GetContext(ctx, "llamas"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetContext err = %v, want context.Canceled", err)
+	}
+}
+
+func TestFileKeyringWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	k := &fileKeyring{dir: dir, passwordFunc: FixedStringPrompt("no more secrets")}
+	if err := k.Set(Item{Key: "llamas", Data: []byte("llamas are great")}); err != nil {
+		t.Fatal(err)
+	}
+
+	k = &fileKeyring{dir: dir, passwordFunc: FixedStringPrompt("wrong")}
+	if _, err := k.Get("llamas"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Get err = %v, want ErrWrongPassphrase", err)
+	}
+	if k.password != "" {
+		t.Fatal("wrong passphrase was kept for the next attempt")
+	}
+}
+
+func TestFileKeyringCapabilities(t *testing.T) {
+	caps, ok := BackendInfo(FileBackend)
+	if !ok {
+		t.Fatal("file backend has no capabilities")
+	}
+	if caps.Metadata != MetadataSupported || !caps.Label || !caps.Description || caps.Remote || !caps.PlaintextMetadata {
+		t.Fatalf("unexpected file capabilities: %+v", caps)
+	}
+}
+
+func TestFileKeyringProbe(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	k := &fileKeyring{dir: dir, passwordFunc: FixedStringPrompt("no more secrets")}
+
+	if err := k.probe(); err != nil {
+		t.Fatalf("probe of a missing directory: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatal("probe created the directory")
+	}
+
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := k.probe(); err == nil {
+			t.Fatal("probe accepted a directory readable by other users")
+		}
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.probe(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+
+	k.passwordFunc = nil
+	if err := k.probe(); err == nil {
+		t.Fatal("probe accepted a keyring with no passphrase prompt")
+	}
+}
+
+func TestOpenWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	k, err := Open(Config{
+		AllowedBackends:  []BackendType{FileBackend},
+		FileDir:          t.TempDir(),
+		FilePasswordFunc: FixedStringPrompt("no more secrets"),
+		Logger:           slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The backend logs its own calls, so it is not wrapped and keeps its
+	// optional interfaces.
+	if _, ok := k.(*fileKeyring); !ok {
+		t.Fatalf("Expected a *fileKeyring, got %T", k)
+	}
+	if err := k.Set(Item{Key: "llamas", Data: []byte("llamas are great")}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"backend":"file","op":"set","key":"llamas"`) {
+		t.Fatalf("The Set was not logged: %s", buf.String())
+	}
+	if _, err := History(k, "llamas"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"backend":"file","op":"history","key":"llamas"`) {
+		t.Fatalf("The History was not logged: %s", buf.String())
+	}
+}
+
+func TestFileKeyringCompareAndSwap(t *testing.T) {
+	k := &fileKeyring{
+		dir:          t.TempDir(),
+		passwordFunc: FixedStringPrompt("no more secrets"),
+	}
+
+	if err := SetIfAbsent(k, Item{Key: "token", Data: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetIfAbsent(k, Item{Key: "token", Data: []byte("other")}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict setting a taken key, got %v", err)
+	}
+
+	item, version, err := GetVersioned(k, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(item.Data) != "1" {
+		t.Fatalf("Expected the first value, got %q", item.Data)
+	}
+
+	// Another writer refreshes the token first.
+	if err := k.Set(Item{Key: "token", Data: []byte("2")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareAndSwap(k, "token", version, Item{Data: []byte("3")}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict swapping a changed item, got %v", err)
+	}
+
+	_, version, err = GetVersioned(k, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareAndSwap(k, "token", version, Item{Data: []byte("3")}); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := k.Get("token"); string(item.Data) != "3" || item.Key != "token" {
+		t.Fatalf("Expected token to be swapped to 3, got %+v", item)
+	}
+
+	if err := k.Remove("token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareAndSwap(k, "token", version, Item{Data: []byte("4")}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict swapping a removed item, got %v", err)
+	}
+}
+
+func TestFileKeyringHistory(t *testing.T) {
+	k := &fileKeyring{
+		dir:          t.TempDir(),
+		passwordFunc: FixedStringPrompt("no more secrets"),
+		history:      2,
+	}
+
+	for _, data := range []string{"1", "2", "3", "4"} {
+		if err := k.Set(Item{Key: "token", Data: []byte(data)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := History(k, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, rev := range revisions {
+		versions = append(versions, rev.Version)
+		if rev.Modified.IsZero() {
+			t.Errorf("Expected version %s to have a modification time", rev.Version)
+		}
+	}
+	// The first value has been pruned.
+	if !slices.Equal(versions, []string{"3", "2"}) {
+		t.Fatalf("Expected versions 3 and 2, got %v", versions)
+	}
+
+	if keys, _ := k.Keys(); !slices.Equal(keys, []string{"token"}) {
+		t.Fatalf("Expected the history to stay out of the keys, got %v", keys)
+	}
+
+	if err := Restore(k, "token", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := k.Get("token"); string(item.Data) != "2" {
+		t.Fatalf("Expected the restored value 2, got %q", item.Data)
+	}
+	if err := Restore(k, "token", "1"); !errors.Is(err, ErrRevisionNotFound) {
+		t.Fatalf("Expected ErrRevisionNotFound restoring a pruned version, got %v", err)
+	}
+	if err := Restore(k, "token", "../token"); !errors.Is(err, ErrRevisionNotFound) {
+		t.Fatalf("Expected ErrRevisionNotFound restoring a bad version, got %v", err)
+	}
+
+	// A removed item can be brought back.
+	if err := k.Remove("token"); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err = History(k, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(k, "token", revisions[0].Version); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := k.Get("token"); string(item.Data) != "2" {
+		t.Fatalf("Expected the removed value 2 back, got %q", item.Data)
+	}
+
+	if _, err := History(k, "missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound for the history of a missing key, got %v", err)
+	}
+}
+
+func TestFileKeyringRefusesTheHistoryKey(t *testing.T) {
+	k := &fileKeyring{
+		dir:          t.TempDir(),
+		passwordFunc: FixedStringPrompt("no more secrets"),
+		history:      2,
+	}
+	if err := k.Set(Item{Key: "token", Data: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Set(Item{Key: "token", Data: []byte("2")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{fileHistoryDir, "..", ""} {
+		if err := k.Set(Item{Key: key, Data: []byte("x")}); err == nil {
+			t.Errorf("Expected an error setting the key %q", key)
+		}
+		if err := k.Remove(key); err == nil || errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected an error removing the key %q, got %v", key, err)
+		}
+	}
+
+	if revisions, err := History(k, "token"); err != nil || len(revisions) != 1 {
+		t.Fatalf("Expected the history to be intact, got %v, %v", revisions, err)
+	}
+}
+
+func TestFileKeyringRotate(t *testing.T) {
+	k := &fileKeyring{
+		dir:          t.TempDir(),
+		passwordFunc: FixedStringPrompt("no more secrets"),
+	}
+	if err := k.Set(Item{Key: "aws", Data: []byte("old")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.SetMetadata("aws", map[string]string{"owner": "ops"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var verified string
+	err := Rotate(t.Context(), k, "aws", func(old Item) (Item, error) {
+		if string(old.Data) != "old" {
+			t.Errorf("Expected to rotate the old item, got %q", old.Data)
+		}
+		if backup, err := k.Get("aws" + DefaultBackupSuffix); err != nil || string(backup.Data) != "old" {
+			t.Errorf("Expected the old item to be backed up, got %q, %v", backup.Data, err)
+		}
+		return Item{Data: []byte("new")}, nil
+	}, RotateOptions{
+		Verify: func(_ context.Context, item Item) error {
+			verified = string(item.Data)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified != "new" {
+		t.Fatalf("Expected the new item to be verified, got %q", verified)
+	}
+	if item, _ := k.Get("aws"); string(item.Data) != "new" {
+		t.Fatalf("Expected the new item to be stored, got %q", item.Data)
+	}
+	if _, err := k.Get("aws" + DefaultBackupSuffix); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected the backup to be removed, got %v", err)
+	}
+
+	md, err := k.GetMetadata("aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Fields["owner"] != "ops" {
+		t.Fatalf("Expected the other fields to be kept, got %v", md.Fields)
+	}
+	if rotated := LastRotated(md); time.Since(rotated) > time.Minute {
+		t.Fatalf("Expected the rotation time to be recorded, got %v", rotated)
+	}
+}
@@ -0,0 +1,177 @@
+package keyring
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrHistoryNotSupported is returned by History and Restore for keyrings that
+// do not implement HistoryKeyring.
+var ErrHistoryNotSupported = errors.New("the keyring backend does not keep earlier versions of items")
+
+// ErrRevisionNotFound is returned by Restore when the item has no earlier
+// version with the given identifier, for example because it has been pruned.
+var ErrRevisionNotFound = errors.New("the item has no such earlier version")
+
+// Revision is an earlier version of an item, one that has since been
+// replaced or removed.
+type Revision struct {
+	// Version identifies the revision to Restore. It is opaque, and unrelated
+	// to the versions used by CompareAndSwap.
+	Version string
+
+	// Modified is when this version of the item was written, if the backend
+	// records it.
+	Modified time.Time
+}
+
+// HistoryKeyring is implemented by keyrings that keep earlier versions of
+// their items.
+type HistoryKeyring interface {
+	// Returns the earlier versions of the item for key, newest first
+	HistoryContext(ctx context.Context, key string) ([]Revision, error)
+	// Makes an earlier version of the item for key the current one
+	RestoreContext(ctx context.Context, key, version string) error
+}
+
+// History returns the earlier versions of the item for key in k. See
+// HistoryContext.
+func History(k Keyring, key string) ([]Revision, error) {
+	return HistoryContext(context.Background(), k, key)
+}
+
+// HistoryContext returns the earlier versions of the item for key in k that
+// the backend has kept, newest first. The current version is not among them.
+// It returns ErrHistoryNotSupported if k does not implement HistoryKeyring.
+func HistoryContext(ctx context.Context, k Keyring, key string) ([]Revision, error) {
+	hk, ok := k.(HistoryKeyring)
+	if !ok {
+		return nil, ErrHistoryNotSupported
+	}
+	return hk.HistoryContext(ctx, key)
+}
+
+// Restore brings back an earlier version of the item for key in k. See
+// RestoreContext.
+func Restore(k Keyring, key, version string) error {
+	return RestoreContext(context.Background(), k, key, version)
+}
+
+// RestoreContext stores the earlier version of the item for key identified by
+// version, as returned by History, as its current version. The version being
+// replaced is kept in the history in turn, so a restore can be undone. Where
+// the backend keeps removed items, this brings a removed item back too.
+//
+// It returns ErrRevisionNotFound if there is no such version, and
+// ErrHistoryNotSupported if k does not implement HistoryKeyring.
+func RestoreContext(ctx context.Context, k Keyring, key, version string) error {
+	hk, ok := k.(HistoryKeyring)
+	if !ok {
+		return ErrHistoryNotSupported
+	}
+	return hk.RestoreContext(ctx, key, version)
+}
+
+// gitHistory returns the earlier versions of the item stored in the file at
+// path, in a pass or passage store under root that is kept in git: the
+// commits that wrote the file, newest first, less those that wrote what it
+// holds now. git runs git in the store with the store's own command, such
+// as "pass git", which fails if the store is not in a git repository.
+func gitHistory(ctx context.Context, git func(ctx context.Context, args ...string) ([]byte, error), root, path string) ([]Revision, error) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, err
+	}
+	rel = filepath.ToSlash(rel)
+
+	out, err := git(ctx, "log", "--no-abbrev", "--raw", "--diff-filter=AM", "--format=commit %H %ct", "--", rel)
+	if err != nil {
+		return nil, err
+	}
+
+	var current string
+	if _, err := os.Stat(path); err == nil {
+		blob, err := git(ctx, "hash-object", "--", rel)
+		if err != nil {
+			return nil, err
+		}
+		current = strings.TrimSpace(string(blob))
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	revisions := []Revision{}
+	var rev Revision
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if commit, ok := strings.CutPrefix(line, "commit "); ok {
+			hash, ts, _ := strings.Cut(commit, " ")
+			rev = Revision{Version: hash}
+			if secs, err := strconv.ParseInt(ts, 10, 64); err == nil {
+				rev.Modified = time.Unix(secs, 0)
+			}
+			continue
+		}
+		// A raw diff line, ":100644 100644 <old blob> <new blob> M\t<path>"
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(line, ":") || rev.Version == "" {
+			continue
+		}
+		if fields[3] != current {
+			revisions = append(revisions, rev)
+		}
+		rev = Revision{}
+	}
+	if len(revisions) == 0 && current == "" {
+		return nil, ErrKeyNotFound
+	}
+	return revisions, nil
+}
+
+// gitRestore checks out the file at path, in a store kept in git as for
+// gitHistory, as it was written by the commit version and commits it.
+func gitRestore(ctx context.Context, git func(ctx context.Context, args ...string) ([]byte, error), root, path, version string) error {
+	revisions, err := gitHistory(ctx, git, root, path)
+	if errors.Is(err, ErrKeyNotFound) {
+		return ErrRevisionNotFound
+	} else if err != nil {
+		return err
+	}
+	// Only versions listed in the history are taken, so that version is a
+	// commit hash rather than anything git might read as an option.
+	if !slices.ContainsFunc(revisions, func(rev Revision) bool { return rev.Version == version }) {
+		return ErrRevisionNotFound
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+	if _, err := git(ctx, "checkout", version, "--", rel); err != nil {
+		return err
+	}
+	_, err = git(ctx, "commit", "-m", fmt.Sprintf("Restore %s from %s.", strings.TrimSuffix(rel, filepath.Ext(rel)), version[:min(len(version), 12)]), "--", rel)
+	return err
+}
+
+// gitStoreError maps git's complaint that a store is not kept in git onto
+// ErrHistoryNotSupported, leaving the rest to classify.
+func gitStoreError(classifyStderr func(stderr string) error) func(stderr string) error {
+	return func(stderr string) error {
+		if strings.Contains(strings.ToLower(stderr), "not a git repository") {
+			return ErrHistoryNotSupported
+		}
+		return classifyStderr(stderr)
+	}
+}
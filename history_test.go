@@ -0,0 +1,92 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGitHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "keyring")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "keyring@example.com")
+	}
+
+	root := t.TempDir()
+	git := func(ctx context.Context, args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", root}, args...)...)
+		return runCommand(ctx, cmd, gitStoreError(func(string) error { return nil }))
+	}
+	path := filepath.Join(root, "aws", "dev.gpg")
+
+	if _, err := gitHistory(t.Context(), git, root, path); !errors.Is(err, ErrHistoryNotSupported) {
+		t.Fatalf("Expected ErrHistoryNotSupported outside a git repository, got %v", err)
+	}
+
+	if _, err := git(t.Context(), "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	var commits []string
+	for _, data := range []string{"1", "2", "3"} {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := git(t.Context(), "add", "aws/dev.gpg"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := git(t.Context(), "commit", "-q", "-m", "Set "+data); err != nil {
+			t.Fatal(err)
+		}
+		head, err := git(t.Context(), "rev-parse", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, strings.TrimSpace(string(head)))
+	}
+
+	versions := func() []string {
+		t.Helper()
+		revisions, err := gitHistory(t.Context(), git, root, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var versions []string
+		for _, rev := range revisions {
+			versions = append(versions, rev.Version)
+		}
+		return versions
+	}
+	if got, want := versions(), []string{commits[1], commits[0]}; !slices.Equal(got, want) {
+		t.Fatalf("Expected the commits before the last, newest first, got %v", got)
+	}
+
+	if err := gitRestore(t.Context(), git, root, path, "--help"); !errors.Is(err, ErrRevisionNotFound) {
+		t.Fatalf("Expected ErrRevisionNotFound for a bad version, got %v", err)
+	}
+	if err := gitRestore(t.Context(), git, root, path, commits[0]); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "1" {
+		t.Fatalf("Expected the first version back, got %q", data)
+	}
+	if status, _ := git(t.Context(), "status", "--porcelain"); len(status) != 0 {
+		t.Fatalf("Expected the restore to be committed, got status %q", status)
+	}
+	// The first commit now holds what the file does.
+	if got, want := versions(), []string{commits[2], commits[1]}; !slices.Equal(got, want) {
+		t.Fatalf("Expected the history after the restore to be %v, got %v", want, got)
+	}
+}
@@ -48,6 +48,7 @@ type API interface {
 	Authenticate(ctx context.Context, pat string) (*Session, error)
 	ListShares(ctx context.Context, s *Session) ([]Share, error)
 	ListItems(ctx context.Context, s *Session, shareID string) ([]ItemRevision, error)
+	ListItemRevisions(ctx context.Context, s *Session, shareID, itemID string) ([]ItemRevision, error)
 	GetShareKeys(ctx context.Context, s *Session, shareID string) ([]ShareKey, error)
 	CreateItem(ctx context.Context, s *Session, shareID string, req CreateItemRequest) (*ItemRevision, error)
 	UpdateItem(ctx context.Context, s *Session, shareID, itemID string, req UpdateItemRequest) (*ItemRevision, error)
@@ -280,6 +281,36 @@ func (c *Client) ListItems(ctx context.Context, s *Session, shareID string) ([]I
 	return nil, fmt.Errorf("list items in share %q: more than %d items (reached the %d-page cap); refusing to return a truncated list", shareID, maxItemPages*itemPageSize, maxItemPages)
 }
 
+// ListItemRevisions returns every revision Proton Pass keeps of one item, the
+// current one included, following pagination.
+func (c *Client) ListItemRevisions(ctx context.Context, s *Session, shareID, itemID string) ([]ItemRevision, error) {
+	var all []ItemRevision
+	since := ""
+	for page := 0; page < maxItemPages; page++ {
+		path := fmt.Sprintf("%s/%s/item/%s/revision?PageSize=%d", pathShares, shareID, itemID, itemPageSize)
+		if since != "" {
+			path += "&Since=" + url.QueryEscape(since)
+		}
+		var resp struct {
+			Revisions struct {
+				LastToken     string         `json:"LastToken"`
+				RevisionsData []ItemRevision `json:"RevisionsData"`
+			} `json:"Revisions"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, nil, s.UID, s.AccessToken, &resp); err != nil {
+			return nil, fmt.Errorf("list revisions of item %q in share %q: %w", itemID, shareID, err)
+		}
+		all = append(all, resp.Revisions.RevisionsData...)
+		if resp.Revisions.LastToken == "" || len(resp.Revisions.RevisionsData) == 0 {
+			return all, nil
+		}
+		since = resp.Revisions.LastToken
+	}
+	// Unlike a truncated item list, a truncated history only loses the oldest
+	// revisions, but say so rather than pass it off as complete.
+	return nil, fmt.Errorf("list revisions of item %q in share %q: more than %d revisions (reached the %d-page cap)", itemID, shareID, maxItemPages*itemPageSize, maxItemPages)
+}
+
 // GetShareKeys returns the (still-encrypted) key rotations for a share.
 func (c *Client) GetShareKeys(ctx context.Context, s *Session, shareID string) ([]ShareKey, error) {
 	var resp struct {
@@ -215,6 +215,34 @@ func TestListItemsPagination(t *testing.T) {
 	}
 }
 
+func TestListItemRevisions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pass/v1/share/{shareID}/item/{itemID}/revision", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("shareID") != testShareID || r.PathValue("itemID") != "item1" {
+			t.Errorf("revisions path = share %q item %q", r.PathValue("shareID"), r.PathValue("itemID"))
+		}
+		switch r.URL.Query().Get("Since") {
+		case "":
+			writeJSON(w, map[string]any{"Code": 1000, "Revisions": map[string]any{
+				"LastToken": "next", "RevisionsData": []map[string]any{{"ItemID": "item1", "Revision": 3}},
+			}})
+		default:
+			writeJSON(w, map[string]any{"Code": 1000, "Revisions": map[string]any{
+				"RevisionsData": []map[string]any{{"ItemID": "item1", "Revision": 2}},
+			}})
+		}
+	})
+
+	c := newTestClient(t, mux)
+	revs, err := c.ListItemRevisions(context.Background(), &Session{UID: "u", AccessToken: "a"}, testShareID, "item1")
+	if err != nil {
+		t.Fatalf("ListItemRevisions: %v", err)
+	}
+	if len(revs) != 2 || revs[0].Revision != 3 || revs[1].Revision != 2 {
+		t.Fatalf("unexpected revisions: %+v", revs)
+	}
+}
+
 func TestGetShareKeys(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /pass/v1/share/{shareID}/key", func(w http.ResponseWriter, r *http.Request) {
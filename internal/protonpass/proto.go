@@ -3,6 +3,8 @@ package protonpass
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 
 	"google.golang.org/protobuf/encoding/protowire"
 )
@@ -10,31 +12,40 @@ import (
 // Field numbers from Proton Pass's item-v1.proto (the wire contract, re-declared
 // clean-room from the documented schema, not generated):
 //
-//	message Item     { Metadata metadata = 1; Content content = 2; ... }
-//	message Metadata { string name = 1; string note = 2; string item_uuid = 3; ... }
-//	message Content  { oneof content { ItemNote note = 2; ... } }
-//	message ItemNote {}   // empty marker; the note text lives in Metadata.note
+//	message Item           { Metadata metadata = 1; Content content = 2; ...; repeated ExtraField extra_fields = 4; }
+//	message Metadata       { string name = 1; string note = 2; string item_uuid = 3; ... }
+//	message Content        { oneof content { ItemNote note = 2; ... } }
+//	message ItemNote       {}   // empty marker; the note text lives in Metadata.note
+//	message ExtraField     { string field_name = 1; oneof content { ...; ExtraTextField text = 3; ... } }
+//	message ExtraTextField { string content = 1; }
 //
 // aws-vault keys items by metadata.name and stores its blob in metadata.note.
+// Metadata fields set with SetMetadata are the item's text extra fields.
 const (
 	fieldItemMetadata     = 1
 	fieldItemContent      = 2
+	fieldItemExtraField   = 4
 	fieldMetadataName     = 1
 	fieldMetadataNote     = 2
 	fieldMetadataItemUUID = 3
 	fieldContentNote      = 2
+	fieldExtraFieldName   = 1
+	fieldExtraFieldText   = 3
+	fieldExtraTextContent = 1
 )
 
-// ItemMetadata is the subset of a decrypted Item the backend uses: the title and
-// the note payload.
+// ItemMetadata is the subset of a decrypted Item the backend uses: the title,
+// the note payload and the text extra fields, by name.
 type ItemMetadata struct {
-	Name string
-	Note string
+	Name   string
+	Note   string
+	Fields map[string]string
 }
 
-// ParseItemMetadata extracts Metadata.name and Metadata.note from a decrypted Item
-// protobuf (the plaintext returned by OpenItemContent). Unknown fields and other
-// content types are skipped.
+// ParseItemMetadata extracts Metadata.name, Metadata.note and the text extra
+// fields from a decrypted Item protobuf (the plaintext returned by
+// OpenItemContent). Unknown fields, other content types and other kinds of extra
+// field are skipped.
 func ParseItemMetadata(item []byte) (ItemMetadata, error) {
 	meta, found, err := bytesField(item, fieldItemMetadata)
 	if err != nil {
@@ -52,12 +63,49 @@ func ParseItemMetadata(item []byte) (ItemMetadata, error) {
 	if err != nil {
 		return ItemMetadata{}, fmt.Errorf("metadata: read note: %w", err)
 	}
-	return ItemMetadata{Name: string(name), Note: string(note)}, nil
+	fields, err := parseExtraFields(item)
+	if err != nil {
+		return ItemMetadata{}, err
+	}
+	return ItemMetadata{Name: string(name), Note: string(note), Fields: fields}, nil
+}
+
+// parseExtraFields returns the text extra fields of an Item, or nil if it has
+// none.
+func parseExtraFields(item []byte) (map[string]string, error) {
+	extras, err := bytesFields(item, fieldItemExtraField)
+	if err != nil {
+		return nil, fmt.Errorf("item: read extra fields: %w", err)
+	}
+	var fields map[string]string
+	for _, extra := range extras {
+		name, _, err := bytesField(extra, fieldExtraFieldName)
+		if err != nil {
+			return nil, fmt.Errorf("extra field: read name: %w", err)
+		}
+		text, found, err := bytesField(extra, fieldExtraFieldText)
+		if err != nil {
+			return nil, fmt.Errorf("extra field: read text: %w", err)
+		}
+		if !found {
+			continue // a TOTP, hidden or other kind of field
+		}
+		content, _, err := bytesField(text, fieldExtraTextContent)
+		if err != nil {
+			return nil, fmt.Errorf("extra field: read content: %w", err)
+		}
+		if fields == nil {
+			fields = map[string]string{}
+		}
+		fields[string(name)] = string(content)
+	}
+	return fields, nil
 }
 
 // EncodeItem serializes a note-type item-v1 Item protobuf: metadata.name (title),
 // metadata.note (the blob), and metadata.item_uuid, plus a Content oneof selecting
-// the empty ItemNote variant. It is the inverse of ParseItemMetadata.
+// the empty ItemNote variant and a text extra field for each of meta.Fields, in
+// order of name. It is the inverse of ParseItemMetadata.
 func EncodeItem(meta ItemMetadata, itemUUID string) []byte {
 	var m []byte
 	m = protowire.AppendTag(m, fieldMetadataName, protowire.BytesType)
@@ -77,26 +125,51 @@ func EncodeItem(meta ItemMetadata, itemUUID string) []byte {
 	item = protowire.AppendBytes(item, m)
 	item = protowire.AppendTag(item, fieldItemContent, protowire.BytesType)
 	item = protowire.AppendBytes(item, content)
+
+	for _, name := range slices.Sorted(maps.Keys(meta.Fields)) {
+		var text []byte
+		text = protowire.AppendTag(text, fieldExtraTextContent, protowire.BytesType)
+		text = protowire.AppendBytes(text, []byte(meta.Fields[name]))
+
+		var extra []byte
+		extra = protowire.AppendTag(extra, fieldExtraFieldName, protowire.BytesType)
+		extra = protowire.AppendBytes(extra, []byte(name))
+		extra = protowire.AppendTag(extra, fieldExtraFieldText, protowire.BytesType)
+		extra = protowire.AppendBytes(extra, text)
+
+		item = protowire.AppendTag(item, fieldItemExtraField, protowire.BytesType)
+		item = protowire.AppendBytes(item, extra)
+	}
 	return item
 }
 
 // bytesField returns the last length-delimited field number `num` in msg. found is
 // false (with a nil slice) when the field is absent.
 func bytesField(msg []byte, num protowire.Number) (value []byte, found bool, err error) {
+	values, err := bytesFields(msg, num)
+	if err != nil || len(values) == 0 {
+		return nil, false, err
+	}
+	return values[len(values)-1], true, nil // keep the last occurrence (proto3 semantics)
+}
+
+// bytesFields returns every length-delimited field number `num` in msg, in order,
+// as for a repeated field.
+func bytesFields(msg []byte, num protowire.Number) (values [][]byte, err error) {
 	for len(msg) > 0 {
 		fieldNum, typ, tagLen := protowire.ConsumeTag(msg)
 		if tagLen < 0 {
-			return nil, false, protowire.ParseError(tagLen)
+			return nil, protowire.ParseError(tagLen)
 		}
 		msg = msg[tagLen:]
 
 		if typ == protowire.BytesType {
 			v, vLen := protowire.ConsumeBytes(msg)
 			if vLen < 0 {
-				return nil, false, protowire.ParseError(vLen)
+				return nil, protowire.ParseError(vLen)
 			}
 			if fieldNum == num {
-				value, found = v, true // keep the last occurrence (proto3 semantics)
+				values = append(values, v)
 			}
 			msg = msg[vLen:]
 			continue
@@ -104,9 +177,9 @@ func bytesField(msg []byte, num protowire.Number) (value []byte, found bool, err
 
 		skip := protowire.ConsumeFieldValue(fieldNum, typ, msg)
 		if skip < 0 {
-			return nil, false, protowire.ParseError(skip)
+			return nil, protowire.ParseError(skip)
 		}
 		msg = msg[skip:]
 	}
-	return value, found, nil
+	return values, nil
 }
@@ -1,6 +1,7 @@
 package protonpass
 
 import (
+	"maps"
 	"testing"
 
 	"google.golang.org/protobuf/encoding/protowire"
@@ -92,6 +93,32 @@ func TestEncodeItemRoundTrip(t *testing.T) {
 	}
 }
 
+func TestEncodeItemExtraFieldsRoundTrip(t *testing.T) {
+	fields := map[string]string{"owner": "ops", "ticket": "SEC-1"}
+	raw := EncodeItem(ItemMetadata{Name: "t", Note: "n", Fields: fields}, "uuid")
+
+	// A non-text extra field (hidden = 4) is skipped rather than misread.
+	var hidden []byte
+	hidden = protowire.AppendTag(hidden, fieldExtraFieldName, protowire.BytesType)
+	hidden = protowire.AppendBytes(hidden, []byte("pin"))
+	hidden = protowire.AppendTag(hidden, 4, protowire.BytesType)
+	hidden = protowire.AppendBytes(hidden, nil)
+	raw = protowire.AppendTag(raw, fieldItemExtraField, protowire.BytesType)
+	raw = protowire.AppendBytes(raw, hidden)
+
+	got, err := ParseItemMetadata(raw)
+	if err != nil {
+		t.Fatalf("ParseItemMetadata: %v", err)
+	}
+	if !maps.Equal(got.Fields, fields) {
+		t.Fatalf("Fields = %v, want %v", got.Fields, fields)
+	}
+
+	if got, _ := ParseItemMetadata(EncodeItem(ItemMetadata{Name: "t"}, "uuid")); got.Fields != nil {
+		t.Fatalf("Fields = %v, want nil for an item without extra fields", got.Fields)
+	}
+}
+
 func TestParseItemMetadataMissing(t *testing.T) {
 	// An Item with no metadata field is an error.
 	var item []byte
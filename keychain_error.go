@@ -0,0 +1,32 @@
+//go:build darwin
+
+package keyring
+
+import (
+	"errors"
+
+	gokeychain "github.com/byteness/go-keychain"
+)
+
+// errSecUserCanceled is not among the OSStatus codes go-keychain exports.
+const errSecUserCanceled = gokeychain.Error(-128)
+
+// keychainError classifies an OSStatus returned by Keychain Services so that
+// it matches the package's sentinel errors.
+func keychainError(err error) error {
+	switch {
+	case errors.Is(err, errSecUserCanceled):
+		return classify(ErrUserCancelled, err)
+	case errors.Is(err, gokeychain.ErrorInteractionNotAllowed):
+		// The keychain is locked and we are not allowed to prompt.
+		return classify(ErrLocked, err)
+	case errors.Is(err, gokeychain.ErrorAuthFailed):
+		return classify(ErrWrongPassphrase, err)
+	case errors.Is(err, gokeychain.ErrorNoAccessForItem):
+		return classify(ErrAccessDenied, err)
+	case errors.Is(err, gokeychain.ErrorNotAvailable):
+		return classify(ErrBackendUnavailable, err)
+	default:
+		return err
+	}
+}
@@ -6,24 +6,42 @@
 package keyring
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 
 	gokeychain "github.com/byteness/go-keychain"
 )
 
 type keychain struct {
+	logger *slog.Logger
+	log    callLogger
+
 	service string
 
 	passwordFunc PromptFunc
 
 	isSynchronizable         bool
 	isAccessibleWhenUnlocked bool
+
+	// isSidecar is set on the keychain that holds the metadata fields of
+	// another's items.
+	isSidecar bool
 }
 
 func init() {
+	backendCapabilities[KeychainBackend] = Capabilities{
+		Metadata:      MetadataSupported,
+		Label:         true,
+		Description:   true,
+		KeychainFlags: true,
+	}
+
 	supportedBackends[KeychainBackend] = opener(func(cfg Config) (Keyring, error) {
 		kc := &keychain{
+			logger:       cfg.logger(),
+			log:          newCallLogger(cfg, KeychainBackend),
 			service:      cfg.ServiceName,
 			passwordFunc: cfg.KeychainPasswordFunc,
 
@@ -38,7 +56,21 @@ func init() {
 	})
 }
 
+// debug logs msg to the keychain's logger.
+func (k *keychain) debug(msg string, args ...any) {
+	loggerOr(k.logger).Debug(msg, args...)
+}
+
 func (k *keychain) Get(key string) (Item, error) {
+	return k.GetContext(context.Background(), key)
+}
+
+func (k *keychain) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
 	query := gokeychain.NewItem()
 	query.SetSecClass(gokeychain.SecClassGenericPassword)
 	query.SetService(k.service)
@@ -47,16 +79,16 @@ func (k *keychain) Get(key string) (Item, error) {
 	query.SetReturnAttributes(true)
 	query.SetReturnData(true)
 
-	debugf("Querying keychain for service=%q, account=%q", k.service, key)
+	k.debug("Querying keychain", "service", k.service, logKeyAttr, key)
 	results, err := gokeychain.QueryItem(query)
 	if err == gokeychain.ErrorItemNotFound || len(results) == 0 {
-		debugf("No results found")
+		k.debug("No results found")
 		return Item{}, ErrKeyNotFound
 	}
 
 	if err != nil {
-		debugf("Error: %#v", err)
-		return Item{}, err
+		k.debug("Querying keychain failed", "error", err)
+		return Item{}, keychainError(err)
 	}
 
 	item := Item{
@@ -65,12 +97,29 @@ func (k *keychain) Get(key string) (Item, error) {
 		Label:       results[0].Label,
 		Description: results[0].Description,
 	}
+	if !k.isSidecar {
+		rec, err := k.sidecar().metadata(ctx, key)
+		if err != nil {
+			return Item{}, err
+		}
+		item.Attributes = rec.Attributes
+		item.Expires = rec.Expires
+	}
 
-	debugf("Found item %q", results[0].Label)
-	return item, nil
+	k.debug("Found item", "label", results[0].Label)
+	return unexpired(item)
 }
 
 func (k *keychain) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+func (k *keychain) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
+
 	query := gokeychain.NewItem()
 	query.SetSecClass(gokeychain.SecClassGenericPassword)
 	query.SetService(k.service)
@@ -80,14 +129,14 @@ func (k *keychain) GetMetadata(key string) (Metadata, error) {
 	query.SetReturnData(false)
 	query.SetReturnRef(true)
 
-	debugf("Querying keychain for metadata of service=%q, account=%q", k.service, key)
+	k.debug("Querying keychain for metadata", "service", k.service, logKeyAttr, key)
 	results, err := gokeychain.QueryItem(query)
 	if err == gokeychain.ErrorItemNotFound || len(results) == 0 {
-		debugf("No results found")
+		k.debug("No results found")
 		return Metadata{}, ErrKeyNotFound
 	} else if err != nil {
-		debugf("Error: %#v", err)
-		return Metadata{}, err
+		k.debug("Querying keychain failed", "error", err)
+		return Metadata{}, keychainError(err)
 	}
 
 	md := Metadata{
@@ -97,13 +146,53 @@ func (k *keychain) GetMetadata(key string) (Metadata, error) {
 			Description: results[0].Description,
 		},
 		ModificationTime: results[0].ModificationDate,
+		CreationTime:     results[0].CreationDate,
 	}
 
-	debugf("Found metadata for %q", md.Item.Label)
+	// Keychain items have no attribute for arbitrary fields, so they, the
+	// item's attributes and its expiry are kept in a sidecar item.
+	if !k.isSidecar {
+		rec, err := k.sidecar().metadata(ctx, key)
+		if err != nil {
+			return Metadata{}, err
+		}
+		md.Fields = rec.Fields
+		md.Attributes = rec.Attributes
+		md.Expires = rec.Expires
+	}
+
+	k.debug("Found metadata", "label", md.Item.Label)
 
 	return md, nil
 }
 
+func (k *keychain) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *keychain) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if _, err := k.GetMetadataContext(ctx, key); err != nil {
+		return err
+	}
+	return k.sidecar().setFields(ctx, key, fields)
+}
+
+// keychainMetadataSuffix is appended to the service name for the sidecar
+// items, which keeps them out of Keys.
+const keychainMetadataSuffix = ".keyring-metadata"
+
+// sidecar returns the store for the items' metadata fields.
+func (k *keychain) sidecar() metadataSidecar {
+	return metadataSidecar{store: &keychain{
+		logger:                   k.logger,
+		service:                  k.service + keychainMetadataSuffix,
+		isSynchronizable:         k.isSynchronizable,
+		isAccessibleWhenUnlocked: k.isAccessibleWhenUnlocked,
+		isSidecar:                true,
+	}}
+}
+
 func (k *keychain) updateItem(kcItem gokeychain.Item, account string) error {
 	queryItem := gokeychain.NewItem()
 	queryItem.SetSecClass(gokeychain.SecClassGenericPassword)
@@ -114,20 +203,29 @@ func (k *keychain) updateItem(kcItem gokeychain.Item, account string) error {
 
 	results, err := gokeychain.QueryItem(queryItem)
 	if err != nil {
-		return fmt.Errorf("failed to query keychain: %v", err)
+		return fmt.Errorf("failed to query keychain: %w", keychainError(err))
 	}
 	if len(results) == 0 {
 		return errors.New("no results")
 	}
 
 	if err := gokeychain.UpdateItem(queryItem, kcItem); err != nil {
-		return fmt.Errorf("failed to update item in keychain: %v", err)
+		return fmt.Errorf("failed to update item in keychain: %w", keychainError(err))
 	}
 
 	return nil
 }
 
 func (k *keychain) Set(item Item) error {
+	return k.SetContext(context.Background(), item)
+}
+
+func (k *keychain) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	kcItem := gokeychain.NewItem()
 	kcItem.SetSecClass(gokeychain.SecClassGenericPassword)
 	kcItem.SetService(k.service)
@@ -144,49 +242,75 @@ func (k *keychain) Set(item Item) error {
 		kcItem.SetAccessible(gokeychain.AccessibleWhenUnlocked)
 	}
 
-	err := gokeychain.AddItem(kcItem)
+	err = gokeychain.AddItem(kcItem)
 
 	if err == gokeychain.ErrorDuplicateItem {
-		debugf("Item already exists, updating")
+		k.debug("Item already exists, updating")
 		err = k.updateItem(kcItem, item.Key)
 	}
 
 	if err != nil {
-		return err
+		return keychainError(err)
 	}
 
-	return nil
+	if k.isSidecar {
+		return nil
+	}
+	return k.sidecar().itemSet(ctx, item)
 }
 
 func (k *keychain) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *keychain) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	item := gokeychain.NewItem()
 	item.SetSecClass(gokeychain.SecClassGenericPassword)
 	item.SetService(k.service)
 	item.SetAccount(key)
 
-	debugf("Removing keychain item service=%q, account=%q", k.service, key)
-	err := gokeychain.DeleteItem(item)
+	k.debug("Removing keychain item", "service", k.service, logKeyAttr, key)
+	err = gokeychain.DeleteItem(item)
 	if err == gokeychain.ErrorItemNotFound {
 		return ErrKeyNotFound
+	} else if err != nil {
+		return keychainError(err)
 	}
 
-	return err
+	if k.isSidecar {
+		return nil
+	}
+	return k.sidecar().remove(ctx, key)
 }
 
 func (k *keychain) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+func (k *keychain) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	query := gokeychain.NewItem()
 	query.SetSecClass(gokeychain.SecClassGenericPassword)
 	query.SetService(k.service)
 	query.SetMatchLimit(gokeychain.MatchLimitAll)
 	query.SetReturnAttributes(true)
 
-	debugf("Querying keychain for service=%q", k.service)
+	k.debug("Listing keychain items", "service", k.service)
 	results, err := gokeychain.QueryItem(query)
 	if err != nil {
-		return nil, err
+		return nil, keychainError(err)
 	}
 
-	debugf("Found %d results", len(results))
+	k.debug("Found results", "count", len(results))
 	accountNames := make([]string, len(results))
 	for idx, r := range results {
 		accountNames[idx] = r.Account
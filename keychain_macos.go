@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 
 	gokeychain "github.com/byteness/go-keychain"
@@ -19,6 +20,9 @@ const (
 )
 
 type keychain struct {
+	logger *slog.Logger
+	log    callLogger
+
 	path    string
 	service string
 
@@ -28,6 +32,10 @@ type keychain struct {
 	isAccessibleWhenUnlocked bool
 	isTrusted                bool
 
+	// isSidecar is set on the keychain that holds the metadata fields of
+	// another's items.
+	isSidecar bool
+
 	isTouchIDAuthenticated bool
 	useTouchID             bool
 	touchIDAccount         string
@@ -35,8 +43,19 @@ type keychain struct {
 }
 
 func init() {
+	backendCapabilities[KeychainBackend] = Capabilities{
+		Metadata:           MetadataSupported,
+		Label:              true,
+		Description:        true,
+		KeychainFlags:      true,
+		ListRequiresUnlock: true, // when a custom keychain is protected by Touch ID
+		Interactive:        true,
+	}
+
 	supportedBackends[KeychainBackend] = opener(func(cfg Config) (Keyring, error) {
 		kc := &keychain{
+			logger:       cfg.logger(),
+			log:          newCallLogger(cfg, KeychainBackend),
 			service:      cfg.ServiceName,
 			passwordFunc: cfg.KeychainPasswordFunc,
 
@@ -70,6 +89,25 @@ func init() {
 		}
 		return kc, nil
 	})
+	backendProbes[KeychainBackend] = func(_ context.Context, cfg Config) error {
+		ring, err := supportedBackends[KeychainBackend](cfg)
+		if err != nil {
+			return err
+		}
+		return ring.(*keychain).probe()
+	}
+}
+
+// probe checks that a custom keychain, if configured, can be reached. A
+// keychain that does not exist yet is created by Set.
+func (k *keychain) probe() error {
+	if k.path == "" {
+		return nil
+	}
+	if err := gokeychain.NewWithPath(k.path).Status(); err != nil && !errors.Is(err, gokeychain.ErrorNoSuchKeychain) {
+		return keychainError(err)
+	}
+	return nil
 }
 
 // ensureUnlocked triggers Touch ID authentication and keychain unlock when
@@ -79,7 +117,7 @@ func init() {
 //   - biometrics are not enabled
 //   - Touch ID has already succeeded in this process
 //   - the keychain file does not exist yet
-func (k *keychain) ensureUnlocked() error {
+func (k *keychain) ensureUnlocked(ctx context.Context) error {
 	if k.path == "" || !k.useTouchID || k.isTouchIDAuthenticated {
 		return nil
 	}
@@ -90,14 +128,28 @@ func (k *keychain) ensureUnlocked() error {
 			// Don't try to create it here; creation belongs to the Set path.
 			return nil
 		}
-		return err
+		return keychainError(err)
 	}
-	_, err := k.openWithTouchID()
+	_, err := k.openWithTouchID(ctx)
 	return err
 }
 
+// debug logs msg to the keychain's logger.
+func (k *keychain) debug(msg string, args ...any) {
+	loggerOr(k.logger).Debug(msg, args...)
+}
+
 func (k *keychain) Get(key string) (Item, error) {
-	if err := k.ensureUnlocked(); err != nil {
+	return k.GetContext(context.Background(), key)
+}
+
+func (k *keychain) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
+	if err := k.ensureUnlocked(ctx); err != nil {
 		return Item{}, err
 	}
 
@@ -114,16 +166,16 @@ func (k *keychain) Get(key string) (Item, error) {
 		query.SetMatchSearchList(gokeychain.NewWithPath(k.path))
 	}
 
-	debugf("Querying keychain for service=%q, account=%q, keychain=%q", k.service, key, k.path)
+	k.debug("Querying keychain", "service", k.service, logKeyAttr, key, "keychain", k.path)
 	results, err := gokeychain.QueryItem(query)
 	if err == gokeychain.ErrorItemNotFound || len(results) == 0 {
-		debugf("No results found")
+		k.debug("No results found")
 		return Item{}, ErrKeyNotFound
 	}
 
 	if err != nil {
-		debugf("Error: %#v", err)
-		return Item{}, err
+		k.debug("Querying keychain failed", "error", err)
+		return Item{}, keychainError(err)
 	}
 
 	item := Item{
@@ -132,13 +184,30 @@ func (k *keychain) Get(key string) (Item, error) {
 		Label:       results[0].Label,
 		Description: results[0].Description,
 	}
+	if !k.isSidecar {
+		rec, err := k.sidecar().metadata(ctx, key)
+		if err != nil {
+			return Item{}, err
+		}
+		item.Attributes = rec.Attributes
+		item.Expires = rec.Expires
+	}
 
-	debugf("Found item %q", results[0].Label)
-	return item, nil
+	k.debug("Found item", "label", results[0].Label)
+	return unexpired(item)
 }
 
 func (k *keychain) GetMetadata(key string) (Metadata, error) {
-	if err := k.ensureUnlocked(); err != nil {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+func (k *keychain) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
+
+	if err := k.ensureUnlocked(ctx); err != nil {
 		return Metadata{}, err
 	}
 
@@ -151,14 +220,14 @@ func (k *keychain) GetMetadata(key string) (Metadata, error) {
 	query.SetReturnData(false)
 	query.SetReturnRef(true)
 
-	debugf("Querying keychain for metadata of service=%q, account=%q, keychain=%q", k.service, key, k.path)
+	k.debug("Querying keychain for metadata", "service", k.service, logKeyAttr, key, "keychain", k.path)
 	results, err := gokeychain.QueryItem(query)
 	if err == gokeychain.ErrorItemNotFound || len(results) == 0 {
-		debugf("No results found")
+		k.debug("No results found")
 		return Metadata{}, ErrKeyNotFound
 	} else if err != nil {
-		debugf("Error: %#v", err)
-		return Metadata{}, err
+		k.debug("Querying keychain failed", "error", err)
+		return Metadata{}, keychainError(err)
 	}
 
 	md := Metadata{
@@ -168,13 +237,56 @@ func (k *keychain) GetMetadata(key string) (Metadata, error) {
 			Description: results[0].Description,
 		},
 		ModificationTime: results[0].ModificationDate,
+		CreationTime:     results[0].CreationDate,
+	}
+
+	// Keychain items have no attribute for arbitrary fields, so they, the
+	// item's attributes and its expiry are kept in a sidecar item.
+	if !k.isSidecar {
+		rec, err := k.sidecar().metadata(ctx, key)
+		if err != nil {
+			return Metadata{}, err
+		}
+		md.Fields = rec.Fields
+		md.Attributes = rec.Attributes
+		md.Expires = rec.Expires
 	}
 
-	debugf("Found metadata for %q", md.Label)
+	k.debug("Found metadata", "label", md.Label)
 
 	return md, nil
 }
 
+func (k *keychain) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *keychain) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if _, err := k.GetMetadataContext(ctx, key); err != nil {
+		return err
+	}
+	return k.sidecar().setFields(ctx, key, fields)
+}
+
+// keychainMetadataSuffix is appended to the service name for the sidecar
+// items, which keeps them out of Keys.
+const keychainMetadataSuffix = ".keyring-metadata"
+
+// sidecar returns the store for the items' metadata fields.
+func (k *keychain) sidecar() metadataSidecar {
+	return metadataSidecar{store: &keychain{
+		logger:                   k.logger,
+		service:                  k.service + keychainMetadataSuffix,
+		path:                     k.path,
+		passwordFunc:             k.passwordFunc,
+		isSynchronizable:         k.isSynchronizable,
+		isAccessibleWhenUnlocked: k.isAccessibleWhenUnlocked,
+		isTrusted:                true,
+		isSidecar:                true,
+	}}
+}
+
 func (k *keychain) updateItem(kc gokeychain.Keychain, kcItem gokeychain.Item, account string) error {
 	queryItem := gokeychain.NewItem()
 	queryItem.SetSecClass(gokeychain.SecClassGenericPassword)
@@ -189,7 +301,7 @@ func (k *keychain) updateItem(kc gokeychain.Keychain, kcItem gokeychain.Item, ac
 
 	results, err := gokeychain.QueryItem(queryItem)
 	if err != nil {
-		return fmt.Errorf("failed to query keychain: %v", err)
+		return fmt.Errorf("failed to query keychain: %w", keychainError(err))
 	}
 	if len(results) == 0 {
 		return errors.New("no results")
@@ -199,19 +311,28 @@ func (k *keychain) updateItem(kc gokeychain.Keychain, kcItem gokeychain.Item, ac
 	kcItem.SetAccess(nil)
 
 	if err := gokeychain.UpdateItem(queryItem, kcItem); err != nil {
-		return fmt.Errorf("failed to update item in keychain: %v", err)
+		return fmt.Errorf("failed to update item in keychain: %w", keychainError(err))
 	}
 
 	return nil
 }
 
 func (k *keychain) Set(item Item) error {
+	return k.SetContext(context.Background(), item)
+}
+
+func (k *keychain) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	var kc gokeychain.Keychain
 
 	// when we are setting a value, we create or open
 	if k.path != "" {
 		var err error
-		kc, err = k.createOrOpen()
+		kc, err = k.createOrOpen(ctx)
 		if err != nil {
 			return err
 		}
@@ -240,37 +361,49 @@ func (k *keychain) Set(item Item) error {
 	isTrusted := k.isTrusted && !item.KeychainNotTrustApplication
 
 	if isTrusted {
-		debugf("Keychain item trusts keyring")
+		k.debug("Keychain item trusts keyring")
 		kcItem.SetAccess(&gokeychain.Access{
 			Label:               item.Label,
 			TrustedApplications: nil,
 		})
 	} else {
-		debugf("Keychain item doesn't trust keyring")
+		k.debug("Keychain item doesn't trust keyring")
 		kcItem.SetAccess(&gokeychain.Access{
 			Label:               item.Label,
 			TrustedApplications: []string{},
 		})
 	}
 
-	debugf("Adding service=%q, label=%q, account=%q, trusted=%v to osx keychain %q", k.service, item.Label, item.Key, isTrusted, k.path)
+	k.debug("Adding item to keychain", "service", k.service, "label", item.Label, logKeyAttr, item.Key, "trusted", isTrusted, "keychain", k.path)
 
-	err := gokeychain.AddItem(kcItem)
+	err = gokeychain.AddItem(kcItem)
 
 	if err == gokeychain.ErrorDuplicateItem {
-		debugf("Item already exists, updating")
+		k.debug("Item already exists, updating")
 		err = k.updateItem(kc, kcItem, item.Key)
 	}
 
 	if err != nil {
-		return err
+		return keychainError(err)
 	}
 
-	return nil
+	if k.isSidecar {
+		return nil
+	}
+	return k.sidecar().itemSet(ctx, item)
 }
 
 func (k *keychain) Remove(key string) error {
-	if err := k.ensureUnlocked(); err != nil {
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *keychain) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := k.ensureUnlocked(ctx); err != nil {
 		return err
 	}
 
@@ -292,17 +425,31 @@ func (k *keychain) Remove(key string) error {
 		item.SetMatchSearchList(kc)
 	}
 
-	debugf("Removing keychain item service=%q, account=%q, keychain %q", k.service, key, k.path)
-	err := gokeychain.DeleteItem(item)
+	k.debug("Removing keychain item", "service", k.service, logKeyAttr, key, "keychain", k.path)
+	err = gokeychain.DeleteItem(item)
 	if err == gokeychain.ErrorItemNotFound {
 		return ErrKeyNotFound
+	} else if err != nil {
+		return keychainError(err)
 	}
 
-	return err
+	if k.isSidecar {
+		return nil
+	}
+	return k.sidecar().remove(ctx, key)
 }
 
 func (k *keychain) Keys() ([]string, error) {
-	if err := k.ensureUnlocked(); err != nil {
+	return k.KeysContext(context.Background())
+}
+
+func (k *keychain) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := k.ensureUnlocked(ctx); err != nil {
 		return nil, err
 	}
 
@@ -325,13 +472,13 @@ func (k *keychain) Keys() ([]string, error) {
 		query.SetMatchSearchList(kc)
 	}
 
-	debugf("Querying keychain for service=%q, keychain=%q", k.service, k.path)
+	k.debug("Listing keychain items", "service", k.service, "keychain", k.path)
 	results, err := gokeychain.QueryItem(query)
 	if err != nil {
-		return nil, err
+		return nil, keychainError(err)
 	}
 
-	debugf("Found %d results", len(results))
+	k.debug("Found results", "count", len(results))
 	accountNames := make([]string, len(results))
 	for idx, r := range results {
 		accountNames[idx] = r.Account
@@ -340,27 +487,27 @@ func (k *keychain) Keys() ([]string, error) {
 	return accountNames, nil
 }
 
-func (k *keychain) createOrOpen() (gokeychain.Keychain, error) {
+func (k *keychain) createOrOpen(ctx context.Context) (gokeychain.Keychain, error) {
 	kc := gokeychain.NewWithPath(k.path)
 
-	debugf("Checking keychain status")
+	k.debug("Checking keychain status")
 	err := kc.Status()
 	if err == nil {
 		if k.useTouchID {
-			return k.openWithTouchID()
+			return k.openWithTouchID(ctx)
 		}
-		debugf("Keychain status returned nil, keychain exists")
+		k.debug("Keychain exists", "keychain", k.path)
 		return kc, nil
 	}
 
-	debugf("Keychain status returned error: %v", err)
+	k.debug("Keychain status returned error", "error", err)
 
 	if err != gokeychain.ErrorNoSuchKeychain {
 		return gokeychain.Keychain{}, err
 	}
 
 	if k.passwordFunc == nil {
-		debugf("Creating keychain %s with prompt", k.path)
+		k.debug("Creating keychain with prompt", "keychain", k.path)
 		return gokeychain.NewKeychainWithPrompt(k.path)
 	}
 
@@ -369,24 +516,24 @@ func (k *keychain) createOrOpen() (gokeychain.Keychain, error) {
 		return gokeychain.Keychain{}, err
 	}
 
-	debugf("Creating keychain %s with provided password", k.path)
+	k.debug("Creating keychain with provided password", "keychain", k.path)
 	return gokeychain.NewKeychain(k.path, passphrase)
 }
 
-func (k *keychain) openWithTouchID() (gokeychain.Keychain, error) {
+func (k *keychain) openWithTouchID(ctx context.Context) (gokeychain.Keychain, error) {
 	if k.isTouchIDAuthenticated {
 		// already unlocked, return keychain
 		return gokeychain.NewWithPath(k.path), nil
 	}
 
-	debugf("checking with touchid")
-	if err := touchid.Authenticate(context.Background(), touchid.PolicyDeviceOwnerAuthentication, "unlock "+k.path); err != nil {
-		return gokeychain.Keychain{}, fmt.Errorf("failed to authenticate with biometrics: %w", err)
+	k.debug("Checking with Touch ID")
+	if err := touchid.Authenticate(ctx, touchid.PolicyDeviceOwnerAuthentication, "unlock "+k.path); err != nil {
+		return gokeychain.Keychain{}, fmt.Errorf("failed to authenticate with biometrics: %w", touchIDError(err))
 	}
 
 	k.isTouchIDAuthenticated = true
 
-	debugf("looking up %s password in login.keychain", k.path)
+	k.debug("Looking up keychain password in login.keychain", "keychain", k.path)
 	query := gokeychain.NewItem()
 	query.SetSecClass(gokeychain.SecClassGenericPassword)
 	query.SetService(k.touchIDService)
@@ -407,12 +554,12 @@ func (k *keychain) openWithTouchID() (gokeychain.Keychain, error) {
 			return gokeychain.Keychain{}, fmt.Errorf("failed to setup touchid: %v", err)
 		}
 	} else {
-		debugf("found password in login.keychain, unlocking %s with stored password", k.path)
+		k.debug("Found password in login.keychain, unlocking with stored password", "keychain", k.path)
 		passphrase := string(results[0].Data)
 
 		// try unlocking with the passphrase we found
 		if err := gokeychain.UnlockAtPath(k.path, passphrase); err != nil {
-			return gokeychain.Keychain{}, fmt.Errorf("failed to unlock keychain: %v", err)
+			return gokeychain.Keychain{}, fmt.Errorf("failed to unlock keychain: %w", keychainError(err))
 		}
 	}
 	// either way we've unlocked the keychain so we should be able to return it
@@ -420,13 +567,30 @@ func (k *keychain) openWithTouchID() (gokeychain.Keychain, error) {
 	return gokeychain.NewWithPath(k.path), nil
 }
 
+// touchIDError classifies a LocalAuthentication failure so that it matches
+// the package's sentinel errors.
+func touchIDError(err error) error {
+	switch {
+	case errors.Is(err, touchid.ErrUserCancel), errors.Is(err, touchid.ErrSystemCancel), errors.Is(err, touchid.ErrAppCancel):
+		return classify(ErrUserCancelled, err)
+	case errors.Is(err, touchid.ErrAuthenticationFailed):
+		return classify(ErrAccessDenied, err)
+	case errors.Is(err, touchid.ErrBiometryLockout):
+		return classify(ErrLocked, err)
+	case errors.Is(err, touchid.ErrBiometryNotAvailable), errors.Is(err, touchid.ErrBiometryNotEnrolled), errors.Is(err, touchid.ErrPasscodeNotSet):
+		return classify(ErrBackendUnavailable, err)
+	default:
+		return err
+	}
+}
+
 func (k *keychain) setupTouchID() (string, error) {
 	fmt.Printf("\nTo use Touch ID for authentication, the aws-vault keychain password needs to be stored in your login keychain.\n" +
 		"You will be prompted for the password you use to unlock aws-vault.\n\n")
 
 	var passphrase string
 	if k.passwordFunc == nil {
-		debugf("Creating keychain %s with prompt", k.path)
+		k.debug("Creating keychain with prompt", "keychain", k.path)
 		fmt.Printf("Password for %q: ", k.path)
 		passphraseBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
 		if err != nil {
@@ -443,12 +607,12 @@ func (k *keychain) setupTouchID() (string, error) {
 	}
 
 	fmt.Println()
-	debugf("locking keychain %s", k.path)
+	k.debug("Locking keychain", "keychain", k.path)
 	if err := gokeychain.LockAtPath(k.path); err != nil {
 		return "", fmt.Errorf("failed to lock keychain: %v", err)
 	}
 
-	debugf("unlocking keychain %s", k.path)
+	k.debug("Unlocking keychain", "keychain", k.path)
 	if err := gokeychain.UnlockAtPath(k.path, passphrase); err != nil {
 		return "", fmt.Errorf("failed to unlock keychain: %v", err)
 	}
@@ -462,7 +626,7 @@ func (k *keychain) setupTouchID() (string, error) {
 	item.SetSynchronizable(gokeychain.SynchronizableNo)
 	item.SetAccessible(gokeychain.AccessibleWhenUnlocked)
 
-	debugf("Adding service=%q, account=%q to osx keychain %s", k.touchIDService, k.touchIDAccount, k.path)
+	k.debug("Adding Touch ID password to keychain", "service", k.touchIDService, "account", k.touchIDAccount, "keychain", k.path)
 	if err := gokeychain.AddItem(item); err != nil {
 		return "", fmt.Errorf("failed to add item to keychain: %v", err)
 	}
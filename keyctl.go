@@ -4,10 +4,14 @@
 package keyring
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
@@ -52,11 +56,29 @@ func GetKeyringIDForScope(scope string) (int32, error) {
 type keyctlKeyring struct {
 	keyring int32
 	perm    uint32
+
+	// isSidecar is set for the keyring holding another keyctlKeyring's
+	// metadata, which has no metadata of its own.
+	isSidecar bool
+
+	log callLogger
 }
 
 func init() {
+	backendCapabilities[KeyCtlBackend] = Capabilities{
+		Metadata:    MetadataSupported,
+		Label:       true,
+		Description: true,
+		// The data is stored as a "user" key, whose payload the kernel limits
+		// to 32767 bytes. Everything else goes in a sidecar.
+		MaxItemSize: 32767,
+		// Items with an expiry get a kernel timeout, set with
+		// KEYCTL_SET_TIMEOUT.
+		Expiry: true,
+	}
+
 	supportedBackends[KeyCtlBackend] = opener(func(cfg Config) (Keyring, error) {
-		keyring := keyctlKeyring{}
+		keyring := keyctlKeyring{log: newCallLogger(cfg, KeyCtlBackend)}
 		if cfg.KeyCtlPerm > 0 {
 			keyring.perm = cfg.KeyCtlPerm
 		}
@@ -86,41 +108,211 @@ func init() {
 
 		return &keyring, nil
 	})
+	// The opener creates a missing named keyring, so the probe only looks.
+	backendProbes[KeyCtlBackend] = func(_ context.Context, cfg Config) error {
+		parent, err := getKeyringForScope(cfg.KeyCtlScope)
+		if err != nil {
+			return fmt.Errorf("accessing %q keyring failed: %v", cfg.KeyCtlScope, err)
+		}
+		// Special keyrings that don't exist yet are created on first use.
+		if _, err := unix.KeyctlGetKeyringID(int(parent), false); err != nil && !errors.Is(err, syscall.ENOKEY) {
+			return fmt.Errorf("accessing %q keyring failed: %w", cfg.KeyCtlScope, keyctlError(err))
+		}
+		if cfg.ServiceName != "" {
+			if _, err := keyctlSearch(parent, "keyring", cfg.ServiceName); err != nil && !errors.Is(err, syscall.ENOKEY) {
+				return fmt.Errorf("opening named %q keyring failed: %w", cfg.KeyCtlScope, keyctlError(err))
+			}
+		}
+		return nil
+	}
 }
 
 func (k *keyctlKeyring) Get(name string) (Item, error) {
-	key, err := keyctlSearch(k.keyring, "user", name)
+	return k.GetContext(context.Background(), name)
+}
+
+// GetContext returns the named key. Kernel keyring calls do not block, so the
+// context is only checked up front.
+func (k *keyctlKeyring) GetContext(ctx context.Context, name string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, name)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
+	key, err := keyctlFind(k.keyring, "user", name)
 	if err != nil {
 		if errors.Is(err, syscall.ENOKEY) {
 			return Item{}, ErrKeyNotFound
 		}
-		return Item{}, err
+		return Item{}, keyctlError(err)
 	}
 	// data, err := key.Get()
 	data, err := keyctlRead(key)
 	if err != nil {
-		return Item{}, err
+		return Item{}, keyctlError(err)
 	}
 
 	item := Item{
 		Key:  name,
 		Data: data,
 	}
+	if k.isSidecar {
+		return item, nil
+	}
+
+	md, err := k.GetMetadataContext(ctx, name)
+	if err != nil {
+		return Item{}, err
+	}
+	item.Label = md.Label
+	item.Description = md.Description
+	item.Attributes = md.Attributes
+	item.Expires = md.Expires
+	if bytes.Equal(data, keyctlEmptyPayload) {
+		if empty, err := k.storedEmpty(ctx, name); err != nil {
+			return Item{}, err
+		} else if empty {
+			item.Data = []byte{}
+		}
+	}
 
-	return item, nil
+	// The kernel's timeout is in whole seconds, so it may not have caught up.
+	return unexpired(item)
 }
 
-// GetMetadata for pass returns an error indicating that it's unsupported for this backend.
-// TODO: We can deliver metadata different from the defined ones (e.g. permissions, expire-time, etc).
-func (k *keyctlKeyring) GetMetadata(_ string) (Metadata, error) {
-	return Metadata{}, ErrMetadataNotSupported
+// GetMetadata returns the metadata kept for the key in the sidecar keyring.
+func (k *keyctlKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+func (k *keyctlKeyring) GetMetadataContext(ctx context.Context, name string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, name)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
+
+	if _, err := keyctlFind(k.keyring, "user", name); err != nil {
+		if errors.Is(err, syscall.ENOKEY) {
+			return Metadata{}, ErrKeyNotFound
+		}
+		return Metadata{}, keyctlError(err)
+	}
+
+	sidecar, err := k.sidecar(false)
+	if errors.Is(err, ErrKeyNotFound) {
+		return Metadata{Item: &Item{Key: name}}, nil
+	} else if err != nil {
+		return Metadata{}, err
+	}
+	return sidecar.metadata(ctx, name)
+}
+
+func (k *keyctlKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *keyctlKeyring) SetMetadataContext(ctx context.Context, name string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, name)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := keyctlFind(k.keyring, "user", name); err != nil {
+		if errors.Is(err, syscall.ENOKEY) {
+			return ErrKeyNotFound
+		}
+		return keyctlError(err)
+	}
+
+	sidecar, err := k.sidecar(true)
+	if err != nil {
+		return err
+	}
+	return sidecar.setFields(ctx, name, fields)
+}
+
+// keyctlMetadataKeyring is the keyring, linked into the backend's keyring,
+// that holds the sidecar metadata for its items. Keys only lists "user" keys,
+// so it is not mistaken for an item, and items are only looked up among the
+// backend keyring's own keys, not the sidecar's. The metadata keys'
+// descriptions are prefixed all the same, so the two cannot be confused.
+const (
+	keyctlMetadataKeyring = "keyring-metadata"
+	keyctlMetadataPrefix  = "metadata:"
+)
+
+// keyctlEmptyPayload is stored in place of an item with no data, as the
+// kernel does not accept "user" keys with an empty payload. The item's
+// sidecar record tells it apart from an item whose data is a single NUL.
+var keyctlEmptyPayload = []byte{0}
+
+// storedEmpty reports whether the item for name was stored with no data.
+func (k *keyctlKeyring) storedEmpty(ctx context.Context, name string) (bool, error) {
+	sidecar, err := k.sidecar(false)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	rec, err := sidecar.get(ctx, name)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	}
+	return rec.Empty, err
+}
+
+// sidecar returns the metadata sidecar. It returns ErrKeyNotFound if the
+// sidecar keyring does not exist, unless create is set.
+func (k *keyctlKeyring) sidecar(create bool) (metadataSidecar, error) {
+	id, err := keyctlFind(k.keyring, "keyring", keyctlMetadataKeyring)
+	if err == nil {
+		return k.metadataSidecar(id), nil
+	} else if !errors.Is(err, syscall.ENOKEY) {
+		return metadataSidecar{}, keyctlError(err)
+	}
+
+	if !create {
+		return metadataSidecar{}, ErrKeyNotFound
+	}
+	id, err = k.createNamedKeyring(k.keyring, keyctlMetadataKeyring)
+	if err != nil {
+		return metadataSidecar{}, keyctlError(err)
+	}
+	return k.metadataSidecar(id), nil
+}
+
+func (k *keyctlKeyring) metadataSidecar(id int32) metadataSidecar {
+	return metadataSidecar{
+		store:  &keyctlKeyring{keyring: id, perm: k.perm, isSidecar: true},
+		prefix: keyctlMetadataPrefix,
+	}
 }
 
 func (k *keyctlKeyring) Set(item Item) error {
+	return k.SetContext(context.Background(), item)
+}
+
+func (k *keyctlKeyring) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data := item.Data
+	if len(data) == 0 {
+		data = keyctlEmptyPayload
+	}
+
 	if k.perm == 0 {
 		// Keep the default permissions (alswrv-----v------------)
-		_, err := keyctlAdd(k.keyring, "user", item.Key, item.Data)
-		return err
+		key, err := keyctlAdd(k.keyring, "user", item.Key, data)
+		if err != nil {
+			return keyctlError(err)
+		}
+		if err := keyctlSetTimeout(key, item.Expires); err != nil {
+			return fmt.Errorf("setting timeout failed: %w", keyctlError(err))
+		}
+		return k.recordSet(ctx, item)
 	}
 
 	// By default we loose possession of the key in anything above the session keyring.
@@ -128,41 +320,116 @@ func (k *keyctlKeyring) Set(item Item) error {
 	// cannot change the permissions without possessing the key. Therefore, create the
 	// key in the session keyring, change permissions and then link to the target
 	// keyring and unlink from the intermediate keyring again.
-	key, err := keyctlAdd(unix.KEY_SPEC_SESSION_KEYRING, "user", item.Key, item.Data)
+	key, err := keyctlAdd(unix.KEY_SPEC_SESSION_KEYRING, "user", item.Key, data)
 	if err != nil {
-		return fmt.Errorf("adding key to session failed: %v", err)
+		return fmt.Errorf("adding key to session failed: %w", keyctlError(err))
+	}
+
+	// Set the timeout while the key is still possessed, as the permissions
+	// might not allow it afterwards.
+	if err := keyctlSetTimeout(key, item.Expires); err != nil {
+		return fmt.Errorf("setting timeout failed: %w", keyctlError(err))
 	}
 
 	if err := keyctlSetperm(key, k.perm); err != nil {
-		return fmt.Errorf("setting permission 0x%x failed: %v", k.perm, err)
+		return fmt.Errorf("setting permission 0x%x failed: %w", k.perm, keyctlError(err))
 	}
 
 	if err := keyctlLink(k.keyring, key); err != nil {
-		return fmt.Errorf("linking key to keyring failed: %v", err)
+		return fmt.Errorf("linking key to keyring failed: %w", keyctlError(err))
 	}
 
 	if err := keyctlUnlink(unix.KEY_SPEC_SESSION_KEYRING, key); err != nil {
-		return fmt.Errorf("unlinking key from session failed: %v", err)
+		return fmt.Errorf("unlinking key from session failed: %w", keyctlError(err))
 	}
 
-	return nil
+	return k.recordSet(ctx, item)
+}
+
+// recordSet updates the sidecar metadata for item after it has been stored.
+func (k *keyctlKeyring) recordSet(ctx context.Context, item Item) error {
+	if k.isSidecar {
+		return nil
+	}
+	sidecar, err := k.sidecar(true)
+	if err != nil {
+		return err
+	}
+	if err := sidecar.itemSet(ctx, item); err != nil {
+		return err
+	}
+	if len(item.Data) == 0 {
+		rec, err := sidecar.get(ctx, item.Key)
+		if err != nil {
+			return err
+		}
+		rec.Empty = true
+		if err := sidecar.put(ctx, item.Key, rec); err != nil {
+			return err
+		}
+	}
+
+	// Let the record expire with the item, rather than outlive it. Timeouts
+	// are in whole seconds, so give it one more, or an item could briefly be
+	// left without its record and its expiry.
+	store := sidecar.store.(*keyctlKeyring)
+	id, err := keyctlFind(store.keyring, "user", sidecar.prefix+item.Key)
+	if err != nil {
+		return keyctlError(err)
+	}
+	recordExpires := item.Expires
+	if !recordExpires.IsZero() {
+		recordExpires = recordExpires.Add(time.Second)
+	}
+	return keyctlError(keyctlSetTimeout(id, recordExpires))
 }
 
 func (k *keyctlKeyring) Remove(name string) error {
-	key, err := keyctlSearch(k.keyring, "user", name)
+	return k.RemoveContext(context.Background(), name)
+}
+
+func (k *keyctlKeyring) RemoveContext(ctx context.Context, name string) (err error) {
+	defer k.log.op(ctx, OpRemove, name)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key, err := keyctlFind(k.keyring, "user", name)
 	if err != nil {
 		return ErrKeyNotFound
 	}
 
-	return keyctlUnlink(k.keyring, key)
+	if err := keyctlUnlink(k.keyring, key); err != nil {
+		return keyctlError(err)
+	}
+	if k.isSidecar {
+		return nil
+	}
+
+	sidecar, err := k.sidecar(false)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return sidecar.remove(ctx, name)
 }
 
 func (k *keyctlKeyring) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+func (k *keyctlKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	results := []string{}
 
 	data, err := keyctlRead(k.keyring)
 	if err != nil {
-		return nil, fmt.Errorf("reading keyring failed: %v", err)
+		return nil, fmt.Errorf("reading keyring failed: %w", keyctlError(err))
 	}
 	ids, err := keyctlConvertKeyBuffer(data)
 	if err != nil {
@@ -171,8 +438,10 @@ func (k *keyctlKeyring) Keys() ([]string, error) {
 
 	for _, id := range ids {
 		info, err := keyctlDescribe(id)
-		if err != nil {
-			return nil, err
+		if errors.Is(err, syscall.EKEYEXPIRED) || errors.Is(err, syscall.EKEYREVOKED) {
+			continue // waiting to be garbage collected
+		} else if err != nil {
+			return nil, keyctlError(err)
 		}
 		if info["type"] == "user" {
 			results = append(results, info["description"])
@@ -233,6 +502,23 @@ func getKeyringForScope(scope string) (int32, error) {
 	return 0, fmt.Errorf("unknown scope %q", scope)
 }
 
+// keyctlError classifies an errno returned by a keyctl call so that it matches
+// the package's sentinel errors.
+func keyctlError(err error) error {
+	switch {
+	case errors.Is(err, syscall.EKEYEXPIRED), errors.Is(err, syscall.EKEYREVOKED):
+		// Expired and revoked keys stay visible until the kernel garbage
+		// collects them, but can no longer be read.
+		return classify(ErrKeyNotFound, err)
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return classify(ErrAccessDenied, err)
+	case errors.Is(err, syscall.ENOSYS):
+		return classify(ErrBackendUnavailable, err)
+	default:
+		return err
+	}
+}
+
 func keyctlAdd(parent int32, keytype, key string, data []byte) (int32, error) {
 	id, err := unix.AddKey(keytype, key, data, int(parent))
 	if err != nil {
@@ -249,6 +535,32 @@ func keyctlSearch(id int32, idtype, name string) (int32, error) {
 	return int32(key), nil
 }
 
+// keyctlFind returns the key of type idtype described by name that is linked
+// directly into the keyring id. Unlike keyctlSearch it does not descend into
+// the keyrings linked below id, such as the metadata sidecar, where a key of
+// the same description could be found instead. It returns ENOKEY if there is
+// no such key.
+func keyctlFind(id int32, idtype, name string) (int32, error) {
+	data, err := keyctlRead(id)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := keyctlConvertKeyBuffer(data)
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range ids {
+		info, err := keyctlDescribe(key)
+		if err != nil {
+			continue // expired, revoked or not ours to see
+		}
+		if info["type"] == idtype && info["description"] == name {
+			return key, nil
+		}
+	}
+	return 0, syscall.ENOKEY
+}
+
 func keyctlRead(id int32) ([]byte, error) {
 	var buffer []byte
 
@@ -308,6 +620,18 @@ func keyctlUnlink(parent, child int32) error {
 	return nil
 }
 
+// keyctlSetTimeout makes the kernel expire the key at expires, or never if
+// expires is zero. Updating a key keeps its timeout, so it is always set.
+func keyctlSetTimeout(id int32, expires time.Time) error {
+	var timeout int
+	if !expires.IsZero() {
+		// A timeout of zero would mean none.
+		timeout = max(1, int(math.Ceil(time.Until(expires).Seconds())))
+	}
+	_, err := unix.KeyctlInt(unix.KEYCTL_SET_TIMEOUT, int(id), timeout, 0, 0)
+	return err
+}
+
 func keyctlSetperm(id int32, perm uint32) error {
 	return unix.KeyctlSetperm(int(id), perm)
 }
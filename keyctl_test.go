@@ -6,8 +6,10 @@ package keyring_test
 import (
 	"errors"
 	"math/rand"
+	"runtime"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/byteness/keyring"
 	"golang.org/x/sys/unix"
@@ -248,3 +250,116 @@ func TestKeyCtlListEmptyKeyring(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, keys, 0)
 }
+
+func TestKeyCtlMetadata(t *testing.T) {
+	exists, err := doesNamedKeyringExist()
+	require.Falsef(t, exists, "ring %q already exists in scope %q", ringname, ringparent)
+	require.NoErrorf(t, err, "checking for ring %q in scope %q failed: %v", ringname, ringparent, err)
+	t.Cleanup(cleanupNamedKeyring)
+
+	kr, err := keyring.Open(keyring.Config{
+		AllowedBackends: []keyring.BackendType{keyring.KeyCtlBackend},
+		KeyCtlScope:     ringparent,
+		ServiceName:     ringname,
+	})
+	require.NoError(t, err)
+
+	item := keyring.Item{
+		Key:         "test",
+		Data:        []byte("loose lips sink ships"),
+		Label:       "Test item",
+		Description: "for testing",
+	}
+	require.NoError(t, kr.Set(item))
+	require.NoError(t, keyring.SetMetadata(kr, "test", map[string]string{"owner": "ops"}))
+
+	md, err := kr.GetMetadata("test")
+	require.NoError(t, err)
+	require.Equal(t, "Test item", md.Label)
+	require.Equal(t, map[string]string{"owner": "ops"}, md.Fields)
+	require.False(t, md.CreationTime.IsZero())
+
+	got, err := kr.Get("test")
+	require.NoError(t, err)
+	require.Equal(t, item, got)
+
+	keys, err := kr.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"test"}, keys)
+
+	require.NoError(t, kr.Remove("test"))
+	_, err = kr.GetMetadata("test")
+	require.ErrorIs(t, err, keyring.ErrKeyNotFound)
+	require.ErrorIs(t, keyring.SetMetadata(kr, "test", nil), keyring.ErrKeyNotFound)
+}
+
+func TestKeyCtlMetadataIsNotAnItem(t *testing.T) {
+	exists, err := doesNamedKeyringExist()
+	require.Falsef(t, exists, "ring %q already exists in scope %q", ringname, ringparent)
+	require.NoErrorf(t, err, "checking for ring %q in scope %q failed: %v", ringname, ringparent, err)
+	t.Cleanup(cleanupNamedKeyring)
+
+	kr, err := keyring.Open(keyring.Config{
+		AllowedBackends: []keyring.BackendType{keyring.KeyCtlBackend},
+		KeyCtlScope:     ringparent,
+		ServiceName:     ringname,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, kr.Set(keyring.Item{Key: "test", Data: []byte("loose lips sink ships")}))
+
+	// The sidecar record for "test" is a key described "metadata:test" in a
+	// keyring linked below the backend's.
+	_, err = kr.Get("metadata:test")
+	require.ErrorIs(t, err, keyring.ErrKeyNotFound)
+	_, err = kr.GetMetadata("metadata:test")
+	require.ErrorIs(t, err, keyring.ErrKeyNotFound)
+	require.ErrorIs(t, kr.Remove("metadata:test"), keyring.ErrKeyNotFound)
+
+	md, err := kr.GetMetadata("test")
+	require.NoError(t, err)
+	require.False(t, md.CreationTime.IsZero(), "the sidecar record should be intact")
+}
+
+func TestKeyCtlExpires(t *testing.T) {
+	// The keyring is in the thread keyring, which the goroutine would no
+	// longer possess if it moved to another thread while it sleeps.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	exists, err := doesNamedKeyringExist()
+	require.Falsef(t, exists, "ring %q already exists in scope %q", ringname, ringparent)
+	require.NoErrorf(t, err, "checking for ring %q in scope %q failed: %v", ringname, ringparent, err)
+	t.Cleanup(cleanupNamedKeyring)
+
+	kr, err := keyring.Open(keyring.Config{
+		AllowedBackends: []keyring.BackendType{keyring.KeyCtlBackend},
+		KeyCtlScope:     ringparent,
+		ServiceName:     ringname,
+	})
+	require.NoError(t, err)
+
+	expires := time.Now().Add(time.Second)
+	require.NoError(t, kr.Set(keyring.Item{Key: "short", Data: []byte("a"), Expires: expires}))
+	require.NoError(t, kr.Set(keyring.Item{Key: "long", Data: []byte("b")}))
+
+	got, err := kr.Get("short")
+	require.NoError(t, err)
+	require.True(t, got.Expires.Equal(expires))
+
+	// The kernel expires the key on its own.
+	time.Sleep(time.Until(expires) + 100*time.Millisecond)
+	_, err = kr.Get("short")
+	require.ErrorIs(t, err, keyring.ErrKeyNotFound)
+
+	keys, err := kr.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"long"}, keys)
+}
+
+func TestKeyCtlCapabilities(t *testing.T) {
+	caps, ok := keyring.BackendInfo(keyring.KeyCtlBackend)
+	require.True(t, ok)
+	require.True(t, caps.Expiry, "keyctl expires items with kernel timeouts")
+	require.Equal(t, 32767, caps.MaxItemSize)
+}
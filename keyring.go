@@ -2,8 +2,11 @@
 package keyring
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 )
 
@@ -58,6 +61,14 @@ var backendOrder = []BackendType{
 
 var supportedBackends = map[BackendType]opener{}
 
+// unavailableBackends records why a backend compiled into this build did not
+// register itself, e.g. because the D-Bus session bus could not be reached.
+var unavailableBackends = map[BackendType]error{}
+
+// errBackendNotSupported is reported by Open for a backend that is not
+// supported on this platform or was excluded from the build.
+var errBackendNotSupported = errors.New("not supported on this platform or excluded from this build")
+
 // AvailableBackends provides a slice of all available backend keys on the current OS.
 func AvailableBackends() []BackendType {
 	b := []BackendType{}
@@ -72,23 +83,101 @@ func AvailableBackends() []BackendType {
 
 type opener func(cfg Config) (Keyring, error)
 
-// Open will open a specific keyring backend.
+// Open will open a specific keyring backend. If none of the allowed backends
+// can be opened, the returned error is an *OpenError describing why each one
+// was rejected; it matches ErrNoAvailImpl.
+//
+// If cfg.Logger is set, the keyring returned logs each operation to it.
 func Open(cfg Config) (Keyring, error) {
-	if cfg.AllowedBackends == nil {
-		cfg.AllowedBackends = AvailableBackends()
-	}
-	debugf("Considering backends: %v", cfg.AllowedBackends)
-	for _, backend := range cfg.AllowedBackends {
-		if opener, ok := supportedBackends[backend]; ok {
-			openBackend, err := opener(cfg)
-			if err != nil {
-				debugf("Failed backend %s: %s", backend, err)
-				continue
-			}
-			return openBackend, nil
+	logger := cfg.logger()
+	candidates := candidateBackends(cfg)
+	logger.Debug("Considering backends", "backends", candidates)
+
+	openErr := &OpenError{}
+	for _, backend := range candidates {
+		opener, ok := supportedBackends[backend]
+		if !ok {
+			reason := unsupportedReason(backend)
+			logger.Debug("Skipped backend", "backend", backend, "error", reason)
+			openErr.Failures = append(openErr.Failures, BackendFailure{Backend: backend, Err: reason})
+			continue
+		}
+		openBackend, err := opener(cfg)
+		if err != nil {
+			logger.Debug("Failed backend", "backend", backend, "error", err)
+			openErr.Failures = append(openErr.Failures, BackendFailure{Backend: backend, Err: err})
+			continue
+		}
+		return openBackend, nil
+	}
+	return nil, openErr
+}
+
+// candidateBackends returns cfg.AllowedBackends or, if that is nil, every
+// backend in this build. Backends that could not register are not available,
+// but saying why is more useful than leaving them out.
+func candidateBackends(cfg Config) []BackendType {
+	if cfg.AllowedBackends != nil {
+		return cfg.AllowedBackends
+	}
+	candidates := []BackendType{}
+	for _, backend := range backendOrder {
+		_, supported := supportedBackends[backend]
+		_, unavailable := unavailableBackends[backend]
+		if supported || unavailable {
+			candidates = append(candidates, backend)
+		}
+	}
+	return candidates
+}
+
+// unsupportedReason explains why backend has no opener.
+func unsupportedReason(backend BackendType) error {
+	if reason, ok := unavailableBackends[backend]; ok {
+		return reason
+	}
+	return errBackendNotSupported
+}
+
+// BackendFailure is the reason a single backend could not be opened.
+type BackendFailure struct {
+	Backend BackendType
+	Err     error
+}
+
+// OpenError is returned by Open when none of the candidate backends could be
+// opened. errors.Is matches ErrNoAvailImpl as well as any of the individual
+// failures, so errors.Is(err, ErrBackendUnavailable) works too.
+type OpenError struct {
+	// Failures lists every backend that was considered, in the order tried.
+	Failures []BackendFailure
+}
+
+func (e *OpenError) Error() string {
+	if len(e.Failures) == 0 {
+		return ErrNoAvailImpl.Error() + ": no backends were allowed"
+	}
+	var b strings.Builder
+	b.WriteString(ErrNoAvailImpl.Error())
+	for i, f := range e.Failures {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
 		}
+		fmt.Fprintf(&b, "%s: %v", f.Backend, f.Err)
 	}
-	return nil, ErrNoAvailImpl
+	return b.String()
+}
+
+// Unwrap returns ErrNoAvailImpl followed by each backend's error.
+func (e *OpenError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures)+1)
+	errs = append(errs, ErrNoAvailImpl)
+	for _, f := range e.Failures {
+		errs = append(errs, f.Err)
+	}
+	return errs
 }
 
 // Item is a thing stored on the keyring.
@@ -98,6 +187,15 @@ type Item struct {
 	Label       string
 	Description string
 
+	// Attributes are non-secret name and value pairs describing the item,
+	// such as its environment or owner. Backends that can index them store
+	// them natively; see FindByAttributes.
+	Attributes map[string]string
+
+	// Expires is when the item stops being valid, or zero if it never does.
+	// Get treats an expired item as missing; PurgeExpired removes them.
+	Expires time.Time `json:",omitzero"`
+
 	// Backend specific config
 	KeychainNotTrustApplication bool
 	KeychainNotSynchronizable   bool
@@ -111,6 +209,13 @@ type Item struct {
 type Metadata struct {
 	*Item
 	ModificationTime time.Time
+
+	// CreationTime is when the item was first stored. It is zero if the
+	// backend does not know.
+	CreationTime time.Time
+
+	// Fields are the caller-supplied values set with SetMetadata.
+	Fields map[string]string
 }
 
 // Keyring provides the uniform interface over the underlying backends.
@@ -121,12 +226,82 @@ type Keyring interface {
 	GetMetadata(key string) (Metadata, error)
 	// Stores an Item on the keyring
 	Set(item Item) error
-	// Removes the item with matching key
+	// Removes the item with matching key or returns ErrKeyNotFound
 	Remove(key string) error
 	// Provides a slice of all keys stored on the keyring
 	Keys() ([]string, error)
 }
 
+// ContextKeyring is a Keyring whose operations honour a context.Context for
+// cancellation and deadlines. Every built-in backend implements it natively:
+// cancelling the context kills any subprocess the backend spawned and aborts
+// in-flight HTTP and D-Bus calls where the underlying transport allows it.
+// Use AsContextKeyring to adapt any other Keyring.
+type ContextKeyring interface {
+	Keyring
+	// Returns an Item matching the key or ErrKeyNotFound
+	GetContext(ctx context.Context, key string) (Item, error)
+	// Returns the non-secret parts of an Item
+	GetMetadataContext(ctx context.Context, key string) (Metadata, error)
+	// Stores an Item on the keyring
+	SetContext(ctx context.Context, item Item) error
+	// Removes the item with matching key or returns ErrKeyNotFound
+	RemoveContext(ctx context.Context, key string) error
+	// Provides a slice of all keys stored on the keyring
+	KeysContext(ctx context.Context) ([]string, error)
+}
+
+// AsContextKeyring returns k as a ContextKeyring. Keyrings that already
+// implement ContextKeyring are returned unchanged; any other Keyring is wrapped
+// in an adapter that checks the context before delegating. The adapter cannot
+// interrupt an operation once the wrapped Keyring has started it.
+func AsContextKeyring(k Keyring) ContextKeyring {
+	if ck, ok := k.(ContextKeyring); ok {
+		return ck
+	}
+	return contextAdapter{k}
+}
+
+// contextAdapter implements ContextKeyring for a plain Keyring.
+type contextAdapter struct {
+	Keyring
+}
+
+func (a contextAdapter) GetContext(ctx context.Context, key string) (Item, error) {
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+	return a.Get(key)
+}
+
+func (a contextAdapter) GetMetadataContext(ctx context.Context, key string) (Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
+	return a.GetMetadata(key)
+}
+
+func (a contextAdapter) SetContext(ctx context.Context, item Item) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Set(item)
+}
+
+func (a contextAdapter) RemoveContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Remove(key)
+}
+
+func (a contextAdapter) KeysContext(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.Keys()
+}
+
 // ErrNoAvailImpl is returned by Open when a backend cannot be found.
 var ErrNoAvailImpl = errors.New("specified keyring backend not available")
 
@@ -140,11 +315,68 @@ var ErrMetadataNeedsCredentials = errors.New("the keyring backend requires crede
 // ErrMetadataNotSupported is returned when Metadata is not available for the backend.
 var ErrMetadataNotSupported = errors.New("the keyring backend does not support metadata access")
 
+// The following errors classify backend failures so that callers can react to
+// them with errors.Is, whichever backend produced them. Backends wrap the
+// native error, so its text is preserved in the message and it remains
+// reachable with errors.As.
+var (
+	// ErrLocked is returned when the keyring or item is locked and could not
+	// be unlocked without user interaction.
+	ErrLocked = errors.New("the keyring is locked")
+
+	// ErrUserCancelled is returned when the user dismissed an unlock,
+	// passphrase or biometric prompt.
+	ErrUserCancelled = errors.New("the operation was cancelled by the user")
+
+	// ErrAccessDenied is returned when the backend refused access to the
+	// keyring or item, including rejected or expired credentials.
+	ErrAccessDenied = errors.New("access to the keyring was denied")
+
+	// ErrWrongPassphrase is returned when the supplied passphrase could not
+	// decrypt the item.
+	ErrWrongPassphrase = errors.New("the passphrase for the keyring is incorrect")
+
+	// ErrBackendUnavailable is returned when the backend's service, daemon or
+	// helper program could not be reached.
+	ErrBackendUnavailable = errors.New("the keyring backend is unavailable")
+
+	// ErrRateLimited is returned when a remote backend throttled the request.
+	ErrRateLimited = errors.New("the keyring backend rate limited the request")
+)
+
+// kindError is a backend-specific error that also matches one of the
+// classification sentinels, without the sentinel's text leaking into its
+// message.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+func (e *kindError) Unwrap() error { return e.kind }
+
+// newKindError returns an error with text msg that satisfies errors.Is(err, kind).
+func newKindError(kind error, msg string) error {
+	return &kindError{kind: kind, msg: msg}
+}
+
+// classify wraps err so that it also matches kind. It returns nil for a nil err.
+func classify(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
 var (
-	// Debug specifies whether to print debugging output.
+	// Debug specifies whether to print debugging output to the standard log
+	// package. It is kept for compatibility: Config.Logger can be set per
+	// keyring and takes precedence.
 	Debug bool
 )
 
+// debugf logs to the standard log package while Debug is set. Code with a
+// Config at hand logs to its logger instead.
 func debugf(pattern string, args ...interface{}) {
 	if Debug {
 		log.Printf("[keyring] "+pattern, args...)
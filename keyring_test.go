@@ -1,7 +1,11 @@
 package keyring_test
 
 import (
+	"context"
+	"errors"
 	"log"
+	"strings"
+	"testing"
 
 	"github.com/byteness/keyring"
 )
@@ -25,3 +29,75 @@ func ExampleOpen() {
 
 	log.Printf("llamas was %v", v)
 }
+
+// plainKeyring hides the ContextKeyring methods of the wrapped keyring.
+type plainKeyring struct {
+	keyring.Keyring
+}
+
+func TestAsContextKeyring(t *testing.T) {
+	ak := keyring.NewArrayKeyring([]keyring.Item{{Key: "llamas", Data: []byte("llamas are great")}})
+	if ck := keyring.AsContextKeyring(ak); ck != keyring.ContextKeyring(ak) {
+		t.Fatalf("AsContextKeyring wrapped a keyring that already implements ContextKeyring")
+	}
+
+	ck := keyring.AsContextKeyring(plainKeyring{ak})
+	item, err := ck.GetContext(t.Context(), "llamas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(item.Data) != "llamas are great" {
+		t.Fatalf("Value stored was not the value retrieved: %q", item.Data)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := ck.GetContext(ctx, "llamas"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetContext err = %v, want context.Canceled", err)
+	}
+	if err := ck.SetContext(ctx, keyring.Item{Key: "alpacas"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SetContext err = %v, want context.Canceled", err)
+	}
+	if _, err := ak.Get("alpacas"); !errors.Is(err, keyring.ErrKeyNotFound) {
+		t.Fatalf("SetContext with a cancelled context stored the item")
+	}
+}
+
+func TestOpenReportsEachFailure(t *testing.T) {
+	_, err := keyring.Open(keyring.Config{AllowedBackends: []keyring.BackendType{"llamas", "alpacas"}})
+	if !errors.Is(err, keyring.ErrNoAvailImpl) {
+		t.Fatalf("Open err = %v, want ErrNoAvailImpl", err)
+	}
+
+	var openErr *keyring.OpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("Open err = %T, want *OpenError", err)
+	}
+	if len(openErr.Failures) != 2 || openErr.Failures[0].Backend != "llamas" || openErr.Failures[1].Backend != "alpacas" {
+		t.Fatalf("unexpected failures: %+v", openErr.Failures)
+	}
+	if !strings.Contains(err.Error(), "llamas: not supported") {
+		t.Fatalf("error does not name the backend: %v", err)
+	}
+}
+
+func TestBackendInfo(t *testing.T) {
+	for _, backend := range keyring.AvailableBackends() {
+		if _, ok := keyring.BackendInfo(backend); !ok {
+			t.Errorf("backend %q registered without capabilities", backend)
+		}
+	}
+	if _, ok := keyring.BackendInfo("llamas"); ok {
+		t.Error("BackendInfo reported capabilities for an unknown backend")
+	}
+}
+
+func TestProbeReportsEachBackend(t *testing.T) {
+	results := keyring.Probe(keyring.Config{AllowedBackends: []keyring.BackendType{"llamas"}})
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	if r := results[0]; r.Backend != "llamas" || r.OK || r.Err == nil {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
@@ -0,0 +1,185 @@
+// Package keyringtest checks that keyring.Keyring implementations behave
+// like the backends in package keyring, so that custom backends and wrappers
+// can be tested against the same contract.
+package keyringtest
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/byteness/keyring"
+)
+
+// prefix starts every key the suite stores, so that its items are easy to
+// tell apart from others in a shared store.
+const prefix = "keyringtest-"
+
+// unusualKeys are keys that backends storing items as files, D-Bus objects
+// or kernel keys have to escape or otherwise take care with.
+var unusualKeys = []string{
+	prefix + "nested/path/key",
+	prefix + "with spaces",
+	prefix + "ünïcødé-🔑",
+	prefix + "dots.and-dashes_and+plus",
+}
+
+// RunConformance runs the checks of the keyring contract against the keyring
+// open returns, each as a subtest. open is called once per check. It may
+// return the same store each time, as the keys the suite uses start with
+// "keyringtest-" and each check removes the items it stored, but that store
+// must not already hold items under those keys.
+//
+// The checks are that:
+//   - Get and Remove return keyring.ErrKeyNotFound for a missing key
+//   - Set then Get round-trips an item's Data, Label and Description
+//   - Set replaces an existing item
+//   - an item with empty Data can be stored and read back
+//   - Keys lists the items stored, and not those removed
+//   - keys containing "/", spaces and non-ASCII characters work throughout
+func RunConformance(t *testing.T, open func() keyring.Keyring) {
+	t.Helper()
+
+	t.Run("GetMissing", func(t *testing.T) {
+		k := open()
+		if _, err := k.Get(prefix + "missing"); !errors.Is(err, keyring.ErrKeyNotFound) {
+			t.Fatalf("Get of a missing key returned %v, want ErrKeyNotFound", err)
+		}
+	})
+
+	t.Run("RemoveMissing", func(t *testing.T) {
+		k := open()
+		if err := k.Remove(prefix + "missing"); !errors.Is(err, keyring.ErrKeyNotFound) {
+			t.Fatalf("Remove of a missing key returned %v, want ErrKeyNotFound", err)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		k := open()
+		item := keyring.Item{
+			Key:         prefix + "round-trip",
+			Data:        []byte("llamas are great"),
+			Label:       "Llamas",
+			Description: "A fact about llamas",
+		}
+		set(t, k, item)
+		checkGet(t, k, item)
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		k := open()
+		item := keyring.Item{Key: prefix + "overwrite", Data: []byte("llamas are great")}
+		set(t, k, item)
+		item.Data = []byte("alpacas are better")
+		item.Label = "Alpacas"
+		set(t, k, item)
+		checkGet(t, k, item)
+	})
+
+	t.Run("EmptyData", func(t *testing.T) {
+		k := open()
+		item := keyring.Item{Key: prefix + "empty", Data: []byte{}}
+		set(t, k, item)
+		checkGet(t, k, item)
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		k := open()
+		want := []string{prefix + "keys-a", prefix + "keys-b", prefix + "keys-c"}
+		for _, key := range want {
+			set(t, k, keyring.Item{Key: key, Data: []byte(key)})
+		}
+		checkKeys(t, k, want, nil)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		k := open()
+		set(t, k, keyring.Item{Key: prefix + "remove", Data: []byte("llamas are great")})
+		set(t, k, keyring.Item{Key: prefix + "remove-kept", Data: []byte("alpacas are better")})
+
+		if err := k.Remove(prefix + "remove"); err != nil {
+			t.Fatalf("Remove returned %v", err)
+		}
+		if _, err := k.Get(prefix + "remove"); !errors.Is(err, keyring.ErrKeyNotFound) {
+			t.Fatalf("Get of a removed key returned %v, want ErrKeyNotFound", err)
+		}
+		if err := k.Remove(prefix + "remove"); !errors.Is(err, keyring.ErrKeyNotFound) {
+			t.Fatalf("Remove of a removed key returned %v, want ErrKeyNotFound", err)
+		}
+		checkKeys(t, k, []string{prefix + "remove-kept"}, []string{prefix + "remove"})
+	})
+
+	t.Run("UnusualKeys", func(t *testing.T) {
+		for _, key := range unusualKeys {
+			t.Run(key, func(t *testing.T) {
+				k := open()
+				item := keyring.Item{Key: key, Data: []byte("llamas are great"), Label: key}
+				set(t, k, item)
+				checkGet(t, k, item)
+				checkKeys(t, k, []string{key}, nil)
+
+				if err := k.Remove(key); err != nil {
+					t.Fatalf("Remove(%q) returned %v", key, err)
+				}
+				if _, err := k.Get(key); !errors.Is(err, keyring.ErrKeyNotFound) {
+					t.Fatalf("Get(%q) after Remove returned %v, want ErrKeyNotFound", key, err)
+				}
+				checkKeys(t, k, nil, []string{key})
+			})
+		}
+	})
+}
+
+// set stores item in k and removes it again when the test ends.
+func set(t *testing.T, k keyring.Keyring, item keyring.Item) {
+	t.Helper()
+	if err := k.Set(item); err != nil {
+		t.Fatalf("Set(%q) returned %v", item.Key, err)
+	}
+	t.Cleanup(func() {
+		if err := k.Remove(item.Key); err != nil && !errors.Is(err, keyring.ErrKeyNotFound) {
+			t.Errorf("removing %q after the test: %v", item.Key, err)
+		}
+	})
+}
+
+// checkGet fails the test unless k returns want for its key.
+func checkGet(t *testing.T, k keyring.Keyring, want keyring.Item) {
+	t.Helper()
+	got, err := k.Get(want.Key)
+	if err != nil {
+		t.Fatalf("Get(%q) returned %v", want.Key, err)
+	}
+	if got.Key != want.Key {
+		t.Errorf("Get(%q) returned an item with key %q", want.Key, got.Key)
+	}
+	if !bytes.Equal(got.Data, want.Data) {
+		t.Errorf("Get(%q) returned Data %q, want %q", want.Key, got.Data, want.Data)
+	}
+	if got.Label != want.Label {
+		t.Errorf("Get(%q) returned Label %q, want %q", want.Key, got.Label, want.Label)
+	}
+	if got.Description != want.Description {
+		t.Errorf("Get(%q) returned Description %q, want %q", want.Key, got.Description, want.Description)
+	}
+}
+
+// checkKeys fails the test unless k lists all of present and none of absent.
+func checkKeys(t *testing.T, k keyring.Keyring, present, absent []string) {
+	t.Helper()
+	keys, err := k.Keys()
+	if err != nil {
+		t.Fatalf("Keys returned %v", err)
+	}
+	for _, key := range present {
+		if !slices.Contains(keys, key) {
+			t.Errorf("Keys returned %q, which is missing %q", keys, key)
+		}
+	}
+	for _, key := range absent {
+		if slices.Contains(keys, key) {
+			t.Errorf("Keys returned %q, which includes the removed %q", keys, key)
+		}
+	}
+}
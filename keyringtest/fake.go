@@ -0,0 +1,367 @@
+package keyringtest
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/byteness/keyring"
+)
+
+// Call is an operation performed on a FakeKeyring, as recorded for
+// assertions.
+type Call struct {
+	Op  keyring.Op
+	Key string
+
+	// Err is the error the call returned.
+	Err error
+}
+
+// Fault makes matching calls to a FakeKeyring fail.
+type Fault struct {
+	// Op is the operation to fail. The zero value matches every operation.
+	Op keyring.Op
+
+	// Key is the key to fail calls for. The empty string matches every key,
+	// and calls such as Keys that have no key.
+	Key string
+
+	// N is which matching call fails, counting from 1 from when the fault is
+	// injected, after which the fault is spent. If it is 0, every matching
+	// call fails until ClearFaults is called.
+	N int
+
+	// Err is the error the call returns.
+	Err error
+}
+
+// FakeKeyring is an in-memory keyring for tests that can be scripted to
+// behave like a real backend: to fail, to be slow, to be locked, and to
+// report metadata times from a clock the test controls. It records every
+// call made to it. It implements keyring.ContextKeyring and
+// keyring.MetadataKeyring, and is safe for concurrent use.
+//
+// The exported fields must be set before the keyring is used.
+type FakeKeyring struct {
+	// Now returns the time used for items' creation and modification times
+	// and to expire them. It defaults to time.Now.
+	Now func() time.Time
+
+	// Latency is how long every call takes. A call whose context is done
+	// first returns its error.
+	Latency time.Duration
+
+	// UnlockFunc is called, as a backend would prompt for a password, when
+	// Get, Set, Remove or SetMetadata is called while the keyring is locked.
+	// If it returns nil the keyring is unlocked and the call goes ahead;
+	// otherwise the call fails with its error, such as
+	// keyring.ErrUserCancelled. If it is nil, those calls fail with
+	// keyring.ErrLocked.
+	UnlockFunc func(ctx context.Context) error
+
+	mu     sync.Mutex
+	items  map[string]fakeItem
+	locked bool
+	faults []*Fault
+	calls  []Call
+}
+
+type fakeItem struct {
+	item     keyring.Item
+	created  time.Time
+	modified time.Time
+	fields   map[string]string
+}
+
+// NewFakeKeyring returns an unlocked FakeKeyring holding items. Their
+// creation and modification times are zero.
+func NewFakeKeyring(items ...keyring.Item) *FakeKeyring {
+	k := &FakeKeyring{items: map[string]fakeItem{}}
+	for _, item := range items {
+		k.items[item.Key] = fakeItem{item: item}
+	}
+	return k
+}
+
+// Inject adds f to the faults the keyring checks each call against. Where
+// several match a call, the one injected first is used.
+func (k *FakeKeyring) Inject(f Fault) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.faults = append(k.faults, &f)
+}
+
+// FailOn makes the nth call to op from now on, counting from 1, return err.
+// If n is 0, every call to op returns err until ClearFaults is called.
+func (k *FakeKeyring) FailOn(op keyring.Op, n int, err error) {
+	k.Inject(Fault{Op: op, N: n, Err: err})
+}
+
+// ClearFaults removes every fault injected.
+func (k *FakeKeyring) ClearFaults() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.faults = nil
+}
+
+// Lock locks the keyring, as if the user had locked their keychain.
+func (k *FakeKeyring) Lock() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.locked = true
+}
+
+// Unlock unlocks the keyring without calling UnlockFunc.
+func (k *FakeKeyring) Unlock() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.locked = false
+}
+
+// Locked reports whether the keyring is locked.
+func (k *FakeKeyring) Locked() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.locked
+}
+
+// Calls returns the calls made to the keyring so far, oldest first.
+func (k *FakeKeyring) Calls() []Call {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return slices.Clone(k.calls)
+}
+
+// ResetCalls forgets the calls made to the keyring so far.
+func (k *FakeKeyring) ResetCalls() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.calls = nil
+}
+
+func (k *FakeKeyring) now() time.Time {
+	if k.Now != nil {
+		return k.Now()
+	}
+	return time.Now()
+}
+
+// enter does what comes before performing a call: waiting out the latency,
+// failing it if a fault matches and unlocking the keyring if it needs to be.
+func (k *FakeKeyring) enter(ctx context.Context, op keyring.Op, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if k.Latency > 0 {
+		t := time.NewTimer(k.Latency)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	k.mu.Lock()
+	if err := k.fault(op, key); err != nil {
+		k.mu.Unlock()
+		return err
+	}
+	locked := k.locked
+	k.mu.Unlock()
+
+	if !locked || op == keyring.OpKeys || op == keyring.OpGetMetadata {
+		return nil
+	}
+	if k.UnlockFunc == nil {
+		return keyring.ErrLocked
+	}
+	if err := k.UnlockFunc(ctx); err != nil {
+		return err
+	}
+	k.Unlock()
+	return nil
+}
+
+// fault returns the error of the first fault matching the call, if any.
+// The caller holds k.mu.
+func (k *FakeKeyring) fault(op keyring.Op, key string) error {
+	for i, f := range k.faults {
+		if (f.Op != "" && f.Op != op) || (f.Key != "" && f.Key != key) {
+			continue
+		}
+		if f.N == 0 {
+			return f.Err
+		}
+		if f.N--; f.N == 0 {
+			k.faults = slices.Delete(k.faults, i, i+1)
+			return f.Err
+		}
+	}
+	return nil
+}
+
+// record records a call having returned err.
+func (k *FakeKeyring) record(op keyring.Op, key string, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.calls = append(k.calls, Call{Op: op, Key: key, Err: err})
+}
+
+// lookup returns the unexpired item for key. The caller holds k.mu.
+func (k *FakeKeyring) lookup(key string) (fakeItem, error) {
+	fi, ok := k.items[key]
+	if !ok {
+		return fakeItem{}, keyring.ErrKeyNotFound
+	}
+	if !fi.item.Expires.IsZero() && !k.now().Before(fi.item.Expires) {
+		return fakeItem{}, keyring.ErrKeyNotFound
+	}
+	return fi, nil
+}
+
+func (k *FakeKeyring) Get(key string) (keyring.Item, error) {
+	return k.GetContext(context.Background(), key)
+}
+
+func (k *FakeKeyring) GetContext(ctx context.Context, key string) (item keyring.Item, err error) {
+	defer func() { k.record(keyring.OpGet, key, err) }()
+	if err := k.enter(ctx, keyring.OpGet, key); err != nil {
+		return keyring.Item{}, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	fi, err := k.lookup(key)
+	if err != nil {
+		return keyring.Item{}, err
+	}
+	item = fi.item
+	item.Data = slices.Clone(item.Data)
+	item.Attributes = maps.Clone(item.Attributes)
+	return item, nil
+}
+
+func (k *FakeKeyring) GetMetadata(key string) (keyring.Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+func (k *FakeKeyring) GetMetadataContext(ctx context.Context, key string) (md keyring.Metadata, err error) {
+	defer func() { k.record(keyring.OpGetMetadata, key, err) }()
+	if err := k.enter(ctx, keyring.OpGetMetadata, key); err != nil {
+		return keyring.Metadata{}, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	fi, err := k.lookup(key)
+	if err != nil {
+		return keyring.Metadata{}, err
+	}
+	return keyring.Metadata{
+		Item: &keyring.Item{
+			Key:         key,
+			Label:       fi.item.Label,
+			Description: fi.item.Description,
+			Attributes:  maps.Clone(fi.item.Attributes),
+			Expires:     fi.item.Expires,
+		},
+		ModificationTime: fi.modified,
+		CreationTime:     fi.created,
+		Fields:           maps.Clone(fi.fields),
+	}, nil
+}
+
+func (k *FakeKeyring) Set(item keyring.Item) error {
+	return k.SetContext(context.Background(), item)
+}
+
+func (k *FakeKeyring) SetContext(ctx context.Context, item keyring.Item) (err error) {
+	defer func() { k.record(keyring.OpSet, item.Key, err) }()
+	if err := k.enter(ctx, keyring.OpSet, item.Key); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := k.now()
+	fi, err := k.lookup(item.Key)
+	if err != nil {
+		fi = fakeItem{created: now}
+	}
+	item.Data = slices.Clone(item.Data)
+	item.Attributes = maps.Clone(item.Attributes)
+	fi.item = item
+	fi.modified = now
+	if k.items == nil {
+		k.items = map[string]fakeItem{}
+	}
+	k.items[item.Key] = fi
+	return nil
+}
+
+func (k *FakeKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *FakeKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer func() { k.record(keyring.OpSetMetadata, key, err) }()
+	if err := k.enter(ctx, keyring.OpSetMetadata, key); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	fi, err := k.lookup(key)
+	if err != nil {
+		return err
+	}
+	fi.fields = maps.Clone(fields)
+	fi.modified = k.now()
+	k.items[key] = fi
+	return nil
+}
+
+func (k *FakeKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *FakeKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer func() { k.record(keyring.OpRemove, key, err) }()
+	if err := k.enter(ctx, keyring.OpRemove, key); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, err := k.lookup(key); err != nil {
+		return err
+	}
+	delete(k.items, key)
+	return nil
+}
+
+func (k *FakeKeyring) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+func (k *FakeKeyring) KeysContext(ctx context.Context) (keys []string, err error) {
+	defer func() { k.record(keyring.OpKeys, "", err) }()
+	if err := k.enter(ctx, keyring.OpKeys, ""); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys = []string{}
+	for key := range k.items {
+		if _, err := k.lookup(key); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
@@ -0,0 +1,138 @@
+package keyringtest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/byteness/keyring"
+	"github.com/byteness/keyring/keyringtest"
+)
+
+func TestFakeKeyringConformance(t *testing.T) {
+	k := keyringtest.NewFakeKeyring()
+	keyringtest.RunConformance(t, func() keyring.Keyring { return k })
+}
+
+func TestFakeKeyringFaults(t *testing.T) {
+	k := keyringtest.NewFakeKeyring(keyring.Item{Key: "llamas"}, keyring.Item{Key: "alpacas"})
+	errBoom := errors.New("boom")
+
+	k.FailOn(keyring.OpGet, 2, errBoom)
+	for i, want := range []error{nil, errBoom, nil} {
+		if _, err := k.Get("llamas"); !errors.Is(err, want) {
+			t.Fatalf("Get %d returned %v, want %v", i+1, err, want)
+		}
+	}
+
+	k.Inject(keyringtest.Fault{Key: "alpacas", Err: keyring.ErrAccessDenied})
+	if err := keyring.RemoveMany(k, []string{"llamas", "alpacas"}); !errors.Is(err, keyring.ErrAccessDenied) {
+		t.Fatalf("RemoveMany returned %v, want ErrAccessDenied", err)
+	}
+	if _, err := k.Get("llamas"); !errors.Is(err, keyring.ErrKeyNotFound) {
+		t.Fatalf("Expected llamas to be removed, got %v", err)
+	}
+	if _, err := k.Get("alpacas"); !errors.Is(err, keyring.ErrAccessDenied) {
+		t.Fatalf("Get of alpacas returned %v, want ErrAccessDenied", err)
+	}
+
+	k.ClearFaults()
+	if _, err := k.Get("alpacas"); err != nil {
+		t.Fatalf("Get after ClearFaults returned %v", err)
+	}
+}
+
+func TestFakeKeyringLatency(t *testing.T) {
+	k := keyringtest.NewFakeKeyring(keyring.Item{Key: "llamas"})
+	k.Latency = time.Minute
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := k.GetContext(ctx, "llamas"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetContext returned %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestFakeKeyringLocked(t *testing.T) {
+	k := keyringtest.NewFakeKeyring(keyring.Item{Key: "llamas"})
+	k.Lock()
+
+	if _, err := k.Get("llamas"); !errors.Is(err, keyring.ErrLocked) {
+		t.Fatalf("Get while locked returned %v, want ErrLocked", err)
+	}
+	if _, err := k.Keys(); err != nil {
+		t.Fatalf("Keys while locked returned %v", err)
+	}
+
+	prompts := 0
+	k.UnlockFunc = func(context.Context) error {
+		prompts++
+		if prompts == 1 {
+			return keyring.ErrUserCancelled
+		}
+		return nil
+	}
+	if _, err := k.Get("llamas"); !errors.Is(err, keyring.ErrUserCancelled) {
+		t.Fatalf("Get with a cancelled prompt returned %v, want ErrUserCancelled", err)
+	}
+	if _, err := k.Get("llamas"); err != nil {
+		t.Fatalf("Get with an accepted prompt returned %v", err)
+	}
+	if k.Locked() {
+		t.Fatal("Expected the keyring to be unlocked by the prompt")
+	}
+	if _, err := k.Get("llamas"); err != nil || prompts != 2 {
+		t.Fatalf("Get once unlocked returned %v after %d prompts", err, prompts)
+	}
+}
+
+func TestFakeKeyringClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	k := keyringtest.NewFakeKeyring()
+	k.Now = func() time.Time { return now }
+
+	if err := k.Set(keyring.Item{Key: "llamas", Expires: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	created := now
+	now = now.Add(time.Minute)
+	if err := k.SetMetadata("llamas", map[string]string{"owner": "ops"}); err != nil {
+		t.Fatal(err)
+	}
+
+	md, err := k.GetMetadata("llamas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !md.CreationTime.Equal(created) || !md.ModificationTime.Equal(now) || md.Fields["owner"] != "ops" {
+		t.Fatalf("Unexpected metadata %+v", md)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := k.Get("llamas"); !errors.Is(err, keyring.ErrKeyNotFound) {
+		t.Fatalf("Get of an expired item returned %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestFakeKeyringCalls(t *testing.T) {
+	k := keyringtest.NewFakeKeyring()
+	_ = k.Set(keyring.Item{Key: "llamas"})
+	_, _ = k.Get("alpacas")
+	_, _ = k.Keys()
+
+	want := []keyringtest.Call{
+		{Op: keyring.OpSet, Key: "llamas"},
+		{Op: keyring.OpGet, Key: "alpacas", Err: keyring.ErrKeyNotFound},
+		{Op: keyring.OpKeys},
+	}
+	if got := k.Calls(); !slices.Equal(got, want) {
+		t.Fatalf("Calls returned %+v, want %+v", got, want)
+	}
+
+	k.ResetCalls()
+	if got := k.Calls(); len(got) != 0 {
+		t.Fatalf("Calls after ResetCalls returned %+v", got)
+	}
+}
@@ -4,8 +4,15 @@
 package keyring
 
 import (
+	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"unicode/utf16"
 
 	"github.com/godbus/dbus/v5"
 )
@@ -16,13 +23,23 @@ const (
 )
 
 func init() {
+	backendCapabilities[KWalletBackend] = Capabilities{
+		Metadata:           MetadataNeedsCredentials,
+		Label:              true,
+		Description:        true,
+		ListRequiresUnlock: true,
+		Interactive:        true,
+	}
+
 	if os.Getenv("DISABLE_KWALLET") == "1" {
+		unavailableBackends[KWalletBackend] = errors.New("disabled by DISABLE_KWALLET=1")
 		return
 	}
 
-	// silently fail if dbus isn't available
+	// don't register if dbus isn't available, but remember why for Open
 	_, err := dbus.SessionBus()
 	if err != nil {
+		unavailableBackends[KWalletBackend] = classify(ErrBackendUnavailable, err)
 		return
 	}
 
@@ -49,10 +66,27 @@ func init() {
 			name:   cfg.ServiceName,
 			appID:  cfg.KWalletAppID,
 			folder: cfg.KWalletFolder,
+			log:    newCallLogger(cfg, KWalletBackend),
 		}
 
-		return ring, ring.openWallet()
+		return ring, ring.openWallet(context.Background())
 	})
+	// Opening the wallet may prompt for its password, so the probe only
+	// checks that kwalletd answers and has wallets enabled.
+	backendProbes[KWalletBackend] = func(ctx context.Context, _ Config) error {
+		wallet, err := newKwallet()
+		if err != nil {
+			return dbusError(err)
+		}
+		enabled, err := wallet.IsEnabled(ctx)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return newKindError(ErrBackendUnavailable, "kwallet is disabled")
+		}
+		return nil
+	}
 }
 
 type kwalletKeyring struct {
@@ -61,19 +95,25 @@ type kwalletKeyring struct {
 	handle int32
 	appID  string
 	folder string
+	log    callLogger
 }
 
-func (k *kwalletKeyring) openWallet() error {
-	isOpen, err := k.wallet.IsOpen(k.handle)
+func (k *kwalletKeyring) openWallet(ctx context.Context) error {
+	isOpen, err := k.wallet.IsOpen(ctx, k.handle)
 	if err != nil {
 		return err
 	}
 
 	if !isOpen {
-		handle, err := k.wallet.Open(k.name, 0, k.appID)
+		handle, err := k.wallet.Open(ctx, k.name, 0, k.appID)
 		if err != nil {
 			return err
 		}
+		if handle < 0 {
+			// kwalletd reports a refused or dismissed open request as a
+			// negative handle rather than a D-Bus error.
+			return fmt.Errorf("%w: kwallet %q was not opened", ErrAccessDenied, k.name)
+		}
 		k.handle = handle
 	}
 
@@ -81,18 +121,24 @@ func (k *kwalletKeyring) openWallet() error {
 }
 
 func (k *kwalletKeyring) Get(key string) (Item, error) {
-	err := k.openWallet()
+	return k.GetContext(context.Background(), key)
+}
+
+func (k *kwalletKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	err = k.openWallet(ctx)
 	if err != nil {
 		return Item{}, err
 	}
 
-	data, err := k.wallet.ReadEntry(k.handle, k.folder, key, k.appID)
+	data, err := k.wallet.ReadEntry(ctx, k.handle, k.folder, key, k.appID)
 	if err != nil {
 		return Item{}, err
 	}
 	if len(data) == 0 {
 		return Item{}, ErrKeyNotFound
 	}
+	defer zeroBytes(data)
 
 	item := Item{}
 	err = json.Unmarshal(data, &item)
@@ -100,7 +146,7 @@ func (k *kwalletKeyring) Get(key string) (Item, error) {
 		return Item{}, err
 	}
 
-	return item, nil
+	return unexpired(item)
 }
 
 // GetMetadata for kwallet returns an error indicating that it's unsupported
@@ -109,12 +155,25 @@ func (k *kwalletKeyring) Get(key string) (Item, error) {
 // The only APIs found around KWallet are for retrieving content, no indication
 // found in docs for methods to use to retrieve metadata without needing unlock
 // credentials.
-func (k *kwalletKeyring) GetMetadata(_ string) (Metadata, error) {
+func (k *kwalletKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+func (k *kwalletKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
 	return Metadata{}, ErrMetadataNeedsCredentials
 }
 
 func (k *kwalletKeyring) Set(item Item) error {
-	err := k.openWallet()
+	return k.SetContext(context.Background(), item)
+}
+
+func (k *kwalletKeyring) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
+	err = k.openWallet(ctx)
 	if err != nil {
 		return err
 	}
@@ -123,36 +182,90 @@ func (k *kwalletKeyring) Set(item Item) error {
 	if err != nil {
 		return err
 	}
+	defer zeroBytes(data)
 
-	err = k.wallet.WriteEntry(k.handle, k.folder, item.Key, data, k.appID)
+	err = k.wallet.WriteEntry(ctx, k.handle, k.folder, item.Key, data, k.appID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// The attributes are also kept as a map entry, which KWallet Manager shows
+	// and other applications can read, in a folder of their own so that Keys
+	// does not list them.
+	if len(item.Attributes) == 0 {
+		return k.wallet.RemoveEntry(ctx, k.handle, k.attributesFolder(), item.Key, k.appID)
+	}
+	return k.wallet.WriteMap(ctx, k.handle, k.attributesFolder(), item.Key, kwalletEncodeMap(item.Attributes), k.appID)
+}
+
+func (k *kwalletKeyring) attributesFolder() string {
+	return k.folder + "-attributes"
+}
+
+// kwalletEncodeMap serializes m the way Qt's QDataStream writes a
+// QMap<QString, QString>, which is what KWallet expects of a map entry: the
+// number of entries, then each key and value from the last key to the first.
+func kwalletEncodeMap(m map[string]string) []byte {
+	buf := binary.BigEndian.AppendUint32(nil, uint32(len(m)))
+	keys := slices.Sorted(maps.Keys(m))
+	for _, key := range slices.Backward(keys) {
+		buf = kwalletAppendString(buf, key)
+		buf = kwalletAppendString(buf, m[key])
+	}
+	return buf
+}
+
+// kwalletAppendString appends s as a QDataStream QString: its length in bytes
+// followed by its UTF-16 big-endian code units.
+func kwalletAppendString(buf []byte, s string) []byte {
+	units := utf16.Encode([]rune(s))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(2*len(units)))
+	for _, u := range units {
+		buf = binary.BigEndian.AppendUint16(buf, u)
+	}
+	return buf
 }
 
 func (k *kwalletKeyring) Remove(key string) error {
-	err := k.openWallet()
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *kwalletKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	err = k.openWallet(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = k.wallet.RemoveEntry(k.handle, k.folder, key, k.appID)
+	// removeEntry succeeds whether or not there is an entry to remove.
+	found, err := k.wallet.HasEntry(ctx, k.handle, k.folder, key, k.appID)
 	if err != nil {
 		return err
 	}
+	if !found {
+		return ErrKeyNotFound
+	}
 
-	return nil
+	err = k.wallet.RemoveEntry(ctx, k.handle, k.folder, key, k.appID)
+	if err != nil {
+		return err
+	}
+
+	return k.wallet.RemoveEntry(ctx, k.handle, k.attributesFolder(), key, k.appID)
 }
 
 func (k *kwalletKeyring) Keys() ([]string, error) {
-	err := k.openWallet()
+	return k.KeysContext(context.Background())
+}
+
+func (k *kwalletKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	err = k.openWallet(ctx)
 	if err != nil {
 		return []string{}, err
 	}
 
-	entries, err := k.wallet.EntryList(k.handle, k.folder, k.appID)
+	entries, err := k.wallet.EntryList(ctx, k.handle, k.folder, k.appID)
 	if err != nil {
 		return []string{}, err
 	}
@@ -160,6 +273,63 @@ func (k *kwalletKeyring) Keys() ([]string, error) {
 	return entries, nil
 }
 
+// Watch reports changes to the items in the folder. kwalletd's folderUpdated
+// and entryUpdated signals do not say how an entry changed, so the folder is
+// read again for each of them and compared with how it was before.
+func (k *kwalletKeyring) Watch(ctx context.Context) (<-chan Event, error) {
+	_, signals, unsubscribe, err := subscribeSignals(ctx,
+		dbus.WithMatchObjectPath(dbusPath),
+		dbus.WithMatchInterface("org.kde.KWallet"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	notify := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(notify)
+		for {
+			select {
+			case <-done:
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				if !k.isFolderUpdate(sig) {
+					continue
+				}
+				select {
+				case notify <- struct{}{}:
+				default: // a read of the folder is already due
+				}
+			}
+		}
+	}()
+
+	return watchSnapshots(ctx, k, notify, func() {
+		close(done)
+		unsubscribe()
+	})
+}
+
+// isFolderUpdate reports whether sig says the keyring's folder was updated.
+//
+// signal void org.kde.KWallet.folderUpdated(QString wallet, QString folder)
+// signal void org.kde.KWallet.entryUpdated(QString wallet, QString folder, QString key)
+func (k *kwalletKeyring) isFolderUpdate(sig *dbus.Signal) bool {
+	if sig.Name != "org.kde.KWallet.folderUpdated" && sig.Name != "org.kde.KWallet.entryUpdated" {
+		return false
+	}
+	if len(sig.Body) < 2 {
+		return false
+	}
+	wallet, _ := sig.Body[0].(string)
+	folder, _ := sig.Body[1].(string)
+	return wallet == k.name && folder == k.folder
+}
+
 func newKwallet() (*kwalletBinding, error) {
 	conn, err := dbus.SessionBus()
 	if err != nil {
@@ -176,61 +346,91 @@ type kwalletBinding struct {
 	dbus dbus.BusObject
 }
 
+// method bool org.kde.KWallet.isEnabled()
+func (k *kwalletBinding) IsEnabled(ctx context.Context) (bool, error) {
+	call := k.dbus.CallWithContext(ctx, "org.kde.KWallet.isEnabled", 0)
+	if call.Err != nil {
+		return false, dbusError(call.Err)
+	}
+
+	return call.Body[0].(bool), call.Err
+}
+
 // method bool org.kde.KWallet.isOpen(int handle)
-func (k *kwalletBinding) IsOpen(handle int32) (bool, error) {
-	call := k.dbus.Call("org.kde.KWallet.isOpen", 0, handle)
+func (k *kwalletBinding) IsOpen(ctx context.Context, handle int32) (bool, error) {
+	call := k.dbus.CallWithContext(ctx, "org.kde.KWallet.isOpen", 0, handle)
 	if call.Err != nil {
-		return false, call.Err
+		return false, dbusError(call.Err)
 	}
 
 	return call.Body[0].(bool), call.Err
 }
 
 // method int org.kde.KWallet.open(QString wallet, qlonglong wId, QString appid)
-func (k *kwalletBinding) Open(name string, wID int64, appid string) (int32, error) {
-	call := k.dbus.Call("org.kde.KWallet.open", 0, name, wID, appid)
+func (k *kwalletBinding) Open(ctx context.Context, name string, wID int64, appid string) (int32, error) {
+	call := k.dbus.CallWithContext(ctx, "org.kde.KWallet.open", 0, name, wID, appid)
 	if call.Err != nil {
-		return 0, call.Err
+		return 0, dbusError(call.Err)
 	}
 
 	return call.Body[0].(int32), call.Err
 }
 
 // method QStringList org.kde.KWallet.entryList(int handle, QString folder, QString appid)
-func (k *kwalletBinding) EntryList(handle int32, folder string, appid string) ([]string, error) {
-	call := k.dbus.Call("org.kde.KWallet.entryList", 0, handle, folder, appid)
+func (k *kwalletBinding) EntryList(ctx context.Context, handle int32, folder string, appid string) ([]string, error) {
+	call := k.dbus.CallWithContext(ctx, "org.kde.KWallet.entryList", 0, handle, folder, appid)
 	if call.Err != nil {
-		return []string{}, call.Err
+		return []string{}, dbusError(call.Err)
 	}
 
 	return call.Body[0].([]string), call.Err
 }
 
 // method int org.kde.KWallet.writeEntry(int handle, QString folder, QString key, QByteArray value, QString appid)
-func (k *kwalletBinding) WriteEntry(handle int32, folder string, key string, value []byte, appid string) error {
-	call := k.dbus.Call("org.kde.KWallet.writeEntry", 0, handle, folder, key, value, appid)
+func (k *kwalletBinding) WriteEntry(ctx context.Context, handle int32, folder string, key string, value []byte, appid string) error {
+	call := k.dbus.CallWithContext(ctx, "org.kde.KWallet.writeEntry", 0, handle, folder, key, value, appid)
 	if call.Err != nil {
-		return call.Err
+		return dbusError(call.Err)
+	}
+
+	return call.Err
+}
+
+// method int org.kde.KWallet.writeMap(int handle, QString folder, QString key, QByteArray value, QString appid)
+func (k *kwalletBinding) WriteMap(ctx context.Context, handle int32, folder string, key string, value []byte, appid string) error {
+	call := k.dbus.CallWithContext(ctx, "org.kde.KWallet.writeMap", 0, handle, folder, key, value, appid)
+	if call.Err != nil {
+		return dbusError(call.Err)
 	}
 
 	return call.Err
 }
 
 // method int org.kde.KWallet.removeEntry(int handle, QString folder, QString key, QString appid)
-func (k *kwalletBinding) RemoveEntry(handle int32, folder string, key string, appid string) error {
-	call := k.dbus.Call("org.kde.KWallet.removeEntry", 0, handle, folder, key, appid)
+func (k *kwalletBinding) RemoveEntry(ctx context.Context, handle int32, folder string, key string, appid string) error {
+	call := k.dbus.CallWithContext(ctx, "org.kde.KWallet.removeEntry", 0, handle, folder, key, appid)
 	if call.Err != nil {
-		return call.Err
+		return dbusError(call.Err)
 	}
 
 	return call.Err
 }
 
+// method bool org.kde.KWallet.hasEntry(int handle, QString folder, QString key, QString appid)
+func (k *kwalletBinding) HasEntry(ctx context.Context, handle int32, folder string, key string, appid string) (bool, error) {
+	call := k.dbus.CallWithContext(ctx, "org.kde.KWallet.hasEntry", 0, handle, folder, key, appid)
+	if call.Err != nil {
+		return false, dbusError(call.Err)
+	}
+
+	return call.Body[0].(bool), call.Err
+}
+
 // method QByteArray org.kde.KWallet.readEntry(int handle, QString folder, QString key, QString appid)
-func (k *kwalletBinding) ReadEntry(handle int32, folder string, key string, appid string) ([]byte, error) {
-	call := k.dbus.Call("org.kde.KWallet.readEntry", 0, handle, folder, key, appid)
+func (k *kwalletBinding) ReadEntry(ctx context.Context, handle int32, folder string, key string, appid string) ([]byte, error) {
+	call := k.dbus.CallWithContext(ctx, "org.kde.KWallet.readEntry", 0, handle, folder, key, appid)
 	if call.Err != nil {
-		return []byte{}, call.Err
+		return []byte{}, dbusError(call.Err)
 	}
 
 	return call.Body[0].([]byte), call.Err
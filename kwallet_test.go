@@ -0,0 +1,27 @@
+//go:build linux
+// +build linux
+
+package keyring
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKwalletEncodeMap(t *testing.T) {
+	got := kwalletEncodeMap(map[string]string{"a": "x", "b": "é"})
+	want := []byte{
+		0, 0, 0, 2, // entries
+		0, 0, 0, 2, 0, 'b', // last key first
+		0, 0, 0, 2, 0x00, 0xe9,
+		0, 0, 0, 2, 0, 'a',
+		0, 0, 0, 2, 0, 'x',
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("kwalletEncodeMap = % x, want % x", got, want)
+	}
+
+	if got := kwalletEncodeMap(nil); !bytes.Equal(got, []byte{0, 0, 0, 0}) {
+		t.Fatalf("kwalletEncodeMap(nil) = % x", got)
+	}
+}
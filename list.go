@@ -0,0 +1,189 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PrefixLister is implemented by keyrings that can list the keys starting
+// with a prefix without listing every key, for example by walking only a
+// subdirectory or by fetching only the matching items.
+type PrefixLister interface {
+	// Returns the keys that start with prefix
+	KeysWithPrefixContext(ctx context.Context, prefix string) ([]string, error)
+}
+
+// KeysWithPrefix returns the keys in k that start with prefix.
+func KeysWithPrefix(k Keyring, prefix string) ([]string, error) {
+	return KeysWithPrefixContext(context.Background(), k, prefix)
+}
+
+// KeysWithPrefixContext is KeysWithPrefix with a context. Keyrings that
+// implement PrefixLister filter the keys natively; for others every key is
+// listed and filtered here.
+func KeysWithPrefixContext(ctx context.Context, k Keyring, prefix string) ([]string, error) {
+	if pl, ok := k.(PrefixLister); ok && prefix != "" {
+		return pl.KeysWithPrefixContext(ctx, prefix)
+	}
+
+	keys, err := AsContextKeyring(k).KeysContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterKeys(keys, func(key string) bool { return strings.HasPrefix(key, prefix) }), nil
+}
+
+// filterKeys returns the keys for which keep returns true.
+func filterKeys(keys []string, keep func(key string) bool) []string {
+	found := []string{}
+	for _, key := range keys {
+		if keep(key) {
+			found = append(found, key)
+		}
+	}
+	return found
+}
+
+// ListOptions select the items List yields.
+type ListOptions struct {
+	// Prefix selects the keys that start with it.
+	Prefix string
+
+	// Pattern selects the keys matching it, in the syntax of path.Match, so
+	// "*" does not match a "/". The literal part of the pattern before its
+	// first wildcard is used as a prefix too.
+	Pattern string
+}
+
+// prefix returns the prefix every key matching opts starts with.
+func (opts ListOptions) prefix() string {
+	literal := opts.Pattern
+	if i := strings.IndexAny(literal, `*?[\`); i >= 0 {
+		literal = literal[:i]
+	}
+	if len(literal) > len(opts.Prefix) && strings.HasPrefix(literal, opts.Prefix) {
+		return literal
+	}
+	return opts.Prefix
+}
+
+// List returns an iterator over the metadata of the items in k selected by
+// opts. Keys are listed up front, using PrefixLister where k implements it,
+// but metadata is only read as the iteration reaches each item, so breaking
+// out of the loop early saves reading the rest.
+//
+// Items whose metadata k cannot return without credentials, or at all, are
+// yielded with only Metadata.Item.Key set. An error reading one item's
+// metadata is yielded with its key and the iteration carries on; an error
+// listing the keys is yielded once and ends it.
+func List(ctx context.Context, k Keyring, opts ListOptions) iter.Seq2[Metadata, error] {
+	return func(yield func(Metadata, error) bool) {
+		if opts.Pattern != "" {
+			if _, err := path.Match(opts.Pattern, ""); err != nil {
+				yield(Metadata{}, fmt.Errorf("key pattern %q: %w", opts.Pattern, err))
+				return
+			}
+		}
+
+		keys, err := KeysWithPrefixContext(ctx, k, opts.prefix())
+		if err != nil {
+			yield(Metadata{}, err)
+			return
+		}
+
+		ck := AsContextKeyring(k)
+		for _, key := range keys {
+			if !strings.HasPrefix(key, opts.Prefix) {
+				continue
+			}
+			if opts.Pattern != "" {
+				if ok, _ := path.Match(opts.Pattern, key); !ok {
+					continue
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				yield(Metadata{}, err)
+				return
+			}
+
+			md, err := ck.GetMetadataContext(ctx, key)
+			switch {
+			case errors.Is(err, ErrKeyNotFound):
+				continue // removed since the keys were listed
+			case errors.Is(err, ErrMetadataNotSupported), errors.Is(err, ErrMetadataNeedsCredentials):
+				md, err = Metadata{}, nil
+			}
+			if md.Item == nil {
+				md.Item = &Item{}
+			}
+			md.Item.Key = key
+			if !yield(md, err) {
+				return
+			}
+		}
+	}
+}
+
+// walkStoreKeys lists the keys of the files with extension ext in the tree at
+// root, laid out as pass and passage lay out their stores: each key is the
+// file's path relative to root, without the extension. Only the keys starting
+// with prefix are listed, and only the directories that could hold them are
+// walked.
+func walkStoreKeys(ctx context.Context, root, prefix, ext string) ([]string, error) {
+	keys := []string{}
+	info, err := os.Stat(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return keys, nil
+		}
+		return keys, err
+	}
+	if !info.IsDir() {
+		return keys, fmt.Errorf("%s is not a directory", root)
+	}
+
+	prefix = filepath.FromSlash(prefix)
+	start := root
+	if i := strings.LastIndexByte(prefix, os.PathSeparator); i >= 0 {
+		start = filepath.Join(root, prefix[:i])
+		if rel, err := filepath.Rel(root, start); err != nil || !filepath.IsLocal(rel) {
+			return keys, nil // the prefix reaches outside the store
+		}
+		if _, err := os.Stat(start); errors.Is(err, fs.ErrNotExist) {
+			return keys, nil
+		}
+	}
+
+	err = filepath.Walk(start, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(p, root)
+		if name != "" && name[0] == os.PathSeparator {
+			name = name[1:]
+		}
+		if info.IsDir() {
+			if p != start && !strings.HasPrefix(name, prefix) && !strings.HasPrefix(prefix, name+string(os.PathSeparator)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if key, ok := strings.CutSuffix(name, ext); ok && filepath.Ext(p) == ext && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+
+	return keys, err
+}
@@ -0,0 +1,153 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestKeysWithPrefix(t *testing.T) {
+	k := NewArrayKeyring([]Item{{Key: "team/a"}, {Key: "team/b"}, {Key: "other/a"}})
+
+	keys, err := KeysWithPrefix(k, "team/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(keys)
+	if want := []string{"team/a", "team/b"}; !slices.Equal(keys, want) {
+		t.Fatalf("Expected %v, got %v", want, keys)
+	}
+}
+
+// prefixListingKeyring lists keys natively, recording the prefixes asked for.
+type prefixListingKeyring struct {
+	*ArrayKeyring
+	prefixes []string
+}
+
+func (k *prefixListingKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) ([]string, error) {
+	k.prefixes = append(k.prefixes, prefix)
+	keys, err := k.KeysContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterKeys(keys, func(key string) bool { return strings.HasPrefix(key, prefix) }), nil
+}
+
+func TestWrappersListPrefixesNatively(t *testing.T) {
+	inner := &prefixListingKeyring{ArrayKeyring: NewArrayKeyring([]Item{{Key: "team/a"}, {Key: "other/a"}})}
+	var ops []Op
+	recordOps := func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			ops = append(ops, call.Op)
+			return next(ctx, call)
+		}
+	}
+
+	for _, k := range []Keyring{Wrap(inner, recordOps), NewCachingKeyring(inner, CacheOptions{})} {
+		keys, err := KeysWithPrefix(k, "team/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"team/a"}; !slices.Equal(keys, want) {
+			t.Fatalf("%T: expected %v, got %v", k, want, keys)
+		}
+	}
+	if want := []string{"team/", "team/"}; !slices.Equal(inner.prefixes, want) {
+		t.Fatalf("Expected the wrapped keyring to list %v, got %v", want, inner.prefixes)
+	}
+	if want := []Op{OpKeysWithPrefix}; !slices.Equal(ops, want) {
+		t.Fatalf("Expected the middleware to see %v, got %v", want, ops)
+	}
+}
+
+func TestList(t *testing.T) {
+	var reads int
+	countReads := func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			if call.Op == OpGetMetadata {
+				reads++
+			}
+			return next(ctx, call)
+		}
+	}
+	k := Wrap(NewArrayKeyring([]Item{
+		{Key: "team/app/db"},
+		{Key: "team/app/api"},
+		{Key: "team/app/nested/db"},
+		{Key: "team/web/db"},
+	}), countReads)
+
+	var keys []string
+	for md, err := range List(context.Background(), k, ListOptions{Pattern: "team/*/db"}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, md.Item.Key)
+	}
+	slices.Sort(keys)
+	if want := []string{"team/app/db", "team/web/db"}; !slices.Equal(keys, want) {
+		t.Fatalf("Expected %v, got %v", want, keys)
+	}
+
+	// Breaking out early reads no more metadata.
+	reads = 0
+	for range List(context.Background(), k, ListOptions{Prefix: "team/app/"}) {
+		break
+	}
+	if reads != 1 {
+		t.Fatalf("Expected 1 metadata read, got %d", reads)
+	}
+
+	for _, err := range List(context.Background(), k, ListOptions{Pattern: "team/["}) {
+		if err == nil {
+			t.Fatal("Expected an error for a bad pattern")
+		}
+	}
+}
+
+func TestWalkStoreKeys(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.gpg", "team/app/x.gpg", "team/apq.gpg", "team/other/y.gpg", "team/app/notes.txt"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for prefix, want := range map[string][]string{
+		"":          {"a", "team/app/x", "team/apq", "team/other/y"},
+		"team/ap":   {"team/app/x", "team/apq"},
+		"team/app/": {"team/app/x"},
+		"missing/":  {},
+		"../":       {},
+	} {
+		keys, err := walkStoreKeys(context.Background(), root, prefix, ".gpg")
+		if err != nil {
+			t.Fatalf("%q: %v", prefix, err)
+		}
+		for i := range want {
+			want[i] = filepath.FromSlash(want[i])
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, want) {
+			t.Errorf("%q: expected %v, got %v", prefix, want, keys)
+		}
+	}
+
+	if _, err := walkStoreKeys(context.Background(), filepath.Join(root, "a.gpg"), "", ".gpg"); err == nil {
+		t.Fatal("Expected an error listing a file")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := walkStoreKeys(ctx, root, "", ".gpg"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
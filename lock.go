@@ -0,0 +1,82 @@
+package keyring
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultLockTimeout is how long the file, pass and passage backends wait
+// for another process's lock on their directory when Config.LockTimeout is
+// zero.
+const DefaultLockTimeout = 30 * time.Second
+
+// LockTimeoutError is returned by the file, pass and passage backends when
+// another process held the lock on their directory for longer than the
+// lock timeout. It matches ErrBackendUnavailable, so the Retry middleware
+// tries again.
+type LockTimeoutError struct {
+	// Path is the directory whose lock could not be taken.
+	Path string
+
+	// Timeout is how long was waited for it.
+	Timeout time.Duration
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %v waiting for another process's lock on %s", e.Timeout, e.Path)
+}
+
+// Is reports whether target is ErrBackendUnavailable.
+func (e *LockTimeoutError) Is(target error) bool {
+	return target == ErrBackendUnavailable
+}
+
+// lockDir takes an advisory lock on dir, shared with other readers unless
+// exclusive is set, and returns the function that releases it. dir may also
+// be a file, such as an audit log. Locks are taken with flock where the
+// system has it, so they are only respected by other processes using this
+// package, not by pass itself or an editor. A directory that does not exist
+// yet has nothing to protect and is not locked.
+//
+// If the lock is not free within timeout, or DefaultLockTimeout if timeout
+// is zero, it returns a *LockTimeoutError.
+func lockDir(ctx context.Context, dir string, exclusive bool, timeout time.Duration) (func(), error) {
+	if timeout == 0 {
+		timeout = DefaultLockTimeout
+	}
+
+	f, err := openLock(dir)
+	if os.IsNotExist(err) {
+		return func() {}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for wait := 5 * time.Millisecond; ; wait = min(2*wait, 100*time.Millisecond) {
+		ok, err := tryLock(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, &os.PathError{Op: "lock", Path: dir, Err: err}
+		}
+		if ok {
+			// Closing the file releases the lock.
+			return func() { f.Close() }, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			f.Close()
+			return nil, &LockTimeoutError{Path: dir, Timeout: timeout}
+		}
+		t := time.NewTimer(min(wait, time.Until(deadline)))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			f.Close()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
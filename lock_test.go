@@ -0,0 +1,70 @@
+//go:build unix || windows
+
+package keyring
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockDirExclusive(t *testing.T) {
+	dir := t.TempDir()
+
+	release, err := lockDir(context.Background(), dir, true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exclusive := range []bool{true, false} {
+		_, err := lockDir(context.Background(), dir, exclusive, 50*time.Millisecond)
+		var lte *LockTimeoutError
+		if !errors.As(err, &lte) {
+			t.Fatalf("Expected a *LockTimeoutError taking the lock with exclusive=%v, got %v", exclusive, err)
+		}
+		if lte.Path != dir || lte.Timeout != 50*time.Millisecond {
+			t.Fatalf("Unexpected error %#v", lte)
+		}
+		if !errors.Is(err, ErrBackendUnavailable) {
+			t.Fatalf("Expected the timeout to match ErrBackendUnavailable")
+		}
+	}
+
+	release()
+	release, err = lockDir(context.Background(), dir, true, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected the lock to be free once released, got %v", err)
+	}
+	release()
+}
+
+func TestLockDirShared(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := lockDir(context.Background(), dir, false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first()
+
+	second, err := lockDir(context.Background(), dir, false, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected shared locks to coexist, got %v", err)
+	}
+	defer second()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := lockDir(ctx, dir, true, time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled waiting for a contended lock, got %v", err)
+	}
+}
+
+func TestLockDirMissing(t *testing.T) {
+	release, err := lockDir(context.Background(), t.TempDir()+"/missing", true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
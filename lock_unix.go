@@ -0,0 +1,36 @@
+//go:build unix
+
+package keyring
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openLock opens dir itself to lock, so that no lock file is left in it.
+func openLock(dir string) (*os.File, error) {
+	return os.Open(dir)
+}
+
+// tryLock takes a lock on f if it is free, reporting whether it did.
+func tryLock(f *os.File, exclusive bool) (bool, error) {
+	how := unix.LOCK_SH
+	if exclusive {
+		how = unix.LOCK_EX
+	}
+	for {
+		err := unix.Flock(int(f.Fd()), how|unix.LOCK_NB)
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, unix.EWOULDBLOCK):
+			return false, nil
+		case errors.Is(err, unix.EINTR):
+			continue
+		default:
+			return false, err
+		}
+	}
+}
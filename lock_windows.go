@@ -0,0 +1,32 @@
+//go:build windows
+
+package keyring
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// openLock opens a lock file next to dir, as Windows cannot lock a
+// directory.
+func openLock(dir string) (*os.File, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(dir+".lock", os.O_RDWR|os.O_CREATE, 0600)
+}
+
+// tryLock takes a lock on f if it is free, reporting whether it did.
+func tryLock(f *os.File, exclusive bool) (bool, error) {
+	flags := uint32(windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if exclusive {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	err := windows.LockFileEx(windows.Handle(f.Fd()), flags, 0, 1, 0, &windows.Overlapped{})
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return err == nil, err
+}
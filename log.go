@@ -0,0 +1,198 @@
+package keyring
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// logKeyAttr is the name of the attribute holding an item's key in log
+// records. Config.RedactLogKeys applies to attributes of this name.
+const logKeyAttr = "key"
+
+// debugLogger is used where no Config.Logger was given. It writes to the
+// standard log package while Debug is set, as debugf does.
+var debugLogger = slog.New(debugHandler{})
+
+// loggerOr returns l, or debugLogger if l is nil.
+func loggerOr(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return debugLogger
+	}
+	return l
+}
+
+// logger returns the logger for keyrings opened with cfg.
+func (cfg Config) logger() *slog.Logger {
+	l := loggerOr(cfg.Logger)
+	if cfg.RedactLogKeys {
+		l = slog.New(redactHandler{l.Handler()})
+	}
+	return l
+}
+
+// debugHandler is a slog.Handler writing "[keyring] message key=value ..."
+// lines to the standard log package while Debug is set.
+type debugHandler struct {
+	attrs string
+	group string
+}
+
+func (h debugHandler) Enabled(context.Context, slog.Level) bool {
+	return Debug
+}
+
+func (h debugHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString("[keyring] ")
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendLogAttr(&b, h.group, a)
+		return true
+	})
+	log.Print(b.String())
+	return nil
+}
+
+func (h debugHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		appendLogAttr(&b, h.group, a)
+	}
+	h.attrs = b.String()
+	return h
+}
+
+func (h debugHandler) WithGroup(name string) slog.Handler {
+	h.group += name + "."
+	return h
+}
+
+func appendLogAttr(b *strings.Builder, group string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			appendLogAttr(b, group+a.Key+".", ga)
+		}
+		return
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	fmt.Fprintf(b, " %s%s=%q", group, a.Key, v.String())
+}
+
+// redactHandler replaces the keys in log records with a short hash of them,
+// so that records about the same item can still be matched up.
+type redactHandler struct {
+	slog.Handler
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactLogAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		redacted = append(redacted, redactLogAttr(a))
+	}
+	return redactHandler{h.Handler.WithAttrs(redacted)}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{h.Handler.WithGroup(name)}
+}
+
+func redactLogAttr(a slog.Attr) slog.Attr {
+	if a.Key != logKeyAttr {
+		return a
+	}
+	sum := sha256.Sum256([]byte(a.Value.Resolve().String()))
+	return slog.String(a.Key, "sha256:"+hex.EncodeToString(sum[:6]))
+}
+
+// errorClass names the kind of err for logs, from the error sentinels it
+// matches.
+func errorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrKeyNotFound):
+		return "not-found"
+	case errors.Is(err, ErrLocked):
+		return "locked"
+	case errors.Is(err, ErrUserCancelled):
+		return "user-cancelled"
+	case errors.Is(err, ErrAccessDenied):
+		return "access-denied"
+	case errors.Is(err, ErrWrongPassphrase):
+		return "wrong-passphrase"
+	case errors.Is(err, ErrBackendUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrRateLimited):
+		return "rate-limited"
+	case errors.Is(err, ErrConflict):
+		return "conflict"
+	case errors.Is(err, ErrMetadataNotSupported):
+		return "metadata-not-supported"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "other"
+}
+
+// callLogger logs each operation on a backend opened with Config.Logger to
+// it at debug level. The zero value logs nothing.
+type callLogger struct {
+	l       *slog.Logger
+	backend BackendType
+}
+
+// newCallLogger returns the callLogger for backend opened with cfg.
+func newCallLogger(cfg Config, backend BackendType) callLogger {
+	if cfg.Logger == nil {
+		return callLogger{}
+	}
+	return callLogger{l: cfg.logger(), backend: backend}
+}
+
+// op starts an operation on key, and returns the function logging it once
+// it has finished with *err. Backends defer it with their named error result:
+//
+//	defer k.log.op(ctx, OpGet, key)(&err)
+func (c callLogger) op(ctx context.Context, op Op, key string) func(err *error) {
+	if c.l == nil || !c.l.Enabled(ctx, slog.LevelDebug) {
+		return func(*error) {}
+	}
+	start := time.Now()
+	return func(err *error) {
+		attrs := []slog.Attr{
+			slog.String("backend", string(c.backend)),
+			slog.String("op", string(op)),
+		}
+		if key != "" {
+			attrs = append(attrs, slog.String(logKeyAttr, key))
+		}
+		attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+		if *err != nil {
+			attrs = append(attrs, slog.String("error_class", errorClass(*err)), slog.Any("error", *err))
+		}
+		c.l.LogAttrs(ctx, slog.LevelDebug, "Keyring operation", attrs...)
+	}
+}
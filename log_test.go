@@ -0,0 +1,125 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// logRecords decodes the JSON log records in buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var r map[string]any
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// get stands in for a backend's GetContext, logging the call with c.
+func get(c callLogger, key string) (err error) {
+	defer c.op(context.Background(), OpGet, key)(&err)
+	if key == "missing" {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+func TestCallLogger(t *testing.T) {
+	var buf bytes.Buffer
+	c := callLogger{
+		l:       slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		backend: FileBackend,
+	}
+
+	if err := get(c, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(c, "missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	for i, want := range []map[string]any{
+		{"backend": "file", "op": "get", "key": "a"},
+		{"backend": "file", "op": "get", "key": "missing", "error_class": "not-found"},
+	} {
+		for attr, v := range want {
+			if records[i][attr] != v {
+				t.Errorf("Record %d: expected %s=%v, got %v", i, attr, v, records[i][attr])
+			}
+		}
+		if _, ok := records[i]["duration"]; !ok {
+			t.Errorf("Record %d has no duration", i)
+		}
+	}
+}
+
+func TestCallLoggerWithoutLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+	Debug = true
+	defer func() { Debug = false }()
+
+	if err := get(newCallLogger(Config{}, FileBackend), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("Logged without a Config.Logger: %s", buf.String())
+	}
+}
+
+func TestRedactLogKeys(t *testing.T) {
+	var buf bytes.Buffer
+	c := newCallLogger(Config{
+		Logger:        slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		RedactLogKeys: true,
+	}, FileBackend)
+
+	for range 2 {
+		if err := get(c, "team/db-password"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Contains(buf.String(), "db-password") {
+		t.Fatalf("The key was logged: %s", buf.String())
+	}
+	records := logRecords(t, &buf)
+	if records[0]["key"] != records[1]["key"] {
+		t.Fatalf("The same key was redacted differently: %v, %v", records[0]["key"], records[1]["key"])
+	}
+}
+
+func TestDebugShim(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	logger := Config{}.logger()
+	logger.Debug("Hidden")
+
+	Debug = true
+	defer func() { Debug = false }()
+	logger.With("backend", "file").Debug("Shown", "key", "a")
+
+	if strings.Contains(buf.String(), "Hidden") {
+		t.Fatalf("Logged while Debug was unset: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `[keyring] Shown backend="file" key="a"`) {
+		t.Fatalf("Unexpected output %q", buf.String())
+	}
+}
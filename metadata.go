@@ -0,0 +1,209 @@
+package keyring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/byteness/percent"
+)
+
+// MetadataKeyring is implemented by keyrings that can store caller-supplied
+// fields alongside an item. The fields are not secret: backends keep them
+// where GetMetadata can read them without unlocking the item.
+type MetadataKeyring interface {
+	Keyring
+	// Replaces the metadata fields of the item with matching key, or returns ErrKeyNotFound
+	SetMetadata(key string, fields map[string]string) error
+	// Replaces the metadata fields of the item with matching key, or returns ErrKeyNotFound
+	SetMetadataContext(ctx context.Context, key string, fields map[string]string) error
+}
+
+// SetMetadata replaces the metadata fields of the item with matching key. A
+// nil or empty fields removes them. It returns ErrMetadataNotSupported if k
+// cannot store metadata.
+func SetMetadata(k Keyring, key string, fields map[string]string) error {
+	mk, ok := k.(MetadataKeyring)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return mk.SetMetadata(key, fields)
+}
+
+// metadataRecord is the metadata a backend keeps for an item when it has to
+// store it itself, in a sidecar or, for the file backend, in the JWE header.
+type metadataRecord struct {
+	Label       string            `json:"label,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Created     time.Time         `json:"created"`
+	Modified    time.Time         `json:"modified"`
+	Fields      map[string]string `json:"fields,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Expires     time.Time         `json:"expires,omitzero"`
+
+	// Empty is set by backends that cannot store an item with no data, such
+	// as keyctl, when what they stored in its place stands for no data.
+	Empty bool `json:"empty,omitempty"`
+}
+
+// update returns the record for item having been stored at now. The creation
+// time and fields of an existing record are kept.
+func (r metadataRecord) update(item Item, now time.Time) metadataRecord {
+	if r.Created.IsZero() {
+		r.Created = now
+	}
+	r.Modified = now
+	r.Label = item.Label
+	r.Description = item.Description
+	r.Attributes = maps.Clone(item.Attributes)
+	if len(r.Attributes) == 0 {
+		r.Attributes = nil
+	}
+	r.Expires = item.Expires
+	r.Empty = false
+	return r
+}
+
+// withFields returns the record with its fields replaced at now.
+func (r metadataRecord) withFields(fields map[string]string, now time.Time) metadataRecord {
+	r.Fields = maps.Clone(fields)
+	if len(r.Fields) == 0 {
+		r.Fields = nil
+	}
+	r.Modified = now
+	return r
+}
+
+func (r metadataRecord) metadata(key string) Metadata {
+	return Metadata{
+		Item: &Item{
+			Key:         key,
+			Label:       r.Label,
+			Description: r.Description,
+			Attributes:  maps.Clone(r.Attributes),
+			Expires:     r.Expires,
+		},
+		ModificationTime: r.Modified,
+		CreationTime:     r.Created,
+		Fields:           maps.Clone(r.Fields),
+	}
+}
+
+// sidecarStore is where a backend with no native place for metadata keeps its
+// metadataRecords. Reading it must not require unlocking the keyring.
+type sidecarStore interface {
+	GetContext(ctx context.Context, key string) (Item, error)
+	SetContext(ctx context.Context, item Item) error
+	RemoveContext(ctx context.Context, key string) error
+}
+
+// metadataSidecar keeps metadataRecords as JSON in a sidecarStore, under the
+// key of the item they describe with prefix prepended. Callers check that the
+// item exists.
+type metadataSidecar struct {
+	store  sidecarStore
+	prefix string
+}
+
+func (s metadataSidecar) get(ctx context.Context, key string) (metadataRecord, error) {
+	item, err := s.store.GetContext(ctx, s.prefix+key)
+	if err != nil {
+		return metadataRecord{}, err
+	}
+	var rec metadataRecord
+	err = json.Unmarshal(item.Data, &rec)
+	return rec, err
+}
+
+func (s metadataSidecar) put(ctx context.Context, key string, rec metadataRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.store.SetContext(ctx, Item{Key: s.prefix + key, Data: data})
+}
+
+// metadata returns the metadata for key. Items stored before the backend kept
+// a sidecar have no record, so only their key is known.
+func (s metadataSidecar) metadata(ctx context.Context, key string) (Metadata, error) {
+	rec, err := s.get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return Metadata{Item: &Item{Key: key}}, nil
+	} else if err != nil {
+		return Metadata{}, err
+	}
+	return rec.metadata(key), nil
+}
+
+// itemSet updates the record for item after it has been stored.
+func (s metadataSidecar) itemSet(ctx context.Context, item Item) error {
+	rec, err := s.get(ctx, item.Key)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	return s.put(ctx, item.Key, rec.update(item, time.Now()))
+}
+
+// setFields replaces the fields in the record for key.
+func (s metadataSidecar) setFields(ctx context.Context, key string, fields map[string]string) error {
+	rec, err := s.get(ctx, key)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	return s.put(ctx, key, rec.withFields(fields, time.Now()))
+}
+
+// remove deletes the record for key, if there is one.
+func (s metadataSidecar) remove(ctx context.Context, key string) error {
+	err := s.store.RemoveContext(ctx, s.prefix+key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// dirSidecar is a sidecarStore of plaintext JSON files in a directory, for
+// backends whose items are files encrypted by an external program.
+type dirSidecar string
+
+func (d dirSidecar) filename(key string) string {
+	return filepath.Join(string(d), percent.Encode(key, "/")+".json")
+}
+
+func (d dirSidecar) GetContext(ctx context.Context, key string) (Item, error) {
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+	data, err := os.ReadFile(d.filename(key))
+	if os.IsNotExist(err) {
+		return Item{}, ErrKeyNotFound
+	} else if err != nil {
+		return Item{}, err
+	}
+	return Item{Key: key, Data: data}, nil
+}
+
+func (d dirSidecar) SetContext(ctx context.Context, item Item) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(string(d), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(d.filename(item.Key), item.Data, 0600)
+}
+
+func (d dirSidecar) RemoveContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := os.Remove(d.filename(key))
+	if os.IsNotExist(err) {
+		return ErrKeyNotFound
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetMetadataNotSupported(t *testing.T) {
+	k := &ArrayKeyring{}
+	if err := SetMetadata(k, "llamas", map[string]string{"owner": "ops"}); !errors.Is(err, ErrMetadataNotSupported) {
+		t.Fatalf("Expected ErrMetadataNotSupported, got %v", err)
+	}
+}
+
+func TestMetadataSidecar(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "metadata")
+	s := metadataSidecar{store: dirSidecar(dir)}
+
+	// An item stored before the sidecar existed has only its key.
+	md, err := s.metadata(ctx, "a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Key != "a/b" || !md.CreationTime.IsZero() {
+		t.Fatalf("Unexpected metadata for an unknown item: %+v", md)
+	}
+
+	if err := s.itemSet(ctx, Item{Key: "a/b", Label: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.setFields(ctx, "a/b", map[string]string{"owner": "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.metadata(ctx, "a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.itemSet(ctx, Item{Key: "a/b", Label: "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	md, err = s.metadata(ctx, "a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Label != "second" || md.Fields["owner"] != "ops" || !md.CreationTime.Equal(first.CreationTime) {
+		t.Fatalf("Unexpected metadata after a second Set: %+v", md)
+	}
+
+	if err := s.remove(ctx, "a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.remove(ctx, "a/b"); err != nil {
+		t.Fatalf("Removing a missing record should succeed, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("Expected no records left, found %d", len(entries))
+	}
+}
@@ -0,0 +1,359 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// Op names a keyring operation passing through middleware.
+type Op string
+
+const (
+	OpGet              Op = "get"
+	OpGetMetadata      Op = "get-metadata"
+	OpSet              Op = "set"
+	OpSetMetadata      Op = "set-metadata"
+	OpRemove           Op = "remove"
+	OpKeys             Op = "keys"
+	OpFindByAttributes Op = "find-by-attributes"
+	OpGetVersioned     Op = "get-versioned"
+	OpSetIfAbsent      Op = "set-if-absent"
+	OpCompareAndSwap   Op = "compare-and-swap"
+	OpHistory          Op = "history"
+	OpRestore          Op = "restore"
+	OpGetMany          Op = "get-many"
+	OpSetMany          Op = "set-many"
+	OpRemoveMany       Op = "remove-many"
+	OpKeysWithPrefix   Op = "keys-with-prefix"
+)
+
+// IsWrite reports whether the operation changes the keyring.
+func (op Op) IsWrite() bool {
+	switch op {
+	case OpSet, OpSetMetadata, OpRemove, OpSetIfAbsent, OpCompareAndSwap, OpRestore, OpSetMany, OpRemoveMany:
+		return true
+	}
+	return false
+}
+
+// Call is a keyring operation passing through middleware. Middleware may
+// change any of it before passing it on, and the results after.
+type Call struct {
+	Op  Op
+	Key string
+
+	// Item is the item to store for OpSet, OpSetIfAbsent and
+	// OpCompareAndSwap, under Key, and the item read for OpGet and
+	// OpGetVersioned.
+	Item Item
+
+	// Version is the version read for OpGetVersioned, the one expected by
+	// OpCompareAndSwap and the earlier one to bring back for OpRestore.
+	Version string
+
+	// Revisions are the earlier versions listed by OpHistory.
+	Revisions []Revision
+
+	// Metadata is the metadata read for OpGetMetadata.
+	Metadata Metadata
+
+	// Fields are the metadata fields to store for OpSetMetadata.
+	Fields map[string]string
+
+	// Attributes are the attributes to search for with OpFindByAttributes.
+	Attributes map[string]string
+
+	// Prefix is the prefix of the keys to list for OpKeysWithPrefix.
+	Prefix string
+
+	// Keys are the keys listed by OpKeys and OpKeysWithPrefix or found by
+	// OpFindByAttributes, and the keys to read for OpGetMany or remove for
+	// OpRemoveMany.
+	Keys []string
+
+	// Items are the items to store for OpSetMany.
+	Items []Item
+
+	// Found are the items read by OpGetMany, mapped by key.
+	Found map[string]Item
+}
+
+// Handler performs a Call, storing its results in it.
+type Handler func(ctx context.Context, call *Call) error
+
+// Middleware wraps a Handler with behaviour of its own.
+type Middleware func(next Handler) Handler
+
+// WrappedKeyring is a Keyring whose operations pass through a chain of
+// middleware before reaching another keyring. It supports metadata,
+// attribute searches, conditional writes and history if the wrapped keyring
+// does, and passes batches and prefix listings to it whole if it implements
+// BatchKeyring or PrefixLister.
+type WrappedKeyring struct {
+	inner   ContextKeyring
+	handler Handler
+}
+
+// Wrap returns k with its operations passing through middlewares. The first
+// middleware is the outermost: it sees each call first and its result last.
+func Wrap(k Keyring, middlewares ...Middleware) *WrappedKeyring {
+	w := &WrappedKeyring{inner: AsContextKeyring(k)}
+	w.handler = w.call
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		w.handler = middlewares[i](w.handler)
+	}
+	return w
+}
+
+// call performs call against the wrapped keyring.
+func (w *WrappedKeyring) call(ctx context.Context, call *Call) error {
+	var err error
+	switch call.Op {
+	case OpGet:
+		call.Item, err = w.inner.GetContext(ctx, call.Key)
+	case OpGetMetadata:
+		call.Metadata, err = w.inner.GetMetadataContext(ctx, call.Key)
+	case OpSet:
+		item := call.Item
+		item.Key = call.Key
+		err = w.inner.SetContext(ctx, item)
+	case OpSetMetadata:
+		mk, ok := w.inner.(MetadataKeyring)
+		if !ok {
+			return ErrMetadataNotSupported
+		}
+		err = mk.SetMetadataContext(ctx, call.Key, call.Fields)
+	case OpRemove:
+		err = w.inner.RemoveContext(ctx, call.Key)
+	case OpKeys:
+		call.Keys, err = w.inner.KeysContext(ctx)
+	case OpKeysWithPrefix:
+		call.Keys, err = KeysWithPrefixContext(ctx, w.inner, call.Prefix)
+	case OpFindByAttributes:
+		call.Keys, err = FindByAttributesContext(ctx, w.inner, call.Attributes)
+	case OpGetVersioned:
+		call.Item, call.Version, err = GetVersionedContext(ctx, w.inner, call.Key)
+	case OpSetIfAbsent:
+		item := call.Item
+		item.Key = call.Key
+		err = SetIfAbsentContext(ctx, w.inner, item)
+	case OpCompareAndSwap:
+		err = CompareAndSwapContext(ctx, w.inner, call.Key, call.Version, call.Item)
+	case OpHistory:
+		call.Revisions, err = HistoryContext(ctx, w.inner, call.Key)
+	case OpRestore:
+		err = RestoreContext(ctx, w.inner, call.Key, call.Version)
+	case OpGetMany:
+		call.Found, err = GetManyContext(ctx, w.inner, call.Keys)
+	case OpSetMany:
+		err = SetManyContext(ctx, w.inner, call.Items)
+	case OpRemoveMany:
+		err = RemoveManyContext(ctx, w.inner, call.Keys)
+	default:
+		return errors.New("keyring: unknown operation " + string(call.Op))
+	}
+	return err
+}
+
+func (w *WrappedKeyring) Get(key string) (Item, error) {
+	return w.GetContext(context.Background(), key)
+}
+
+func (w *WrappedKeyring) GetContext(ctx context.Context, key string) (Item, error) {
+	call := &Call{Op: OpGet, Key: key}
+	if err := w.handler(ctx, call); err != nil {
+		return Item{}, err
+	}
+	return call.Item, nil
+}
+
+func (w *WrappedKeyring) GetMetadata(key string) (Metadata, error) {
+	return w.GetMetadataContext(context.Background(), key)
+}
+
+func (w *WrappedKeyring) GetMetadataContext(ctx context.Context, key string) (Metadata, error) {
+	call := &Call{Op: OpGetMetadata, Key: key}
+	if err := w.handler(ctx, call); err != nil {
+		return Metadata{}, err
+	}
+	return call.Metadata, nil
+}
+
+func (w *WrappedKeyring) Set(item Item) error {
+	return w.SetContext(context.Background(), item)
+}
+
+func (w *WrappedKeyring) SetContext(ctx context.Context, item Item) error {
+	return w.handler(ctx, &Call{Op: OpSet, Key: item.Key, Item: item})
+}
+
+func (w *WrappedKeyring) SetMetadata(key string, fields map[string]string) error {
+	return w.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (w *WrappedKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) error {
+	return w.handler(ctx, &Call{Op: OpSetMetadata, Key: key, Fields: fields})
+}
+
+func (w *WrappedKeyring) Remove(key string) error {
+	return w.RemoveContext(context.Background(), key)
+}
+
+func (w *WrappedKeyring) RemoveContext(ctx context.Context, key string) error {
+	return w.handler(ctx, &Call{Op: OpRemove, Key: key})
+}
+
+func (w *WrappedKeyring) Keys() ([]string, error) {
+	return w.KeysContext(context.Background())
+}
+
+func (w *WrappedKeyring) KeysContext(ctx context.Context) ([]string, error) {
+	call := &Call{Op: OpKeys}
+	if err := w.handler(ctx, call); err != nil {
+		return nil, err
+	}
+	return call.Keys, nil
+}
+
+func (w *WrappedKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) ([]string, error) {
+	call := &Call{Op: OpKeysWithPrefix, Prefix: prefix}
+	if err := w.handler(ctx, call); err != nil {
+		return nil, err
+	}
+	return call.Keys, nil
+}
+
+func (w *WrappedKeyring) FindByAttributesContext(ctx context.Context, attrs map[string]string) ([]string, error) {
+	call := &Call{Op: OpFindByAttributes, Attributes: attrs}
+	if err := w.handler(ctx, call); err != nil {
+		return nil, err
+	}
+	return call.Keys, nil
+}
+
+func (w *WrappedKeyring) GetVersionedContext(ctx context.Context, key string) (Item, string, error) {
+	call := &Call{Op: OpGetVersioned, Key: key}
+	if err := w.handler(ctx, call); err != nil {
+		return Item{}, "", err
+	}
+	return call.Item, call.Version, nil
+}
+
+func (w *WrappedKeyring) SetIfAbsentContext(ctx context.Context, item Item) error {
+	return w.handler(ctx, &Call{Op: OpSetIfAbsent, Key: item.Key, Item: item})
+}
+
+func (w *WrappedKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) error {
+	return w.handler(ctx, &Call{Op: OpCompareAndSwap, Key: key, Version: expectedVersion, Item: item})
+}
+
+func (w *WrappedKeyring) HistoryContext(ctx context.Context, key string) ([]Revision, error) {
+	call := &Call{Op: OpHistory, Key: key}
+	if err := w.handler(ctx, call); err != nil {
+		return nil, err
+	}
+	return call.Revisions, nil
+}
+
+func (w *WrappedKeyring) RestoreContext(ctx context.Context, key, version string) error {
+	return w.handler(ctx, &Call{Op: OpRestore, Key: key, Version: version})
+}
+
+func (w *WrappedKeyring) GetManyContext(ctx context.Context, keys []string) (map[string]Item, error) {
+	call := &Call{Op: OpGetMany, Keys: keys}
+	err := w.handler(ctx, call)
+	return call.Found, err
+}
+
+func (w *WrappedKeyring) SetManyContext(ctx context.Context, items []Item) error {
+	return w.handler(ctx, &Call{Op: OpSetMany, Items: items})
+}
+
+func (w *WrappedKeyring) RemoveManyContext(ctx context.Context, keys []string) error {
+	return w.handler(ctx, &Call{Op: OpRemoveMany, Keys: keys})
+}
+
+// Watch watches the wrapped keyring. Polling it, if it must be polled, does
+// not go through the middleware.
+func (w *WrappedKeyring) Watch(ctx context.Context) (<-chan Event, error) {
+	return Watch(ctx, w.inner)
+}
+
+// ErrReadOnly is returned by the ReadOnly middleware for operations that
+// would change the keyring.
+var ErrReadOnly = errors.New("the keyring is read-only")
+
+// ReadOnly returns middleware that rejects Set, SetMetadata and Remove with
+// ErrReadOnly.
+func ReadOnly() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			if call.Op.IsWrite() {
+				return ErrReadOnly
+			}
+			return next(ctx, call)
+		}
+	}
+}
+
+// Timeout returns middleware that gives each operation at most d to
+// complete.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, call)
+		}
+	}
+}
+
+// Retry returns middleware that retries operations failing with
+// ErrBackendUnavailable or ErrRateLimited, up to attempts times in all. The
+// delay between attempts starts at delay and doubles each time.
+func Retry(attempts int, delay time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			wait := delay
+			for attempt := 1; ; attempt++ {
+				err := next(ctx, call)
+				if attempt >= attempts || !(errors.Is(err, ErrBackendUnavailable) || errors.Is(err, ErrRateLimited)) {
+					return err
+				}
+				debugf("Retrying %s %q after %v: %v", call.Op, call.Key, wait, err)
+
+				t := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return err
+				case <-t.C:
+				}
+				wait *= 2
+			}
+		}
+	}
+}
+
+// Logging returns middleware that logs each operation with its key, how long
+// it took and its error, using logf or, if that is nil, log.Printf. Item data
+// is never logged.
+func Logging(logf func(format string, args ...any)) Middleware {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			start := time.Now()
+			err := next(ctx, call)
+			if err != nil {
+				logf("[keyring] %s %q failed after %v: %v", call.Op, call.Key, time.Since(start), err)
+			} else {
+				logf("[keyring] %s %q took %v", call.Op, call.Key, time.Since(start))
+			}
+			return err
+		}
+	}
+}
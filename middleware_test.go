@@ -0,0 +1,131 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyKeyring fails the first failures Gets as unavailable.
+type flakyKeyring struct {
+	*ArrayKeyring
+	failures int
+}
+
+func (k *flakyKeyring) GetContext(ctx context.Context, key string) (Item, error) {
+	if k.failures > 0 {
+		k.failures--
+		return Item{}, classify(ErrBackendUnavailable, errors.New("connection refused"))
+	}
+	return k.ArrayKeyring.GetContext(ctx, key)
+}
+
+func TestWrapOrder(t *testing.T) {
+	var trace []string
+	tracing := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) error {
+				trace = append(trace, name+" "+string(call.Op))
+				err := next(ctx, call)
+				trace = append(trace, name+" done")
+				return err
+			}
+		}
+	}
+	// A middleware may change the key and the result.
+	rename := func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			call.Key = "prefix/" + call.Key
+			err := next(ctx, call)
+			if call.Op == OpGet && err == nil {
+				call.Item.Label = "seen"
+			}
+			return err
+		}
+	}
+
+	inner := NewArrayKeyring(nil)
+	w := Wrap(inner, tracing("outer"), tracing("inner"), rename)
+	if err := w.Set(Item{Key: "a", Data: []byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inner.Get("prefix/a"); err != nil {
+		t.Fatalf("The changed key was not used: %v", err)
+	}
+	item, err := w.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Label != "seen" {
+		t.Fatalf("The changed result was not returned: %+v", item)
+	}
+
+	want := []string{"outer set", "inner set", "inner done", "outer done", "outer get", "inner get", "inner done", "outer done"}
+	if !slices.Equal(trace, want) {
+		t.Fatalf("Middleware ran in the order %v, want %v", trace, want)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	w := Wrap(NewArrayKeyring([]Item{{Key: "a"}}), ReadOnly())
+	if err := w.Set(Item{Key: "b"}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Set: expected ErrReadOnly, got %v", err)
+	}
+	if err := w.Remove("a"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Remove: expected ErrReadOnly, got %v", err)
+	}
+	if err := SetMetadata(w, "a", nil); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("SetMetadata: expected ErrReadOnly, got %v", err)
+	}
+	if err := CompareAndSwap(w, "a", "1", Item{}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("CompareAndSwap: expected ErrReadOnly, got %v", err)
+	}
+	if _, _, err := GetVersioned(w, "a"); !errors.Is(err, ErrConditionalNotSupported) {
+		t.Fatalf("GetVersioned: expected ErrConditionalNotSupported, got %v", err)
+	}
+	if _, err := w.Get("a"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	inner := &flakyKeyring{ArrayKeyring: NewArrayKeyring([]Item{{Key: "a"}}), failures: 2}
+	if _, err := Wrap(inner, Retry(3, time.Millisecond)).Get("a"); err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got %v", err)
+	}
+
+	inner.failures = 2
+	if _, err := Wrap(inner, Retry(2, time.Millisecond)).Get("a"); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("Expected ErrBackendUnavailable after two attempts, got %v", err)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var lines []string
+	logf := func(format string, args ...any) { lines = append(lines, fmt.Sprintf(format, args...)) }
+
+	w := Wrap(NewArrayKeyring([]Item{{Key: "a", Data: []byte("secret")}}), Logging(logf))
+	if _, err := w.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("Expected one line logged, got %q", lines)
+	}
+	if !strings.Contains(lines[0], `get "a"`) || strings.Contains(lines[0], "secret") {
+		t.Fatalf("Unexpected line %q", lines[0])
+	}
+}
+
+func TestWrapPreservesMetadata(t *testing.T) {
+	w := Wrap(NewArrayKeyring(nil))
+	if err := SetMetadata(w, "a", nil); !errors.Is(err, ErrMetadataNotSupported) {
+		t.Fatalf("Expected ErrMetadataNotSupported from a keyring without metadata, got %v", err)
+	}
+	if _, err := w.GetMetadata("a"); !errors.Is(err, ErrMetadataNeedsCredentials) {
+		t.Fatalf("Expected the wrapped keyring's GetMetadata error, got %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MigrateConflict says what Migrate does with an item whose key already
+// exists in the destination keyring.
+type MigrateConflict int
+
+const (
+	// MigrateSkipExisting leaves the destination item as it is.
+	MigrateSkipExisting MigrateConflict = iota
+	// MigrateOverwrite replaces the destination item.
+	MigrateOverwrite
+)
+
+// MigrateOptions configure Migrate.
+type MigrateOptions struct {
+	// Filter selects the source keys to migrate. Nil migrates every key.
+	Filter func(key string) bool
+
+	// Rename returns the destination key for a source key. Nil keeps the
+	// keys as they are. See ReplaceKeyPrefix.
+	Rename func(key string) string
+
+	// Conflict says what to do when the destination key already exists.
+	Conflict MigrateConflict
+
+	// DryRun reports what would be migrated without reading any items or
+	// changing either keyring.
+	DryRun bool
+
+	// Verify reads each item back from the destination and checks that its
+	// Data matches the source.
+	Verify bool
+
+	// DeleteSource removes each item from the source once it has been
+	// copied and verified. It implies Verify.
+	DeleteSource bool
+}
+
+// ReplaceKeyPrefix returns a MigrateOptions.Rename function that replaces
+// the prefix old of a key with new. Keys without the prefix are unchanged.
+func ReplaceKeyPrefix(old, new string) func(key string) string {
+	return func(key string) string {
+		if rest, ok := strings.CutPrefix(key, old); ok {
+			return new + rest
+		}
+		return key
+	}
+}
+
+// MigrateAction is what Migrate did with an item, or would do in a dry run.
+type MigrateAction string
+
+const (
+	// MigrateCopied means the item was copied to the destination.
+	MigrateCopied MigrateAction = "copied"
+	// MigrateSkipped means the destination key already existed, the item
+	// would have been copied onto itself, or it was gone from the source by
+	// the time it was read.
+	MigrateSkipped MigrateAction = "skipped"
+	// MigrateFailed means the item could not be migrated; see Err.
+	MigrateFailed MigrateAction = "failed"
+)
+
+// MigrateResult reports the migration of a single item.
+type MigrateResult struct {
+	// Key is the item's key in the source, DestKey its key in the
+	// destination.
+	Key     string
+	DestKey string
+
+	Action MigrateAction
+
+	// Verified is set if the item was read back from the destination and
+	// matched.
+	Verified bool
+
+	// SourceRemoved is set if the item was removed from the source.
+	SourceRemoved bool
+
+	// Err is why the item could not be migrated, if Action is
+	// MigrateFailed.
+	Err error
+}
+
+// MigrateReport lists what Migrate did with each source key it selected, in
+// the order the source listed them.
+type MigrateReport struct {
+	DryRun  bool
+	Results []MigrateResult
+}
+
+// MigrateError is returned by Migrate when some of the items could not be
+// migrated. errors.Is matches any of the individual failures.
+type MigrateError struct {
+	// Failures are the results of the items that failed.
+	Failures []MigrateResult
+}
+
+func (e *MigrateError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "migrating %d items failed", len(e.Failures))
+	for i, f := range e.Failures {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s: %v", f.Key, f.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns each item's error.
+func (e *MigrateError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		errs = append(errs, f.Err)
+	}
+	return errs
+}
+
+// Migrate copies the items in src to dst. Items are copied whole, so their
+// Label, Description and Attributes are kept wherever dst can store them,
+// and metadata fields are copied too if both keyrings support them.
+//
+// An item that cannot be migrated does not stop the others: the returned
+// error is then a *MigrateError, and the report says what happened to every
+// item. Migrate stops early only if the key lists cannot be read or ctx is
+// done.
+func Migrate(ctx context.Context, src, dst Keyring, opts MigrateOptions) (MigrateReport, error) {
+	report := MigrateReport{DryRun: opts.DryRun}
+	csrc, cdst := AsContextKeyring(src), AsContextKeyring(dst)
+
+	srcKeys, err := csrc.KeysContext(ctx)
+	if err != nil {
+		return report, fmt.Errorf("listing source keys: %w", err)
+	}
+	dstKeys, err := cdst.KeysContext(ctx)
+	if err != nil {
+		return report, fmt.Errorf("listing destination keys: %w", err)
+	}
+	existing := make(map[string]bool, len(dstKeys))
+	for _, key := range dstKeys {
+		existing[key] = true
+	}
+	same := sameKeyring(src, dst)
+
+	migrateErr := &MigrateError{}
+	for _, key := range srcKeys {
+		if opts.Filter != nil && !opts.Filter(key) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		result := MigrateResult{Key: key, DestKey: key}
+		if opts.Rename != nil {
+			result.DestKey = opts.Rename(key)
+		}
+
+		switch {
+		case same && result.DestKey == key:
+			// Copying the item onto itself would change nothing, and
+			// DeleteSource would then remove it.
+			result.Action = MigrateSkipped
+		case existing[result.DestKey] && opts.Conflict == MigrateSkipExisting:
+			result.Action = MigrateSkipped
+		case opts.DryRun:
+			result.Action = MigrateCopied
+		default:
+			result = migrateItem(ctx, csrc, cdst, result, opts)
+		}
+
+		if result.Action == MigrateFailed {
+			migrateErr.Failures = append(migrateErr.Failures, result)
+		} else if result.Action == MigrateCopied {
+			existing[result.DestKey] = true
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if len(migrateErr.Failures) > 0 {
+		return report, migrateErr
+	}
+	return report, nil
+}
+
+// sameKeyring reports whether a and b are the same keyring, as far as can be
+// told: both are the same pointer. Keyrings held by value are taken to be
+// different.
+func sameKeyring(a, b Keyring) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || reflect.ValueOf(a).Kind() != reflect.Pointer {
+		return false
+	}
+	return a == b
+}
+
+// migrateItem copies, verifies and removes a single item, and returns result
+// with what it did.
+func migrateItem(ctx context.Context, src, dst ContextKeyring, result MigrateResult, opts MigrateOptions) MigrateResult {
+	fail := func(err error) MigrateResult {
+		result.Action = MigrateFailed
+		result.Err = err
+		return result
+	}
+
+	item, err := src.GetContext(ctx, result.Key)
+	if errors.Is(err, ErrKeyNotFound) {
+		// Removed or expired since the keys were listed.
+		result.Action = MigrateSkipped
+		return result
+	} else if err != nil {
+		return fail(fmt.Errorf("reading source: %w", err))
+	}
+
+	data := item.Data
+	item.Key = result.DestKey
+	if err := dst.SetContext(ctx, item); err != nil {
+		return fail(fmt.Errorf("writing destination: %w", err))
+	}
+	result.Action = MigrateCopied
+
+	if err := migrateFields(ctx, src, dst, result); err != nil {
+		return fail(err)
+	}
+
+	if !opts.Verify && !opts.DeleteSource {
+		return result
+	}
+	got, err := dst.GetContext(ctx, result.DestKey)
+	if err != nil {
+		return fail(fmt.Errorf("verifying destination: %w", err))
+	}
+	if !bytes.Equal(got.Data, data) {
+		return fail(errors.New("verifying destination: data read back does not match the source"))
+	}
+	result.Verified = true
+
+	if opts.DeleteSource {
+		if err := src.RemoveContext(ctx, result.Key); err != nil {
+			return fail(fmt.Errorf("removing source: %w", err))
+		}
+		result.SourceRemoved = true
+	}
+	return result
+}
+
+// migrateFields copies the metadata fields of an item, if the source has any
+// and the destination can store them.
+func migrateFields(ctx context.Context, src, dst ContextKeyring, result MigrateResult) error {
+	mdst, ok := dst.(MetadataKeyring)
+	if !ok {
+		return nil
+	}
+	md, err := src.GetMetadataContext(ctx, result.Key)
+	if err != nil || len(md.Fields) == 0 {
+		return nil //nolint:nilerr // fields are copied where they can be
+	}
+	err = mdst.SetMetadataContext(ctx, result.DestKey, md.Fields)
+	if err != nil && !errors.Is(err, ErrMetadataNotSupported) {
+		return fmt.Errorf("writing destination metadata: %w", err)
+	}
+	return nil
+}
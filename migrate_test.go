@@ -0,0 +1,124 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// corruptingKeyring stores items with their data changed.
+type corruptingKeyring struct {
+	*ArrayKeyring
+}
+
+func (k corruptingKeyring) SetContext(ctx context.Context, item Item) error {
+	item.Data = append([]byte("corrupt "), item.Data...)
+	return k.ArrayKeyring.SetContext(ctx, item)
+}
+
+func TestMigrate(t *testing.T) {
+	src := NewArrayKeyring([]Item{
+		{Key: "team/a", Data: []byte("a"), Label: "A", Description: "first"},
+		{Key: "team/b", Data: []byte("b")},
+		{Key: "other", Data: []byte("c")},
+	})
+	dst := NewArrayKeyring([]Item{{Key: "shared/b", Data: []byte("old b")}})
+
+	opts := MigrateOptions{
+		Filter:       func(key string) bool { return key != "other" },
+		Rename:       ReplaceKeyPrefix("team/", "shared/"),
+		DeleteSource: true,
+	}
+	report, err := Migrate(t.Context(), src, dst, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []MigrateResult{
+		{Key: "team/a", DestKey: "shared/a", Action: MigrateCopied, Verified: true, SourceRemoved: true},
+		{Key: "team/b", DestKey: "shared/b", Action: MigrateSkipped},
+	}
+	got := report.Results
+	slices.SortFunc(got, func(a, b MigrateResult) int { return strings.Compare(a.Key, b.Key) })
+	if !slices.Equal(got, want) {
+		t.Fatalf("Migrate results = %+v, want %+v", got, want)
+	}
+
+	item, err := dst.Get("shared/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(item.Data) != "a" || item.Label != "A" || item.Description != "first" {
+		t.Fatalf("Unexpected migrated item %+v", item)
+	}
+	if _, err := src.Get("team/a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected the source item to be removed, got %v", err)
+	}
+	if item, _ := dst.Get("shared/b"); string(item.Data) != "old b" {
+		t.Fatalf("An existing item was overwritten: %q", item.Data)
+	}
+
+	// Overwriting replaces it; a dry run only says so.
+	opts.Conflict, opts.DryRun, opts.DeleteSource = MigrateOverwrite, true, false
+	report, err = Migrate(t.Context(), src, dst, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 1 || report.Results[0].Action != MigrateCopied || !report.DryRun {
+		t.Fatalf("Unexpected dry run report %+v", report)
+	}
+	if item, _ := dst.Get("shared/b"); string(item.Data) != "old b" {
+		t.Fatalf("A dry run changed the destination: %q", item.Data)
+	}
+}
+
+func TestMigrateOntoItself(t *testing.T) {
+	k := NewArrayKeyring([]Item{
+		{Key: "team/a", Data: []byte("a")},
+		{Key: "b", Data: []byte("b")},
+	})
+
+	report, err := Migrate(t.Context(), k, k, MigrateOptions{
+		Rename:       ReplaceKeyPrefix("team/", "shared/"),
+		Conflict:     MigrateOverwrite,
+		DeleteSource: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []MigrateResult{
+		{Key: "b", DestKey: "b", Action: MigrateSkipped},
+		{Key: "team/a", DestKey: "shared/a", Action: MigrateCopied, Verified: true, SourceRemoved: true},
+	}
+	got := report.Results
+	slices.SortFunc(got, func(a, b MigrateResult) int { return strings.Compare(a.Key, b.Key) })
+	if !slices.Equal(got, want) {
+		t.Fatalf("Migrate results = %+v, want %+v", got, want)
+	}
+	if item, err := k.Get("b"); err != nil || string(item.Data) != "b" {
+		t.Fatalf("An item mapped onto itself was lost: %+v, %v", item, err)
+	}
+	if item, err := k.Get("shared/a"); err != nil || string(item.Data) != "a" {
+		t.Fatalf("The renamed item was not kept: %+v, %v", item, err)
+	}
+}
+
+func TestMigrateVerifyFailure(t *testing.T) {
+	src := NewArrayKeyring([]Item{{Key: "a", Data: []byte("a")}})
+	dst := corruptingKeyring{&ArrayKeyring{}}
+
+	report, err := Migrate(t.Context(), src, dst, MigrateOptions{DeleteSource: true})
+	var migrateErr *MigrateError
+	if !errors.As(err, &migrateErr) || len(migrateErr.Failures) != 1 {
+		t.Fatalf("Expected a MigrateError with one failure, got %v", err)
+	}
+	if r := report.Results[0]; r.Action != MigrateFailed || r.Verified || r.SourceRemoved {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+	if _, err := src.Get("a"); err != nil {
+		t.Fatalf("The source item was removed although verification failed: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// WritePolicy says how a MultiKeyring applies Set and Remove to its backends.
+type WritePolicy int
+
+const (
+	// WriteBestEffort applies the change to every backend, carrying on past
+	// failures. The error lists the backends that failed.
+	WriteBestEffort WritePolicy = iota
+	// WriteAllOrNothing applies the change to the backends in order, and if
+	// one fails, undoes it on those already changed. To be able to undo it,
+	// each backend's item is read before it is changed, which may prompt.
+	WriteAllOrNothing
+)
+
+// MultiOptions configure a MultiKeyring.
+type MultiOptions struct {
+	Write WritePolicy
+}
+
+// MultiKeyring is a Keyring over an ordered list of backends. Get is served
+// by the first backend that has the key, so a fast local backend can be put
+// in front of a slower source of truth. Set and Remove are applied to every
+// backend, according to the WritePolicy.
+type MultiKeyring struct {
+	backends []Keyring
+	policy   WritePolicy
+}
+
+// NewMultiKeyring returns a MultiKeyring over backends, in the order given.
+func NewMultiKeyring(backends []Keyring, opts MultiOptions) *MultiKeyring {
+	return &MultiKeyring{backends: slices.Clone(backends), policy: opts.Write}
+}
+
+// backendError identifies which backend an error came from.
+func backendError(i int, err error) error {
+	return fmt.Errorf("backend %d: %w", i, err)
+}
+
+// Get returns the item from the first backend that has it.
+func (m *MultiKeyring) Get(key string) (Item, error) {
+	return m.GetContext(context.Background(), key)
+}
+
+// GetContext returns the item from the first backend that has it. Backends
+// that fail are skipped; if none has the item, their errors are returned, or
+// ErrKeyNotFound if there were none.
+func (m *MultiKeyring) GetContext(ctx context.Context, key string) (Item, error) {
+	var errs []error
+	for i, k := range m.backends {
+		item, err := AsContextKeyring(k).GetContext(ctx, key)
+		if err == nil {
+			return item, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return Item{}, ctxErr
+		}
+		if !errors.Is(err, ErrKeyNotFound) {
+			errs = append(errs, backendError(i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return Item{}, errors.Join(errs...)
+	}
+	return Item{}, ErrKeyNotFound
+}
+
+// GetMetadata returns the metadata from the first backend that has the item
+// and can return its metadata.
+func (m *MultiKeyring) GetMetadata(key string) (Metadata, error) {
+	return m.GetMetadataContext(context.Background(), key)
+}
+
+func (m *MultiKeyring) GetMetadataContext(ctx context.Context, key string) (Metadata, error) {
+	var errs []error
+	for i, k := range m.backends {
+		md, err := AsContextKeyring(k).GetMetadataContext(ctx, key)
+		if err == nil {
+			return md, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return Metadata{}, ctxErr
+		}
+		if !errors.Is(err, ErrKeyNotFound) {
+			errs = append(errs, backendError(i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return Metadata{}, errors.Join(errs...)
+	}
+	return Metadata{}, ErrKeyNotFound
+}
+
+// Set stores the item in every backend.
+func (m *MultiKeyring) Set(item Item) error {
+	return m.SetContext(context.Background(), item)
+}
+
+func (m *MultiKeyring) SetContext(ctx context.Context, item Item) error {
+	return m.write(ctx, item.Key, func(k ContextKeyring) error {
+		return k.SetContext(ctx, item)
+	})
+}
+
+// Remove removes the item from every backend that has it. It returns
+// ErrKeyNotFound only if none of them did.
+func (m *MultiKeyring) Remove(key string) error {
+	return m.RemoveContext(context.Background(), key)
+}
+
+func (m *MultiKeyring) RemoveContext(ctx context.Context, key string) error {
+	found := false
+	err := m.write(ctx, key, func(k ContextKeyring) error {
+		err := k.RemoveContext(ctx, key)
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil
+		}
+		if err == nil {
+			found = true
+		}
+		return err
+	})
+	if err == nil && !found {
+		return ErrKeyNotFound
+	}
+	return err
+}
+
+// write applies change to the backends according to the write policy.
+func (m *MultiKeyring) write(ctx context.Context, key string, change func(k ContextKeyring) error) error {
+	if m.policy == WriteBestEffort {
+		var errs []error
+		for i, k := range m.backends {
+			if err := change(AsContextKeyring(k)); err != nil {
+				errs = append(errs, backendError(i, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	// Keep what each backend had, so that the change can be undone.
+	var done []multiPrevious
+	for i, k := range m.backends {
+		ck := AsContextKeyring(k)
+		item, err := ck.GetContext(ctx, key)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return errors.Join(backendError(i, err), m.undo(ctx, key, done))
+		}
+		prev := multiPrevious{k: ck, item: item, exists: err == nil}
+
+		if err := change(ck); err != nil {
+			// The failed backend may have been partly changed too.
+			return errors.Join(backendError(i, err), m.undo(ctx, key, append(done, prev)))
+		}
+		done = append(done, prev)
+	}
+	return nil
+}
+
+// multiPrevious is what a backend had for a key before a write.
+type multiPrevious struct {
+	k      ContextKeyring
+	item   Item
+	exists bool
+}
+
+// undo restores what the backends had before a failed write, most recently
+// changed first.
+func (m *MultiKeyring) undo(ctx context.Context, key string, done []multiPrevious) error {
+	var errs []error
+	for _, prev := range slices.Backward(done) {
+		var err error
+		if prev.exists {
+			err = prev.k.SetContext(ctx, prev.item)
+		} else if err = prev.k.RemoveContext(ctx, key); errors.Is(err, ErrKeyNotFound) {
+			err = nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("undoing change: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetMetadata replaces the metadata fields of the item in every backend that
+// has it and can store them.
+func (m *MultiKeyring) SetMetadata(key string, fields map[string]string) error {
+	return m.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (m *MultiKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) error {
+	supported, found := false, false
+	var errs []error
+	for i, k := range m.backends {
+		mk, ok := k.(MetadataKeyring)
+		if !ok {
+			continue
+		}
+		err := mk.SetMetadataContext(ctx, key, fields)
+		switch {
+		case errors.Is(err, ErrMetadataNotSupported):
+			continue
+		case errors.Is(err, ErrKeyNotFound):
+		case err != nil:
+			errs = append(errs, backendError(i, err))
+		default:
+			found = true
+		}
+		supported = true
+	}
+	switch {
+	case len(errs) > 0:
+		return errors.Join(errs...)
+	case !supported:
+		return ErrMetadataNotSupported
+	case !found:
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// FindByAttributesContext returns the keys of the matching items in any of the
+// backends.
+func (m *MultiKeyring) FindByAttributesContext(ctx context.Context, attrs map[string]string) ([]string, error) {
+	var found []string
+	for i, k := range m.backends {
+		keys, err := FindByAttributesContext(ctx, k, attrs)
+		if err != nil {
+			return nil, backendError(i, err)
+		}
+		found = append(found, keys...)
+	}
+	slices.Sort(found)
+	return slices.Compact(found), nil
+}
+
+// Keys returns the keys in any of the backends, each listed once.
+func (m *MultiKeyring) Keys() ([]string, error) {
+	return m.KeysContext(context.Background())
+}
+
+func (m *MultiKeyring) KeysContext(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	keys := []string{}
+	for i, k := range m.backends {
+		backendKeys, err := AsContextKeyring(k).KeysContext(ctx)
+		if err != nil {
+			return nil, backendError(i, err)
+		}
+		for _, key := range backendKeys {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
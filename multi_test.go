@@ -0,0 +1,89 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+var errTestWrite = errors.New("write failed")
+
+// failingKeyring fails every write.
+type failingKeyring struct {
+	*ArrayKeyring
+}
+
+func (k failingKeyring) SetContext(context.Context, Item) error { return errTestWrite }
+
+func (k failingKeyring) RemoveContext(context.Context, string) error { return errTestWrite }
+
+func TestMultiKeyringReadFallback(t *testing.T) {
+	local := NewArrayKeyring([]Item{{Key: "a", Data: []byte("local a")}})
+	remote := NewArrayKeyring([]Item{{Key: "a", Data: []byte("remote a")}, {Key: "b", Data: []byte("remote b")}})
+	m := NewMultiKeyring([]Keyring{local, remote}, MultiOptions{})
+
+	for key, want := range map[string]string{"a": "local a", "b": "remote b"} {
+		item, err := m.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(item.Data) != want {
+			t.Fatalf("Get(%q) = %q, want %q", key, item.Data, want)
+		}
+	}
+	if _, err := m.Get("c"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	keys, err := m.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Fatalf("Keys = %v, want [a b]", keys)
+	}
+
+	if err := m.Set(Item{Key: "c", Data: []byte("c")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("b"); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []Keyring{local, remote} {
+		if _, err := k.Get("c"); err != nil {
+			t.Fatalf("Set was not applied to every backend: %v", err)
+		}
+		if _, err := k.Get("b"); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("Remove was not applied to every backend: %v", err)
+		}
+	}
+}
+
+func TestMultiKeyringWritePolicies(t *testing.T) {
+	local := NewArrayKeyring([]Item{{Key: "a", Data: []byte("old")}})
+	broken := failingKeyring{NewArrayKeyring(nil)}
+
+	m := NewMultiKeyring([]Keyring{local, broken}, MultiOptions{Write: WriteBestEffort})
+	if err := m.Set(Item{Key: "a", Data: []byte("new")}); !errors.Is(err, errTestWrite) {
+		t.Fatalf("Expected the failure to be reported, got %v", err)
+	}
+	if item, _ := local.Get("a"); string(item.Data) != "new" {
+		t.Fatalf("Best effort did not keep the change to the working backend: %q", item.Data)
+	}
+
+	m = NewMultiKeyring([]Keyring{local, broken}, MultiOptions{Write: WriteAllOrNothing})
+	if err := m.Set(Item{Key: "a", Data: []byte("newer")}); !errors.Is(err, errTestWrite) {
+		t.Fatalf("Expected the failure to be reported, got %v", err)
+	}
+	if item, _ := local.Get("a"); string(item.Data) != "new" {
+		t.Fatalf("All or nothing did not undo the change: %q", item.Data)
+	}
+	if err := m.Set(Item{Key: "b", Data: []byte("b")}); !errors.Is(err, errTestWrite) {
+		t.Fatalf("Expected the failure to be reported, got %v", err)
+	}
+	if _, err := local.Get("b"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("All or nothing did not remove a new item: %v", err)
+	}
+}
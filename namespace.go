@@ -0,0 +1,315 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrKeyOutsideNamespace is returned by a NamespacedKeyring for a key that
+// would refer to an item outside its namespace.
+var ErrKeyOutsideNamespace = errors.New("the key is outside the keyring's namespace")
+
+// NamespacedKeyring is a view of the items in another keyring whose keys
+// start with a prefix. Keys are given and returned without the prefix, so
+// several tools can share one keyring without seeing each other's items.
+type NamespacedKeyring struct {
+	inner  ContextKeyring
+	prefix string
+}
+
+// WithNamespace returns a view of the items in inner whose keys start with
+// prefix, such as "team/app/".
+func WithNamespace(inner Keyring, prefix string) *NamespacedKeyring {
+	return &NamespacedKeyring{inner: AsContextKeyring(inner), prefix: prefix}
+}
+
+// Prefix returns the namespace's prefix.
+func (n *NamespacedKeyring) Prefix() string {
+	return n.prefix
+}
+
+// innerKey returns the key in the wrapped keyring for key. Backends such as
+// pass and file store items at paths built from their keys, so keys with
+// empty, "." or ".." path elements, or starting with a slash, are rejected.
+func (n *NamespacedKeyring) innerKey(key string) (string, error) {
+	if key == "" || strings.ContainsRune(key, 0) {
+		return "", fmt.Errorf("%w: %q", ErrKeyOutsideNamespace, key)
+	}
+	for elem := range strings.SplitSeq(strings.ReplaceAll(key, `\`, "/"), "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return "", fmt.Errorf("%w: %q", ErrKeyOutsideNamespace, key)
+		}
+	}
+	return n.prefix + key, nil
+}
+
+// outerKey returns the key in the namespace for a key in the wrapped keyring,
+// or false if it is outside the namespace.
+func (n *NamespacedKeyring) outerKey(key string) (string, bool) {
+	key, ok := strings.CutPrefix(key, n.prefix)
+	return key, ok && key != ""
+}
+
+// Get returns the item for key in the namespace.
+func (n *NamespacedKeyring) Get(key string) (Item, error) {
+	return n.GetContext(context.Background(), key)
+}
+
+func (n *NamespacedKeyring) GetContext(ctx context.Context, key string) (Item, error) {
+	innerKey, err := n.innerKey(key)
+	if err != nil {
+		return Item{}, err
+	}
+	item, err := n.inner.GetContext(ctx, innerKey)
+	if err != nil {
+		return Item{}, err
+	}
+	item.Key = key
+	return item, nil
+}
+
+// GetMetadata returns the metadata of the item for key in the namespace.
+func (n *NamespacedKeyring) GetMetadata(key string) (Metadata, error) {
+	return n.GetMetadataContext(context.Background(), key)
+}
+
+func (n *NamespacedKeyring) GetMetadataContext(ctx context.Context, key string) (Metadata, error) {
+	innerKey, err := n.innerKey(key)
+	if err != nil {
+		return Metadata{}, err
+	}
+	md, err := n.inner.GetMetadataContext(ctx, innerKey)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if md.Item != nil {
+		item := *md.Item
+		item.Key = key
+		md.Item = &item
+	}
+	return md, nil
+}
+
+// Set stores item under its key in the namespace.
+func (n *NamespacedKeyring) Set(item Item) error {
+	return n.SetContext(context.Background(), item)
+}
+
+func (n *NamespacedKeyring) SetContext(ctx context.Context, item Item) error {
+	innerKey, err := n.innerKey(item.Key)
+	if err != nil {
+		return err
+	}
+	item.Key = innerKey
+	return n.inner.SetContext(ctx, item)
+}
+
+// GetVersionedContext returns the item for key in the namespace and its
+// version, if the wrapped keyring supports conditional writes.
+func (n *NamespacedKeyring) GetVersionedContext(ctx context.Context, key string) (Item, string, error) {
+	innerKey, err := n.innerKey(key)
+	if err != nil {
+		return Item{}, "", err
+	}
+	item, version, err := GetVersionedContext(ctx, n.inner, innerKey)
+	if err != nil {
+		return Item{}, "", err
+	}
+	item.Key = key
+	return item, version, nil
+}
+
+func (n *NamespacedKeyring) SetIfAbsentContext(ctx context.Context, item Item) error {
+	innerKey, err := n.innerKey(item.Key)
+	if err != nil {
+		return err
+	}
+	item.Key = innerKey
+	return SetIfAbsentContext(ctx, n.inner, item)
+}
+
+func (n *NamespacedKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) error {
+	innerKey, err := n.innerKey(key)
+	if err != nil {
+		return err
+	}
+	return CompareAndSwapContext(ctx, n.inner, innerKey, expectedVersion, item)
+}
+
+// HistoryContext returns the earlier versions of the item for key in the
+// namespace, if the wrapped keyring keeps them.
+func (n *NamespacedKeyring) HistoryContext(ctx context.Context, key string) ([]Revision, error) {
+	innerKey, err := n.innerKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return HistoryContext(ctx, n.inner, innerKey)
+}
+
+func (n *NamespacedKeyring) RestoreContext(ctx context.Context, key, version string) error {
+	innerKey, err := n.innerKey(key)
+	if err != nil {
+		return err
+	}
+	return RestoreContext(ctx, n.inner, innerKey, version)
+}
+
+// SetMetadata replaces the metadata fields of the item for key in the
+// namespace.
+func (n *NamespacedKeyring) SetMetadata(key string, fields map[string]string) error {
+	return n.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (n *NamespacedKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) error {
+	innerKey, err := n.innerKey(key)
+	if err != nil {
+		return err
+	}
+	mk, ok := n.inner.(MetadataKeyring)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return mk.SetMetadataContext(ctx, innerKey, fields)
+}
+
+// GetManyContext returns the items for keys in the namespace, reading them
+// from the wrapped keyring in one batch if it can.
+func (n *NamespacedKeyring) GetManyContext(ctx context.Context, keys []string) (map[string]Item, error) {
+	innerKeys, outer, errs := n.innerKeys(keys)
+	found, err := GetManyContext(ctx, n.inner, innerKeys)
+	items := make(map[string]Item, len(found))
+	for innerKey, item := range found {
+		item.Key = outer[innerKey]
+		items[item.Key] = item
+	}
+	return items, n.batchError(err, outer, errs)
+}
+
+// SetManyContext stores items in the namespace, in one batch if the wrapped
+// keyring can.
+func (n *NamespacedKeyring) SetManyContext(ctx context.Context, items []Item) error {
+	innerItems := make([]Item, 0, len(items))
+	outer := make(map[string]string, len(items))
+	errs := map[string]error{}
+	for _, item := range items {
+		innerKey, err := n.innerKey(item.Key)
+		if err != nil {
+			errs[item.Key] = err
+			continue
+		}
+		outer[innerKey] = item.Key
+		item.Key = innerKey
+		innerItems = append(innerItems, item)
+	}
+	return n.batchError(SetManyContext(ctx, n.inner, innerItems), outer, errs)
+}
+
+// RemoveManyContext removes the items for keys in the namespace, in one batch
+// if the wrapped keyring can.
+func (n *NamespacedKeyring) RemoveManyContext(ctx context.Context, keys []string) error {
+	innerKeys, outer, errs := n.innerKeys(keys)
+	return n.batchError(RemoveManyContext(ctx, n.inner, innerKeys), outer, errs)
+}
+
+// innerKeys returns the keys in the wrapped keyring for keys, a map from each
+// of them back to its key in the namespace, and the errors for keys that are
+// outside the namespace.
+func (n *NamespacedKeyring) innerKeys(keys []string) ([]string, map[string]string, map[string]error) {
+	innerKeys := make([]string, 0, len(keys))
+	outer := make(map[string]string, len(keys))
+	errs := map[string]error{}
+	for _, key := range keys {
+		innerKey, err := n.innerKey(key)
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+		innerKeys = append(innerKeys, innerKey)
+		outer[innerKey] = key
+	}
+	return innerKeys, outer, errs
+}
+
+// batchError returns err, from a batch on the wrapped keyring, with the keys
+// of a *BatchError mapped back to the namespace and errs added to them.
+func (n *NamespacedKeyring) batchError(err error, outer map[string]string, errs map[string]error) error {
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		for innerKey, keyErr := range batchErr.Errors {
+			errs[outer[innerKey]] = keyErr
+		}
+	} else if err != nil {
+		return err
+	}
+	return batchError(errs)
+}
+
+// Remove removes the item for key in the namespace.
+func (n *NamespacedKeyring) Remove(key string) error {
+	return n.RemoveContext(context.Background(), key)
+}
+
+func (n *NamespacedKeyring) RemoveContext(ctx context.Context, key string) error {
+	innerKey, err := n.innerKey(key)
+	if err != nil {
+		return err
+	}
+	return n.inner.RemoveContext(ctx, innerKey)
+}
+
+// Keys lists the keys in the namespace.
+func (n *NamespacedKeyring) Keys() ([]string, error) {
+	return n.KeysContext(context.Background())
+}
+
+func (n *NamespacedKeyring) KeysContext(ctx context.Context) ([]string, error) {
+	return n.KeysWithPrefixContext(ctx, "")
+}
+
+// KeysWithPrefixContext lists the keys in the namespace starting with prefix.
+// The wrapped keyring filters them natively if it can.
+func (n *NamespacedKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) ([]string, error) {
+	innerKeys, err := KeysWithPrefixContext(ctx, n.inner, n.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	return n.outerKeys(innerKeys), nil
+}
+
+// FindByAttributesContext returns the keys of the matching items in the
+// namespace.
+func (n *NamespacedKeyring) FindByAttributesContext(ctx context.Context, attrs map[string]string) ([]string, error) {
+	innerKeys, err := FindByAttributesContext(ctx, n.inner, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return n.outerKeys(innerKeys), nil
+}
+
+// Watch reports changes to the items in the namespace, watching the wrapped
+// keyring.
+func (n *NamespacedKeyring) Watch(ctx context.Context) (<-chan Event, error) {
+	inner, err := Watch(ctx, n.inner)
+	if err != nil {
+		return nil, err
+	}
+	return mapEvents(ctx, inner, func(ev Event) (Event, bool) {
+		key, ok := n.outerKey(ev.Key)
+		ev.Key = key
+		return ev, ok
+	}), nil
+}
+
+// outerKeys returns the keys in the namespace among keys in the wrapped
+// keyring, without the prefix.
+func (n *NamespacedKeyring) outerKeys(innerKeys []string) []string {
+	keys := []string{}
+	for _, innerKey := range innerKeys {
+		if key, ok := n.outerKey(innerKey); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
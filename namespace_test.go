@@ -0,0 +1,80 @@
+package keyring
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNamespacedKeyring(t *testing.T) {
+	inner := NewArrayKeyring([]Item{
+		{Key: "team/app/a", Data: []byte("a")},
+		{Key: "team/other/b", Data: []byte("b")},
+	})
+	n := WithNamespace(inner, "team/app/")
+
+	item, err := n.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Key != "a" || string(item.Data) != "a" {
+		t.Fatalf("Unexpected item %+v", item)
+	}
+	if _, err := n.Get("b"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound for a key in another namespace, got %v", err)
+	}
+
+	if err := n.Set(Item{Key: "c", Data: []byte("c")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inner.Get("team/app/c"); err != nil {
+		t.Fatalf("Set did not add the prefix: %v", err)
+	}
+
+	keys, err := n.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "c"}) {
+		t.Fatalf("Keys = %v, want [a c]", keys)
+	}
+
+	for _, key := range []string{"", "../other/b", "x/../../other/b", "/a", "a//b", `..\other\b`} {
+		if _, err := n.Get(key); !errors.Is(err, ErrKeyOutsideNamespace) {
+			t.Fatalf("Get(%q): expected ErrKeyOutsideNamespace, got %v", key, err)
+		}
+		if err := n.Set(Item{Key: key}); !errors.Is(err, ErrKeyOutsideNamespace) {
+			t.Fatalf("Set(%q): expected ErrKeyOutsideNamespace, got %v", key, err)
+		}
+	}
+}
+
+func TestNamespacedKeyringBatch(t *testing.T) {
+	inner := &nativeBatchKeyring{ArrayKeyring: NewArrayKeyring([]Item{{Key: "team/other/b", Data: []byte("b")}})}
+	n := WithNamespace(inner, "team/app/")
+
+	err := n.SetManyContext(t.Context(), []Item{{Key: "a", Data: []byte("a")}, {Key: "../other/b", Data: []byte("x")}})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors["../other/b"], ErrKeyOutsideNamespace) {
+		t.Fatalf("Expected ErrKeyOutsideNamespace for the escaping key only, got %v", err)
+	}
+	if item, err := inner.Get("team/other/b"); err != nil || string(item.Data) != "b" {
+		t.Fatalf("The item outside the namespace changed: %q, %v", item.Data, err)
+	}
+
+	items, err := n.GetManyContext(t.Context(), []string{"a", "b"})
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors["b"], ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound for b only, got %v", err)
+	}
+	if len(items) != 1 || items["a"].Key != "a" || string(items["a"].Data) != "a" {
+		t.Fatalf("Unexpected items %+v", items)
+	}
+
+	if err := n.RemoveManyContext(t.Context(), []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if inner.batches != 3 {
+		t.Fatalf("Expected 3 batches to reach the wrapped keyring, got %d", inner.batches)
+	}
+}
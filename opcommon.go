@@ -6,9 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 
+	connectop "github.com/1Password/connect-sdk-go/onepassword"
 	onepassword "github.com/1password/onepassword-sdk-go"
 )
 
@@ -20,6 +25,8 @@ const (
 	OPItemTag               = "keyring"
 	OPItemTitlePrefix       = "keyring"
 	OPItemTitlePrefixKeySep = ": "
+	OPItemMetadataSection   = "keyring-metadata"
+	OPItemAttributesSection = "keyring-attributes"
 )
 
 // Errors shared by the 1Password backends.
@@ -57,6 +64,8 @@ type OPBaseKeyring struct {
 	ItemFieldTitle  string
 	TokenEnvs       []string
 	TokenFunc       PromptFunc
+
+	log callLogger
 }
 
 // GetItemFromOPItemFieldValue unmarshals a 1Password item field value into an Item.
@@ -80,11 +89,108 @@ func (k *OPBaseKeyring) GetOPItemFieldValueFromItem(item *Item) (string, error)
 	return string(opItemFieldValueBytes), nil
 }
 
+// opItemKeys returns the keys of opItems, which must all have different
+// titles.
+func (k *OPBaseKeyring) opItemKeys(opItems []onepassword.Item) ([]string, error) {
+	opItemTitles := []string{}
+	keys := []string{}
+	for _, opItem := range opItems {
+		if !slices.Contains(opItemTitles, opItem.Title) {
+			opItemTitles = append(opItemTitles, opItem.Title)
+			keys = append(keys, k.GetKeyFromOPItemTitle(opItem.Title))
+		}
+	}
+
+	if len(opItemTitles) != len(opItems) {
+		return nil, fmt.Errorf(
+			"%w in vault with ID %#v: %#v",
+			OPErrItemTitleDuplicate,
+			k.VaultID,
+			opItemTitles,
+		)
+	}
+
+	return keys, nil
+}
+
 // GetOPItemTitleFromKey derives the 1Password item title from a keyring key.
 func (k *OPBaseKeyring) GetOPItemTitleFromKey(key string) string {
 	return k.ItemTitlePrefix + OPItemTitlePrefixKeySep + key
 }
 
+// GetMetadataFromOPItem returns the metadata of a keyring item: the
+// non-secret parts of the Item in its concealed field, the item's timestamps
+// and the text fields in the OPItemMetadataSection section.
+func (k *OPBaseKeyring) GetMetadataFromOPItem(opItem *onepassword.Item) (Metadata, error) {
+	item, err := k.GetItemFromOPItemFieldValue(opItem.Fields[0].Value)
+	if err != nil {
+		return Metadata{}, err
+	}
+	item.Data = nil
+
+	return Metadata{
+		Item:             item,
+		ModificationTime: opItem.UpdatedAt,
+		CreationTime:     opItem.CreatedAt,
+		Fields:           opSectionValues(opItem, OPItemMetadataSection),
+	}, nil
+}
+
+// isOPSectionField reports whether a 1Password item field is one the keyring
+// keeps in a section of its own: a metadata field or an attribute.
+func isOPSectionField(opItemField onepassword.ItemField) bool {
+	if opItemField.SectionID == nil {
+		return false
+	}
+	return *opItemField.SectionID == OPItemMetadataSection || *opItemField.SectionID == OPItemAttributesSection
+}
+
+// opSectionValues returns the values of the fields of opItem in section, by
+// title, or nil if there are none.
+func opSectionValues(opItem *onepassword.Item, section string) map[string]string {
+	var values map[string]string
+	for _, opItemField := range opItem.Fields {
+		if opItemField.SectionID != nil && *opItemField.SectionID == section {
+			if values == nil {
+				values = map[string]string{}
+			}
+			values[opItemField.Title] = opItemField.Value
+		}
+	}
+	return values
+}
+
+// opSectionFields returns the 1Password text fields holding values in
+// section, in order of name.
+func opSectionFields(section string, values map[string]string) []onepassword.ItemField {
+	opItemFields := []onepassword.ItemField{}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		opItemFields = append(opItemFields, onepassword.ItemField{
+			ID:        section + "." + name,
+			Title:     name,
+			SectionID: &section,
+			FieldType: onepassword.ItemFieldTypeText,
+			Value:     values[name],
+		})
+	}
+	return opItemFields
+}
+
+// setOPItemSections replaces the metadata fields and attributes of a keyring
+// item, which follow its concealed field, and adds the sections they need.
+func setOPItemSections(opItem *onepassword.Item, fields, attrs map[string]string) {
+	opItem.Fields = append(opItem.Fields[:1], opSectionFields(OPItemMetadataSection, fields)...)
+	opItem.Fields = append(opItem.Fields, opSectionFields(OPItemAttributesSection, attrs)...)
+
+	for _, section := range []string{OPItemMetadataSection, OPItemAttributesSection} {
+		if len(opSectionValues(opItem, section)) == 0 ||
+			slices.ContainsFunc(opItem.Sections, func(s onepassword.ItemSection) bool { return s.ID == section }) {
+			continue
+		}
+		opItem.Sections = append(opItem.Sections, onepassword.ItemSection{ID: section})
+	}
+}
+
 // GetOPToken returns the 1Password token from the configured environment
 // variables, falling back to prompting via TokenFunc.
 func (k *OPBaseKeyring) GetOPToken(prompt string) (string, error) {
@@ -99,3 +205,52 @@ func (k *OPBaseKeyring) GetOPToken(prompt string) (string, error) {
 	}
 	return "", OPErrTokenFuncNil
 }
+
+// probeOPToken checks that GetOPToken has somewhere to get a token from,
+// without calling TokenFunc, which may prompt.
+func (k *OPBaseKeyring) probeOPToken() error {
+	for _, tokenEnv := range k.TokenEnvs {
+		if os.Getenv(tokenEnv) != "" {
+			return nil
+		}
+	}
+	if k.TokenFunc != nil {
+		return nil
+	}
+	return OPErrTokenFuncNil
+}
+
+// opError classifies an error returned by a 1Password SDK or Connect client so
+// that it matches the package's sentinel errors.
+func opError(err error) error {
+	var (
+		rateLimitErr      *onepassword.RateLimitExceededError
+		sessionExpiredErr *onepassword.DesktopSessionExpiredError
+		connectErr        *connectop.Error
+	)
+	switch {
+	case errors.As(err, &rateLimitErr):
+		return classify(ErrRateLimited, err)
+	case errors.As(err, &sessionExpiredErr):
+		// The desktop app locked or the user revoked the integration.
+		return classify(ErrLocked, err)
+	case errors.As(err, &connectErr):
+		switch {
+		case connectErr.StatusCode == http.StatusUnauthorized, connectErr.StatusCode == http.StatusForbidden:
+			return classify(ErrAccessDenied, err)
+		case connectErr.StatusCode == http.StatusTooManyRequests:
+			return classify(ErrRateLimited, err)
+		case connectErr.StatusCode == http.StatusConflict:
+			return classify(ErrConflict, err)
+		case connectErr.StatusCode >= http.StatusInternalServerError:
+			return classify(ErrBackendUnavailable, err)
+		}
+	}
+	return err
+}
+
+// opItemVersion returns the version of a 1Password item as ConditionalKeyring
+// reports it.
+func opItemVersion(opItem *onepassword.Item) string {
+	return strconv.FormatUint(uint64(opItem.Version), 10)
+}
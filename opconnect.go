@@ -3,10 +3,12 @@
 package keyring
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/1Password/connect-sdk-go/connect"
@@ -34,6 +36,14 @@ var (
 )
 
 func init() {
+	backendCapabilities[OPConnectBackend] = Capabilities{
+		Metadata:           MetadataSupported,
+		Label:              true,
+		Description:        true,
+		ListRequiresUnlock: true,
+		Remote:             true,
+	}
+
 	supportedBackends[OPConnectBackend] = opener(func(cfg Config) (Keyring, error) {
 		keyring, err := NewOPConnectKeyring(&cfg)
 		if err != nil {
@@ -41,6 +51,13 @@ func init() {
 		}
 		return *keyring, nil
 	})
+	backendProbes[OPConnectBackend] = func(_ context.Context, cfg Config) error {
+		keyring, err := NewOPConnectKeyring(&cfg)
+		if err != nil {
+			return err
+		}
+		return keyring.probeOPToken()
+	}
 }
 
 // OPConnectKeyring implements the Keyring interface for 1Password Connect.
@@ -97,6 +114,7 @@ func NewOPConnectKeyring(cfg *Config) (*OPConnectKeyring, error) {
 			ItemFieldTitle:  itemFieldTitle,
 			TokenEnvs:       []string{cfg.OPConnectTokenEnv, OPConnectEnvToken},
 			TokenFunc:       cfg.OPTokenFunc,
+			log:             newCallLogger(*cfg, OPConnectBackend),
 		},
 		Host: host,
 	}
@@ -122,6 +140,17 @@ func (k *OPConnectKeyring) InitializeOPConnectClient() error {
 
 // GetOPItem returns the 1Password item matching the given key.
 func (k *OPConnectKeyring) GetOPItem(key string) (*onepassword.Item, error) {
+	return k.GetOPItemContext(context.Background(), key)
+}
+
+// GetOPItemContext returns the 1Password item matching the given key. The
+// Connect SDK does not accept a context, so cancellation is observed between
+// HTTP requests rather than during one.
+func (k *OPConnectKeyring) GetOPItemContext(ctx context.Context, key string) (*onepassword.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	opItemTitle := k.GetOPItemTitleFromKey(key)
 
 	opConnectItemOverviews, err := k.Client.GetItemsByTitle(opItemTitle, k.VaultID)
@@ -130,11 +159,11 @@ func (k *OPConnectKeyring) GetOPItem(key string) (*onepassword.Item, error) {
 			"unable to get item overviews with title %#v from vault with ID %#v: %w",
 			opItemTitle,
 			k.VaultID,
-			err,
+			opError(err),
 		)
 	}
 
-	opItems, err := k.PruneAndHydrateOPItemOverviews(opConnectItemOverviews)
+	opItems, err := k.pruneAndHydrateOPItemOverviews(ctx, opConnectItemOverviews)
 	if err != nil {
 		return nil, err
 	}
@@ -159,9 +188,20 @@ func (k *OPConnectKeyring) GetOPItem(key string) (*onepassword.Item, error) {
 // and fetches their full contents.
 func (k *OPConnectKeyring) PruneAndHydrateOPItemOverviews(
 	opConnectItemOverviews []connectop.Item,
+) ([]onepassword.Item, error) {
+	return k.pruneAndHydrateOPItemOverviews(context.Background(), opConnectItemOverviews)
+}
+
+func (k *OPConnectKeyring) pruneAndHydrateOPItemOverviews(
+	ctx context.Context,
+	opConnectItemOverviews []connectop.Item,
 ) ([]onepassword.Item, error) {
 	opItems := []onepassword.Item{}
 	for _, opConnectItemOverview := range opConnectItemOverviews {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		if !slices.Contains(opConnectItemOverview.Tags, k.ItemTag) ||
 			opConnectItemOverview.Category != OPConnectItemCategory {
 			continue
@@ -173,19 +213,26 @@ func (k *OPConnectKeyring) PruneAndHydrateOPItemOverviews(
 				"unable to get item with ID %#v from vault with ID %#v: %w",
 				opConnectItemOverview.ID,
 				k.VaultID,
-				err,
+				opError(err),
 			)
 		}
 
 		opItemFields := []onepassword.ItemField{}
+		opItemMetadataFields := map[string]string{}
+		opItemAttributes := map[string]string{}
 		for _, opConnectItemField := range opConnectItem.Fields {
-			if opConnectItemField.Type == OPConnectItemFieldType {
+			switch {
+			case opConnectItemField.Type == OPConnectItemFieldType:
 				opItemFields = append(opItemFields, onepassword.ItemField{
 					ID:        opConnectItemField.ID,
 					Title:     opConnectItemField.Label,
 					FieldType: OPStandardItemFieldType,
 					Value:     opConnectItemField.Value,
 				})
+			case opConnectItemField.Section != nil && opConnectItemField.Section.ID == OPItemMetadataSection:
+				opItemMetadataFields[opConnectItemField.Label] = opConnectItemField.Value
+			case opConnectItemField.Section != nil && opConnectItemField.Section.ID == OPItemAttributesSection:
+				opItemAttributes[opConnectItemField.Label] = opConnectItemField.Value
 			}
 		}
 
@@ -193,15 +240,19 @@ func (k *OPConnectKeyring) PruneAndHydrateOPItemOverviews(
 			continue
 		}
 
-		opItems = append(opItems, onepassword.Item{
+		opItem := onepassword.Item{
 			ID:        opConnectItem.ID,
 			Title:     opConnectItem.Title,
 			Category:  OPStandardItemCategory,
 			VaultID:   opConnectItem.Vault.ID,
 			Fields:    opItemFields,
 			Tags:      opConnectItem.Tags,
+			CreatedAt: opConnectItem.CreatedAt,
 			UpdatedAt: opConnectItem.UpdatedAt,
-		})
+			Version:   uint32(opConnectItem.Version),
+		}
+		setOPItemSections(&opItem, opItemMetadataFields, opItemAttributes)
+		opItems = append(opItems, opItem)
 	}
 
 	return opItems, nil
@@ -209,11 +260,17 @@ func (k *OPConnectKeyring) PruneAndHydrateOPItemOverviews(
 
 // Get returns the Item matching the given key, or ErrKeyNotFound.
 func (k OPConnectKeyring) Get(key string) (Item, error) {
+	return k.GetContext(context.Background(), key)
+}
+
+// GetContext returns the Item matching the given key, or ErrKeyNotFound.
+func (k OPConnectKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
 	if err := k.InitializeOPConnectClient(); err != nil {
 		return Item{}, err
 	}
 
-	opItem, err := k.GetOPItem(key)
+	opItem, err := k.GetOPItemContext(ctx, key)
 	if err != nil {
 		return Item{}, err
 	}
@@ -221,25 +278,130 @@ func (k OPConnectKeyring) Get(key string) (Item, error) {
 	if err != nil {
 		return Item{}, err
 	}
-	return *item, nil
+	return unexpired(*item)
 }
 
 // GetMetadata returns the non-secret parts of an Item.
-func (k OPConnectKeyring) GetMetadata(_ string) (Metadata, error) {
-	return Metadata{}, nil
+func (k OPConnectKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+// GetMetadataContext returns the non-secret parts of an Item.
+func (k OPConnectKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := k.InitializeOPConnectClient(); err != nil {
+		return Metadata{}, err
+	}
+
+	opItem, err := k.GetOPItemContext(ctx, key)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return k.GetMetadataFromOPItem(opItem)
+}
+
+// SetMetadata replaces the metadata fields of an Item.
+func (k OPConnectKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+// SetMetadataContext replaces the metadata fields of an Item. They are kept
+// as text fields in their own section of the 1Password item.
+func (k OPConnectKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if err := k.InitializeOPConnectClient(); err != nil {
+		return err
+	}
+
+	opItem, err := k.GetOPItemContext(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	setOPItemSections(opItem, fields, opSectionValues(opItem, OPItemAttributesSection))
+	if _, err := k.Client.UpdateItem(k.connectItem(opItem), k.VaultID); err != nil {
+		return fmt.Errorf(
+			"unable to update item with title %#v in vault with ID %#v: %w",
+			opItem.Title,
+			k.VaultID,
+			opError(err),
+		)
+	}
+	return nil
+}
+
+// connectItem converts a keyring item, as returned by GetOPItem, back into a
+// 1Password Connect item.
+func (k OPConnectKeyring) connectItem(opItem *onepassword.Item) *connectop.Item {
+	opConnectItem := &connectop.Item{
+		ID:       opItem.ID,
+		Title:    opItem.Title,
+		Tags:     []string{k.ItemTag},
+		Vault:    connectop.ItemVault{ID: k.VaultID},
+		Category: OPConnectItemCategory,
+		Fields: []*connectop.ItemField{{
+			ID:    opItem.Fields[0].ID,
+			Type:  OPConnectItemFieldType,
+			Label: k.ItemFieldTitle,
+			Value: opItem.Fields[0].Value,
+		}},
+		UpdatedAt: time.Now(),
+		Version:   int(opItem.Version),
+	}
+
+	opConnectItem.Fields, opConnectItem.Sections = connectSectionFields(opConnectItem.Fields, opItem.Fields[1:])
+	return opConnectItem
+}
+
+// connectSectionFields appends the metadata fields and attributes of a keyring
+// item to a 1Password Connect item's fields, and returns the sections they are
+// in.
+func connectSectionFields(
+	opConnectItemFields []*connectop.ItemField,
+	opItemFields []onepassword.ItemField,
+) ([]*connectop.ItemField, []*connectop.ItemSection) {
+	var sections []*connectop.ItemSection
+	for _, opItemField := range opItemFields {
+		i := slices.IndexFunc(sections, func(s *connectop.ItemSection) bool { return s.ID == *opItemField.SectionID })
+		if i < 0 {
+			i = len(sections)
+			sections = append(sections, &connectop.ItemSection{ID: *opItemField.SectionID})
+		}
+		opConnectItemFields = append(opConnectItemFields, &connectop.ItemField{
+			ID:      opItemField.ID,
+			Section: sections[i],
+			Type:    connectop.FieldTypeString,
+			Label:   opItemField.Title,
+			Value:   opItemField.Value,
+		})
+	}
+	return opConnectItemFields, sections
 }
 
 // Set creates or updates an Item.
 func (k OPConnectKeyring) Set(item Item) error {
+	return k.SetContext(context.Background(), item)
+}
+
+// SetContext creates or updates an Item.
+func (k OPConnectKeyring) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
 	if err := k.InitializeOPConnectClient(); err != nil {
 		return err
 	}
 
-	opItem, err := k.GetOPItem(item.Key)
+	opItem, err := k.GetOPItemContext(ctx, item.Key)
 	if err != nil && !errors.Is(err, ErrKeyNotFound) {
 		return err
 	}
+	return k.setOPItem(ctx, opItem, item)
+}
 
+// setOPItem updates opItem with item, or creates it if opItem is nil.
+func (k OPConnectKeyring) setOPItem(ctx context.Context, opItem *onepassword.Item, item Item) error {
 	opItemTitle := k.GetOPItemTitleFromKey(item.Key)
 	opItemFieldValue, err := k.GetOPItemFieldValueFromItem(&item)
 	if err != nil {
@@ -258,6 +420,17 @@ func (k OPConnectKeyring) Set(item Item) error {
 		}},
 	}
 
+	if len(item.Attributes) > 0 {
+		opConnectItem.Fields, opConnectItem.Sections = connectSectionFields(
+			opConnectItem.Fields,
+			opSectionFields(OPItemAttributesSection, item.Attributes),
+		)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if opItem == nil {
 		_, err := k.Client.CreateItem(opConnectItem, k.VaultID)
 		if err != nil {
@@ -265,45 +438,113 @@ func (k OPConnectKeyring) Set(item Item) error {
 				"unable to create item with title %#v in vault with ID %#v: %w",
 				opItemTitle,
 				k.VaultID,
-				err,
+				opError(err),
 			)
 		}
 		return nil
 	}
 
-	opConnectItem.ID = opItem.ID
-	opConnectItem.Fields[0].ID = opItem.Fields[0].ID
-	opConnectItem.UpdatedAt = time.Now()
-
-	_, err = k.Client.UpdateItem(opConnectItem, k.VaultID)
+	// Carry the item's metadata fields over to the update.
+	opItem.Fields[0].Value = opItemFieldValue
+	setOPItemSections(opItem, opSectionValues(opItem, OPItemMetadataSection), item.Attributes)
+	_, err = k.Client.UpdateItem(k.connectItem(opItem), k.VaultID)
 	if err != nil {
 		return fmt.Errorf(
 			"unable to update item with title %#v in vault with ID %#v: %w",
 			opItemTitle,
 			k.VaultID,
-			err,
+			opError(err),
 		)
 	}
 	return nil
 }
 
+// GetVersionedContext returns the Item matching key and the version of its
+// 1Password item.
+func (k OPConnectKeyring) GetVersionedContext(ctx context.Context, key string) (_ Item, _ string, err error) {
+	defer k.log.op(ctx, OpGetVersioned, key)(&err)
+	if err := k.InitializeOPConnectClient(); err != nil {
+		return Item{}, "", err
+	}
+
+	opItem, err := k.GetOPItemContext(ctx, key)
+	if err != nil {
+		return Item{}, "", err
+	}
+	item, err := k.GetItemFromOPItemFieldValue(opItem.Fields[0].Value)
+	if err != nil {
+		return Item{}, "", err
+	}
+	out, err := unexpired(*item)
+	return out, opItemVersion(opItem), err
+}
+
+// SetIfAbsentContext creates an item for item.Key unless the vault already
+// holds one. 1Password does not keep titles unique, so two writers creating
+// the same key at the same moment can both succeed.
+func (k OPConnectKeyring) SetIfAbsentContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSetIfAbsent, item.Key)(&err)
+	if err := k.InitializeOPConnectClient(); err != nil {
+		return err
+	}
+
+	_, err = k.GetOPItemContext(ctx, item.Key)
+	if err == nil {
+		return ErrConflict
+	} else if !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	return k.setOPItem(ctx, nil, item)
+}
+
+// CompareAndSwapContext updates the item for key if its 1Password item is
+// still at expectedVersion. The update carries that version, and Connect
+// rejects it with a conflict if another writer got in first.
+func (k OPConnectKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) (err error) {
+	defer k.log.op(ctx, OpCompareAndSwap, key)(&err)
+	if err := k.InitializeOPConnectClient(); err != nil {
+		return err
+	}
+
+	opItem, err := k.GetOPItemContext(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return ErrConflict
+	} else if err != nil {
+		return err
+	}
+	if opItemVersion(opItem) != expectedVersion {
+		return ErrConflict
+	}
+	item.Key = key
+	return k.setOPItem(ctx, opItem, item)
+}
+
 // Remove deletes the item with the matching key.
 func (k OPConnectKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext deletes the item with the matching key.
+func (k OPConnectKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
 	if err := k.InitializeOPConnectClient(); err != nil {
 		return err
 	}
 
-	opItem, err := k.GetOPItem(key)
+	opItem, err := k.GetOPItemContext(ctx, key)
 	if err != nil {
 		return err
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	if err := k.Client.DeleteItemByID(opItem.ID, k.VaultID); err != nil {
 		return fmt.Errorf(
 			"unable to delete item with ID %#v in vault with ID %#v: %w",
 			opItem.ID,
 			k.VaultID,
-			err,
+			opError(err),
 		)
 	}
 	return nil
@@ -311,16 +552,36 @@ func (k OPConnectKeyring) Remove(key string) error {
 
 // GetOPItems returns all keyring items from the configured vault.
 func (k *OPConnectKeyring) GetOPItems() ([]onepassword.Item, error) {
+	return k.GetOPItemsContext(context.Background())
+}
+
+// GetOPItemsContext returns all keyring items from the configured vault.
+func (k *OPConnectKeyring) GetOPItemsContext(ctx context.Context) ([]onepassword.Item, error) {
+	return k.getOPItemsWithTitlePrefix(ctx, "")
+}
+
+// getOPItemsWithTitlePrefix returns the keyring items from the configured
+// vault whose titles start with titlePrefix. Connect can only filter titles
+// exactly, so the overviews are filtered here, before any item is fetched.
+func (k *OPConnectKeyring) getOPItemsWithTitlePrefix(ctx context.Context, titlePrefix string) ([]onepassword.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	opItemOverviews, err := k.Client.GetItems(k.VaultID)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"unable to get item overviews from vault with ID %#v: %w",
 			k.VaultID,
-			err,
+			opError(err),
 		)
 	}
 
-	opItems, err := k.PruneAndHydrateOPItemOverviews(opItemOverviews)
+	opItemOverviews = slices.DeleteFunc(opItemOverviews, func(opItemOverview connectop.Item) bool {
+		return !strings.HasPrefix(opItemOverview.Title, titlePrefix)
+	})
+
+	opItems, err := k.pruneAndHydrateOPItemOverviews(ctx, opItemOverviews)
 	if err != nil {
 		return nil, err
 	}
@@ -330,34 +591,36 @@ func (k *OPConnectKeyring) GetOPItems() ([]onepassword.Item, error) {
 
 // Keys returns a slice of all keys stored on the keyring.
 func (k OPConnectKeyring) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+// KeysContext returns a slice of all keys stored on the keyring.
+func (k OPConnectKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
 	if err := k.InitializeOPConnectClient(); err != nil {
 		return nil, err
 	}
 
-	opItems, err := k.GetOPItems()
+	opItems, err := k.GetOPItemsContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return k.opItemKeys(opItems)
+}
 
-	opItemTitles := []string{}
-	keys := []string{}
-	for _, opItem := range opItems {
-		if !slices.Contains(opItemTitles, opItem.Title) {
-			opItemTitles = append(opItemTitles, opItem.Title)
-			keys = append(keys, k.GetKeyFromOPItemTitle(opItem.Title))
-		}
+// KeysWithPrefixContext returns the keys starting with prefix, fetching only
+// the items whose titles match.
+func (k OPConnectKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeysWithPrefix, "")(&err)
+	if err := k.InitializeOPConnectClient(); err != nil {
+		return nil, err
 	}
 
-	if len(opItemTitles) != len(opItems) {
-		return nil, fmt.Errorf(
-			"%w in vault with ID %#v: %#v",
-			OPErrItemTitleDuplicate,
-			k.VaultID,
-			opItemTitles,
-		)
+	opItems, err := k.getOPItemsWithTitlePrefix(ctx, k.GetOPItemTitleFromKey(prefix))
+	if err != nil {
+		return nil, err
 	}
-
-	return keys, nil
+	return k.opItemKeys(opItems)
 }
 
 // OPConnectClientAPI is the subset of the 1Password Connect client used by this backend.
@@ -39,6 +39,15 @@ var (
 )
 
 func init() {
+	backendCapabilities[OPDesktopBackend] = Capabilities{
+		Metadata:           MetadataSupported,
+		Label:              true,
+		Description:        true,
+		ListRequiresUnlock: true,
+		Interactive:        true, // the desktop app asks the user to approve access
+		Remote:             true,
+	}
+
 	supportedBackends[OPDesktopBackend] = opener(func(cfg Config) (Keyring, error) {
 		keyring, err := NewOPDesktopKeyring(&cfg)
 		if err != nil {
@@ -110,6 +119,7 @@ func NewOPDesktopKeyring(cfg *Config) (*OPDesktopKeyring, error) {
 				ItemFieldTitle:  itemFieldTitle,
 				TokenEnvs:       []string{},
 				TokenFunc:       cfg.OPTokenFunc,
+				log:             newCallLogger(*cfg, OPDesktopBackend),
 			},
 			Timeout: timeout,
 		},
@@ -121,18 +131,23 @@ func NewOPDesktopKeyring(cfg *Config) (*OPDesktopKeyring, error) {
 
 // InitializeClient initializes the Desktop Integration client
 func (k *OPDesktopKeyring) InitializeClient() error {
+	return k.InitializeClientContext(context.Background())
+}
+
+// InitializeClientContext initializes the Desktop Integration client
+func (k *OPDesktopKeyring) InitializeClientContext(ctx context.Context) error {
 	if k.Client != nil {
 		return nil
 	}
 
 	client, err := onepassword.NewClient(
-		context.Background(),
+		ctx,
 		// account name or account UUID for Desktop Integration
 		onepassword.WithDesktopAppIntegration(k.DesktopAccountID),
 		onepassword.WithIntegrationInfo(OPStandardIntegrationName, OPStandardIntegrationVersion),
 	)
 	if err != nil {
-		return fmt.Errorf("%w: %w", OPDesktopErrNewClient, err)
+		return fmt.Errorf("%w: %w", OPDesktopErrNewClient, opError(err))
 	}
 
 	// The full client must be retained to prevent garbage collection
@@ -145,38 +160,134 @@ func (k *OPDesktopKeyring) InitializeClient() error {
 
 // Get retrieves an item by key
 func (k *OPDesktopKeyring) Get(key string) (Item, error) {
-	if err := k.InitializeClient(); err != nil {
+	return k.GetContext(context.Background(), key)
+}
+
+// GetContext retrieves an item by key
+func (k *OPDesktopKeyring) GetContext(ctx context.Context, key string) (Item, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
 		return Item{}, err
 	}
-	return k.OPStandardKeyring.Get(key)
+	return k.OPStandardKeyring.GetContext(ctx, key)
 }
 
 // GetMetadata returns metadata for a key
 func (k *OPDesktopKeyring) GetMetadata(key string) (Metadata, error) {
-	return k.OPStandardKeyring.GetMetadata(key)
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+// GetMetadataContext returns metadata for a key
+func (k *OPDesktopKeyring) GetMetadataContext(ctx context.Context, key string) (Metadata, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return Metadata{}, err
+	}
+	return k.OPStandardKeyring.GetMetadataContext(ctx, key)
+}
+
+// SetMetadata replaces the metadata fields of an item
+func (k *OPDesktopKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+// SetMetadataContext replaces the metadata fields of an item
+func (k *OPDesktopKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.SetMetadataContext(ctx, key, fields)
 }
 
 // Set creates or updates an item
 func (k *OPDesktopKeyring) Set(item Item) error {
-	if err := k.InitializeClient(); err != nil {
+	return k.SetContext(context.Background(), item)
+}
+
+// SetContext creates or updates an item
+func (k *OPDesktopKeyring) SetContext(ctx context.Context, item Item) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
 		return err
 	}
-	return k.OPStandardKeyring.Set(item)
+	return k.OPStandardKeyring.SetContext(ctx, item)
 }
 
 // Remove deletes an item by key
 func (k *OPDesktopKeyring) Remove(key string) error {
-	if err := k.InitializeClient(); err != nil {
+	return k.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext deletes an item by key
+func (k *OPDesktopKeyring) RemoveContext(ctx context.Context, key string) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
 		return err
 	}
-	return k.OPStandardKeyring.Remove(key)
+	return k.OPStandardKeyring.RemoveContext(ctx, key)
 }
 
 // Keys returns all keys in the keyring
 func (k *OPDesktopKeyring) Keys() ([]string, error) {
-	if err := k.InitializeClient(); err != nil {
+	return k.KeysContext(context.Background())
+}
+
+// KeysContext returns all keys in the keyring
+func (k *OPDesktopKeyring) KeysContext(ctx context.Context) ([]string, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
 		return nil, err
 	}
+	return k.OPStandardKeyring.KeysContext(ctx)
+}
 
-	return k.OPStandardKeyring.Keys()
+// GetManyContext retrieves several items by key
+func (k *OPDesktopKeyring) GetManyContext(ctx context.Context, keys []string) (map[string]Item, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return nil, err
+	}
+	return k.OPStandardKeyring.GetManyContext(ctx, keys)
+}
+
+// SetManyContext creates or updates several items
+func (k *OPDesktopKeyring) SetManyContext(ctx context.Context, items []Item) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.SetManyContext(ctx, items)
+}
+
+// RemoveManyContext deletes several items by key
+func (k *OPDesktopKeyring) RemoveManyContext(ctx context.Context, keys []string) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.RemoveManyContext(ctx, keys)
+}
+
+// KeysWithPrefixContext returns the keys starting with prefix
+func (k *OPDesktopKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) ([]string, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return nil, err
+	}
+	return k.OPStandardKeyring.KeysWithPrefixContext(ctx, prefix)
+}
+
+// GetVersionedContext retrieves an item by key along with its version
+func (k *OPDesktopKeyring) GetVersionedContext(ctx context.Context, key string) (Item, string, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return Item{}, "", err
+	}
+	return k.OPStandardKeyring.GetVersionedContext(ctx, key)
+}
+
+// SetIfAbsentContext creates an item unless its key is taken
+func (k *OPDesktopKeyring) SetIfAbsentContext(ctx context.Context, item Item) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.SetIfAbsentContext(ctx, item)
+}
+
+// CompareAndSwapContext updates an item if it is still at expectedVersion
+func (k *OPDesktopKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.CompareAndSwapContext(ctx, key, expectedVersion, item)
 }
@@ -32,6 +32,14 @@ var (
 )
 
 func init() {
+	backendCapabilities[OPBackend] = Capabilities{
+		Metadata:           MetadataSupported,
+		Label:              true,
+		Description:        true,
+		ListRequiresUnlock: true,
+		Remote:             true,
+	}
+
 	supportedBackends[OPBackend] = opener(func(cfg Config) (Keyring, error) {
 		keyring, err := NewOPSrvAccountKeyring(&cfg)
 		if err != nil {
@@ -39,6 +47,13 @@ func init() {
 		}
 		return keyring, nil
 	})
+	backendProbes[OPBackend] = func(_ context.Context, cfg Config) error {
+		keyring, err := NewOPSrvAccountKeyring(&cfg)
+		if err != nil {
+			return err
+		}
+		return keyring.probeOPToken()
+	}
 }
 
 // OPSrvAccountKeyring implements Keyring interface for 1Password Service Accounts
@@ -94,6 +109,7 @@ func NewOPSrvAccountKeyring(cfg *Config) (*OPSrvAccountKeyring, error) {
 				ItemFieldTitle:  itemFieldTitle,
 				TokenEnvs:       []string{cfg.OPTokenEnv, OPSrvAccountEnvToken},
 				TokenFunc:       cfg.OPTokenFunc,
+				log:             newCallLogger(*cfg, OPBackend),
 			},
 			Timeout: timeout,
 		},
@@ -104,6 +120,11 @@ func NewOPSrvAccountKeyring(cfg *Config) (*OPSrvAccountKeyring, error) {
 
 // InitializeClient initializes the Service Account client
 func (k *OPSrvAccountKeyring) InitializeClient() error {
+	return k.InitializeClientContext(context.Background())
+}
+
+// InitializeClientContext initializes the Service Account client
+func (k *OPSrvAccountKeyring) InitializeClientContext(ctx context.Context) error {
 	if k.Client != nil {
 		return nil
 	}
@@ -114,12 +135,12 @@ func (k *OPSrvAccountKeyring) InitializeClient() error {
 	}
 
 	client, err := onepassword.NewClient(
-		context.Background(),
+		ctx,
 		onepassword.WithIntegrationInfo(OPStandardIntegrationName, OPStandardIntegrationVersion),
 		onepassword.WithServiceAccountToken(token),
 	)
 	if err != nil {
-		return fmt.Errorf("%w: %w", OPSrvAccountErrNewClient, err)
+		return fmt.Errorf("%w: %w", OPSrvAccountErrNewClient, opError(err))
 	}
 
 	// The full client must be retained to prevent garbage collection
@@ -132,37 +153,134 @@ func (k *OPSrvAccountKeyring) InitializeClient() error {
 
 // Get retrieves an item by key
 func (k *OPSrvAccountKeyring) Get(key string) (Item, error) {
-	if err := k.InitializeClient(); err != nil {
+	return k.GetContext(context.Background(), key)
+}
+
+// GetContext retrieves an item by key
+func (k *OPSrvAccountKeyring) GetContext(ctx context.Context, key string) (Item, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
 		return Item{}, err
 	}
-	return k.OPStandardKeyring.Get(key)
+	return k.OPStandardKeyring.GetContext(ctx, key)
 }
 
 // GetMetadata returns metadata for a key
 func (k *OPSrvAccountKeyring) GetMetadata(key string) (Metadata, error) {
-	return k.OPStandardKeyring.GetMetadata(key)
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+// GetMetadataContext returns metadata for a key
+func (k *OPSrvAccountKeyring) GetMetadataContext(ctx context.Context, key string) (Metadata, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return Metadata{}, err
+	}
+	return k.OPStandardKeyring.GetMetadataContext(ctx, key)
+}
+
+// SetMetadata replaces the metadata fields of an item
+func (k *OPSrvAccountKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+// SetMetadataContext replaces the metadata fields of an item
+func (k *OPSrvAccountKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.SetMetadataContext(ctx, key, fields)
 }
 
 // Set creates or updates an item
 func (k *OPSrvAccountKeyring) Set(item Item) error {
-	if err := k.InitializeClient(); err != nil {
+	return k.SetContext(context.Background(), item)
+}
+
+// SetContext creates or updates an item
+func (k *OPSrvAccountKeyring) SetContext(ctx context.Context, item Item) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
 		return err
 	}
-	return k.OPStandardKeyring.Set(item)
+	return k.OPStandardKeyring.SetContext(ctx, item)
 }
 
 // Remove deletes an item by key
 func (k *OPSrvAccountKeyring) Remove(key string) error {
-	if err := k.InitializeClient(); err != nil {
+	return k.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext deletes an item by key
+func (k *OPSrvAccountKeyring) RemoveContext(ctx context.Context, key string) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
 		return err
 	}
-	return k.OPStandardKeyring.Remove(key)
+	return k.OPStandardKeyring.RemoveContext(ctx, key)
 }
 
 // Keys returns all keys in the keyring
 func (k *OPSrvAccountKeyring) Keys() ([]string, error) {
-	if err := k.InitializeClient(); err != nil {
+	return k.KeysContext(context.Background())
+}
+
+// KeysContext returns all keys in the keyring
+func (k *OPSrvAccountKeyring) KeysContext(ctx context.Context) ([]string, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return nil, err
+	}
+	return k.OPStandardKeyring.KeysContext(ctx)
+}
+
+// GetManyContext retrieves several items by key
+func (k *OPSrvAccountKeyring) GetManyContext(ctx context.Context, keys []string) (map[string]Item, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return nil, err
+	}
+	return k.OPStandardKeyring.GetManyContext(ctx, keys)
+}
+
+// SetManyContext creates or updates several items
+func (k *OPSrvAccountKeyring) SetManyContext(ctx context.Context, items []Item) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.SetManyContext(ctx, items)
+}
+
+// RemoveManyContext deletes several items by key
+func (k *OPSrvAccountKeyring) RemoveManyContext(ctx context.Context, keys []string) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.RemoveManyContext(ctx, keys)
+}
+
+// KeysWithPrefixContext returns the keys starting with prefix
+func (k *OPSrvAccountKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) ([]string, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
 		return nil, err
 	}
-	return k.OPStandardKeyring.Keys()
+	return k.OPStandardKeyring.KeysWithPrefixContext(ctx, prefix)
+}
+
+// GetVersionedContext retrieves an item by key along with its version
+func (k *OPSrvAccountKeyring) GetVersionedContext(ctx context.Context, key string) (Item, string, error) {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return Item{}, "", err
+	}
+	return k.OPStandardKeyring.GetVersionedContext(ctx, key)
+}
+
+// SetIfAbsentContext creates an item unless its key is taken
+func (k *OPSrvAccountKeyring) SetIfAbsentContext(ctx context.Context, item Item) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.SetIfAbsentContext(ctx, item)
+}
+
+// CompareAndSwapContext updates an item if it is still at expectedVersion
+func (k *OPSrvAccountKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) error {
+	if err := k.InitializeClientContext(ctx); err != nil {
+		return err
+	}
+	return k.OPStandardKeyring.CompareAndSwapContext(ctx, key, expectedVersion, item)
 }
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	onepassword "github.com/1password/onepassword-sdk-go"
@@ -29,11 +30,20 @@ type OPStandardKeyring struct {
 
 // GetOPItem returns the 1Password item matching the given key.
 func (k *OPStandardKeyring) GetOPItem(key string) (*onepassword.Item, error) {
-	opItemsAll, err := k.GetOPItems()
+	return k.GetOPItemContext(context.Background(), key)
+}
+
+// GetOPItemContext returns the 1Password item matching the given key.
+func (k *OPStandardKeyring) GetOPItemContext(ctx context.Context, key string) (*onepassword.Item, error) {
+	opItemsAll, err := k.GetOPItemsContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return k.findOPItem(opItemsAll, key)
+}
 
+// findOPItem returns the item matching the given key among opItemsAll.
+func (k *OPStandardKeyring) findOPItem(opItemsAll []onepassword.Item, key string) (*onepassword.Item, error) {
 	opItemTitle := k.GetOPItemTitleFromKey(key)
 
 	opItems := []onepassword.Item{}
@@ -60,7 +70,20 @@ func (k *OPStandardKeyring) GetOPItem(key string) (*onepassword.Item, error) {
 
 // GetOPItems returns all keyring items from the configured vault.
 func (k *OPStandardKeyring) GetOPItems() ([]onepassword.Item, error) {
-	ctxOuter, cancelOuter := context.WithTimeout(context.Background(), k.Timeout)
+	return k.GetOPItemsContext(context.Background())
+}
+
+// GetOPItemsContext returns all keyring items from the configured vault. Each
+// API call is bounded by Timeout and by ctx, whichever ends first.
+func (k *OPStandardKeyring) GetOPItemsContext(ctx context.Context) ([]onepassword.Item, error) {
+	return k.getOPItemsWithTitlePrefix(ctx, "")
+}
+
+// getOPItemsWithTitlePrefix returns the keyring items from the configured
+// vault whose titles start with titlePrefix. The listing is filtered before
+// the items are fetched, so only the matching ones are.
+func (k *OPStandardKeyring) getOPItemsWithTitlePrefix(ctx context.Context, titlePrefix string) ([]onepassword.Item, error) {
+	ctxOuter, cancelOuter := context.WithTimeout(ctx, k.Timeout)
 	defer cancelOuter()
 
 	itemOverviews, err := k.Client.List(
@@ -71,18 +94,19 @@ func (k *OPStandardKeyring) GetOPItems() ([]onepassword.Item, error) {
 		),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("unable to list items from vault with ID %#v: %w", k.VaultID, err)
+		return nil, fmt.Errorf("unable to list items from vault with ID %#v: %w", k.VaultID, opError(err))
 	}
 
 	opItems := []onepassword.Item{}
 	for _, itemOverview := range itemOverviews {
 		if !slices.Contains(itemOverview.Tags, k.ItemTag) ||
 			itemOverview.State != onepassword.ItemStateActive ||
-			itemOverview.Category != OPStandardItemCategory {
+			itemOverview.Category != OPStandardItemCategory ||
+			!strings.HasPrefix(itemOverview.Title, titlePrefix) {
 			continue
 		}
 
-		ctxInner, cancelInner := context.WithTimeout(context.Background(), k.Timeout)
+		ctxInner, cancelInner := context.WithTimeout(ctx, k.Timeout)
 		defer cancelInner()
 
 		opItem, err := k.Client.Get(ctxInner, k.VaultID, itemOverview.ID)
@@ -91,14 +115,18 @@ func (k *OPStandardKeyring) GetOPItems() ([]onepassword.Item, error) {
 				"unable to get item with ID %#v from vault with ID %#v: %w",
 				itemOverview.ID,
 				k.VaultID,
-				err,
+				opError(err),
 			)
 		}
 
 		opItemFields := []onepassword.ItemField{}
+		opItemSectionFields := []onepassword.ItemField{}
 		for _, opItemField := range opItem.Fields {
-			if opItemField.FieldType == OPStandardItemFieldType {
+			switch {
+			case opItemField.FieldType == OPStandardItemFieldType:
 				opItemFields = append(opItemFields, opItemField)
+			case isOPSectionField(opItemField):
+				opItemSectionFields = append(opItemSectionFields, opItemField)
 			}
 		}
 
@@ -106,7 +134,9 @@ func (k *OPStandardKeyring) GetOPItems() ([]onepassword.Item, error) {
 			continue
 		}
 
-		opItem.Fields = opItemFields
+		// The concealed field comes first, followed by any metadata fields
+		// and attributes.
+		opItem.Fields = append(opItemFields, opItemSectionFields...)
 		opItems = append(opItems, opItem)
 	}
 
@@ -115,7 +145,13 @@ func (k *OPStandardKeyring) GetOPItems() ([]onepassword.Item, error) {
 
 // Get returns the Item matching the given key, or ErrKeyNotFound.
 func (k OPStandardKeyring) Get(key string) (Item, error) {
-	opItem, err := k.GetOPItem(key)
+	return k.GetContext(context.Background(), key)
+}
+
+// GetContext returns the Item matching the given key, or ErrKeyNotFound.
+func (k OPStandardKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	opItem, err := k.GetOPItemContext(ctx, key)
 	if err != nil {
 		return Item{}, err
 	}
@@ -123,21 +159,72 @@ func (k OPStandardKeyring) Get(key string) (Item, error) {
 	if err != nil {
 		return Item{}, err
 	}
-	return *item, nil
+	return unexpired(*item)
 }
 
 // GetMetadata returns the non-secret parts of an Item.
-func (k OPStandardKeyring) GetMetadata(_ string) (Metadata, error) {
-	return Metadata{}, nil
+func (k OPStandardKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+// GetMetadataContext returns the non-secret parts of an Item.
+func (k OPStandardKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	opItem, err := k.GetOPItemContext(ctx, key)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return k.GetMetadataFromOPItem(opItem)
+}
+
+// SetMetadata replaces the metadata fields of an Item.
+func (k OPStandardKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+// SetMetadataContext replaces the metadata fields of an Item. They are kept
+// as text fields in their own section of the 1Password item.
+func (k OPStandardKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	opItem, err := k.GetOPItemContext(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	setOPItemSections(opItem, fields, opSectionValues(opItem, OPItemAttributesSection))
+	opItem.UpdatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, k.Timeout)
+	defer cancel()
+
+	if _, err := k.Client.Put(ctx, *opItem); err != nil {
+		return fmt.Errorf(
+			"unable to put item with title %#v in vault with ID %#v: %w",
+			opItem.Title,
+			k.VaultID,
+			opError(err),
+		)
+	}
+	return nil
 }
 
 // Set creates or updates an Item.
 func (k OPStandardKeyring) Set(item Item) error {
-	opItem, err := k.GetOPItem(item.Key)
+	return k.SetContext(context.Background(), item)
+}
+
+// SetContext creates or updates an Item.
+func (k OPStandardKeyring) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
+	opItem, err := k.GetOPItemContext(ctx, item.Key)
 	if err != nil && !errors.Is(err, ErrKeyNotFound) {
 		return err
 	}
+	return k.setOPItem(ctx, opItem, item)
+}
 
+// setOPItem updates opItem with item, or creates it if opItem is nil.
+func (k OPStandardKeyring) setOPItem(ctx context.Context, opItem *onepassword.Item, item Item) error {
 	opItemTitle := k.GetOPItemTitleFromKey(item.Key)
 	opItemFieldValue, err := k.GetOPItemFieldValueFromItem(&item)
 	if err != nil {
@@ -156,8 +243,14 @@ func (k OPStandardKeyring) Set(item Item) error {
 			}},
 			Tags: []string{k.ItemTag},
 		}
+		if len(item.Attributes) > 0 {
+			// The attributes are also in the concealed field, but as fields
+			// of their own they can be seen and searched for in 1Password.
+			params.Fields = append(params.Fields, opSectionFields(OPItemAttributesSection, item.Attributes)...)
+			params.Sections = []onepassword.ItemSection{{ID: OPItemAttributesSection}}
+		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), k.Timeout)
+		ctx, cancel := context.WithTimeout(ctx, k.Timeout)
 		defer cancel()
 
 		_, err := k.Client.Create(ctx, params)
@@ -166,16 +259,17 @@ func (k OPStandardKeyring) Set(item Item) error {
 				"unable to create item with title %#v in vault with ID %#v: %w",
 				opItemTitle,
 				k.VaultID,
-				err,
+				opError(err),
 			)
 		}
 		return nil
 	}
 
 	opItem.Fields[0].Value = opItemFieldValue
+	setOPItemSections(opItem, opSectionValues(opItem, OPItemMetadataSection), item.Attributes)
 	opItem.UpdatedAt = time.Now()
 
-	ctx, cancel := context.WithTimeout(context.Background(), k.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, k.Timeout)
 	defer cancel()
 
 	_, err = k.Client.Put(ctx, *opItem)
@@ -184,20 +278,78 @@ func (k OPStandardKeyring) Set(item Item) error {
 			"unable to put item with title %#v in vault with ID %#v: %w",
 			opItemTitle,
 			k.VaultID,
-			err,
+			opError(err),
 		)
 	}
 	return nil
 }
 
+// GetVersionedContext returns the Item matching key and the version of its
+// 1Password item.
+func (k OPStandardKeyring) GetVersionedContext(ctx context.Context, key string) (_ Item, _ string, err error) {
+	defer k.log.op(ctx, OpGetVersioned, key)(&err)
+	opItem, err := k.GetOPItemContext(ctx, key)
+	if err != nil {
+		return Item{}, "", err
+	}
+	item, err := k.GetItemFromOPItemFieldValue(opItem.Fields[0].Value)
+	if err != nil {
+		return Item{}, "", err
+	}
+	out, err := unexpired(*item)
+	return out, opItemVersion(opItem), err
+}
+
+// SetIfAbsentContext creates an item for item.Key unless the vault already
+// holds one. 1Password does not keep titles unique, so two writers creating
+// the same key at the same moment can both succeed.
+func (k OPStandardKeyring) SetIfAbsentContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSetIfAbsent, item.Key)(&err)
+	_, err = k.GetOPItemContext(ctx, item.Key)
+	if err == nil {
+		return ErrConflict
+	} else if !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	return k.setOPItem(ctx, nil, item)
+}
+
+// CompareAndSwapContext updates the item for key if its 1Password item is
+// still at expectedVersion. The item is put back with the version it was
+// read at, which 1Password checks against the stored one.
+func (k OPStandardKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) (err error) {
+	defer k.log.op(ctx, OpCompareAndSwap, key)(&err)
+	opItem, err := k.GetOPItemContext(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return ErrConflict
+	} else if err != nil {
+		return err
+	}
+	if opItemVersion(opItem) != expectedVersion {
+		return ErrConflict
+	}
+	item.Key = key
+	return k.setOPItem(ctx, opItem, item)
+}
+
 // Remove deletes the item with the matching key.
 func (k OPStandardKeyring) Remove(key string) error {
-	opItem, err := k.GetOPItem(key)
+	return k.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext deletes the item with the matching key.
+func (k OPStandardKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	opItem, err := k.GetOPItemContext(ctx, key)
 	if err != nil {
 		return err
 	}
+	return k.removeOPItem(ctx, opItem)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), k.Timeout)
+// removeOPItem deletes opItem.
+func (k OPStandardKeyring) removeOPItem(ctx context.Context, opItem *onepassword.Item) error {
+	ctx, cancel := context.WithTimeout(ctx, k.Timeout)
 	defer cancel()
 
 	if err := k.Client.Delete(ctx, k.VaultID, opItem.ID); err != nil {
@@ -205,38 +357,108 @@ func (k OPStandardKeyring) Remove(key string) error {
 			"unable to delete item with ID %#v in vault with ID %#v: %w",
 			opItem.ID,
 			k.VaultID,
-			err,
+			opError(err),
 		)
 	}
 	return nil
 }
 
-// Keys returns a slice of all keys stored on the keyring.
-func (k OPStandardKeyring) Keys() ([]string, error) {
-	opItems, err := k.GetOPItems()
+// GetManyContext returns the Items matching keys, listing the vault once.
+func (k OPStandardKeyring) GetManyContext(ctx context.Context, keys []string) (_ map[string]Item, err error) {
+	defer k.log.op(ctx, OpGetMany, "")(&err)
+	opItemsAll, err := k.GetOPItemsContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	opItemTitles := []string{}
-	keys := []string{}
-	for _, opItem := range opItems {
-		if !slices.Contains(opItemTitles, opItem.Title) {
-			opItemTitles = append(opItemTitles, opItem.Title)
-			keys = append(keys, k.GetKeyFromOPItemTitle(opItem.Title))
+	items := make(map[string]Item, len(keys))
+	errs := map[string]error{}
+	for _, key := range keys {
+		opItem, err := k.findOPItem(opItemsAll, key)
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+		item, err := k.GetItemFromOPItemFieldValue(opItem.Fields[0].Value)
+		if err == nil {
+			*item, err = unexpired(*item)
+		}
+		if err != nil {
+			errs[key] = err
+			continue
 		}
+		items[key] = *item
 	}
+	return items, batchError(errs)
+}
 
-	if len(opItemTitles) != len(opItems) {
-		return nil, fmt.Errorf(
-			"%w in vault with ID %#v: %#v",
-			OPErrItemTitleDuplicate,
-			k.VaultID,
-			opItemTitles,
-		)
+// SetManyContext creates or updates items, listing the vault once.
+func (k OPStandardKeyring) SetManyContext(ctx context.Context, items []Item) (err error) {
+	defer k.log.op(ctx, OpSetMany, "")(&err)
+	opItemsAll, err := k.GetOPItemsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	errs := map[string]error{}
+	for _, item := range lastByKey(items) {
+		opItem, err := k.findOPItem(opItemsAll, item.Key)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			errs[item.Key] = err
+			continue
+		}
+		if err := k.setOPItem(ctx, opItem, item); err != nil {
+			errs[item.Key] = err
+		}
+	}
+	return batchError(errs)
+}
+
+// RemoveManyContext deletes the items matching keys, listing the vault once.
+func (k OPStandardKeyring) RemoveManyContext(ctx context.Context, keys []string) (err error) {
+	defer k.log.op(ctx, OpRemoveMany, "")(&err)
+	opItemsAll, err := k.GetOPItemsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	errs := map[string]error{}
+	for _, key := range uniqueKeys(keys) {
+		opItem, err := k.findOPItem(opItemsAll, key)
+		if err == nil {
+			err = k.removeOPItem(ctx, opItem)
+		}
+		if err != nil {
+			errs[key] = err
+		}
+	}
+	return batchError(errs)
+}
+
+// Keys returns a slice of all keys stored on the keyring.
+func (k OPStandardKeyring) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+// KeysContext returns a slice of all keys stored on the keyring.
+func (k OPStandardKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	opItems, err := k.GetOPItemsContext(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return k.opItemKeys(opItems)
+}
 
-	return keys, nil
+// KeysWithPrefixContext returns the keys starting with prefix, fetching only
+// the items whose titles match.
+func (k OPStandardKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeysWithPrefix, "")(&err)
+	opItems, err := k.getOPItemsWithTitlePrefix(ctx, k.GetOPItemTitleFromKey(prefix))
+	if err != nil {
+		return nil, err
+	}
+	return k.opItemKeys(opItems)
 }
 
 // OPStandardClientAPI is the subset of the onepassword-sdk-go items client
@@ -5,10 +5,12 @@ package keyring
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
 
+	connectop "github.com/1Password/connect-sdk-go/onepassword"
 	onepassword "github.com/1password/onepassword-sdk-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -356,6 +358,57 @@ func TestOPStandardKeyring_Set(t *testing.T) {
 	}
 }
 
+func TestOPStandardKeyring_SetMetadata(t *testing.T) {
+	keyring := &OPStandardKeyring{
+		OPBaseKeyring: OPBaseKeyring{
+			VaultID:         "vaultID",
+			ItemTitlePrefix: "itemTitlePrefix",
+			ItemTag:         "itemTag",
+			ItemFieldTitle:  "itemFieldTitle",
+		},
+	}
+	createdAt := time.Date(1955, 11, 5, 11, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(1985, 11, 5, 11, 0, 0, 0, time.UTC)
+
+	fieldValue, err := keyring.GetOPItemFieldValueFromItem(&Item{Key: "key", Data: []byte(`data`), Label: "label"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opItemExisting := onepassword.Item{
+		ID:       "itemID",
+		Title:    "itemTitlePrefix: key",
+		Category: OPStandardItemCategory,
+		VaultID:  "vaultID",
+		Fields: []onepassword.ItemField{{
+			ID:        "itemFieldID",
+			Title:     "itemFieldTitle",
+			FieldType: OPStandardItemFieldType,
+			Value:     fieldValue,
+		}},
+		Tags:      []string{"itemTag"},
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+
+	var opItemSetActual onepassword.Item
+	NewOPStandardKeyringMock_SetItem(t, keyring, "", "", updatedAt, []onepassword.Item{opItemExisting}, &opItemSetActual)
+
+	if err := keyring.SetMetadata("key", map[string]string{"owner": "ops"}); err != nil {
+		t.Fatal(err)
+	}
+
+	md, err := keyring.GetMetadataFromOPItem(&opItemSetActual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "label", md.Label)
+	assert.Nil(t, md.Data)
+	assert.Equal(t, map[string]string{"owner": "ops"}, md.Fields)
+	assert.Equal(t, createdAt, md.CreationTime)
+	assert.Equal(t, updatedAt, md.ModificationTime)
+	assert.Contains(t, opItemSetActual.Sections, onepassword.ItemSection{ID: OPItemMetadataSection})
+}
+
 func TestOPStandardKeyring_Remove(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -539,6 +592,132 @@ func TestOPStandardKeyring_GetKeys(t *testing.T) {
 	}
 }
 
+// newOPStandardTestItem returns a keyring item for key holding "data key".
+func newOPStandardTestItem(t *testing.T, id, key string) onepassword.Item {
+	return onepassword.Item{
+		ID:       id,
+		Title:    "itemTitlePrefix: " + key,
+		Category: onepassword.ItemCategoryAPICredentials,
+		VaultID:  "vaultID",
+		Fields: []onepassword.ItemField{{
+			ID:        "itemFieldID",
+			Title:     "itemFieldTitle",
+			FieldType: onepassword.ItemFieldTypeConcealed,
+			Value:     NewOPItemFieldValue(t, key, []byte("data "+key)),
+		}},
+		Tags: []string{"itemTag"},
+	}
+}
+
+func TestOPStandardKeyring_GetMany(t *testing.T) {
+	keyring := &OPStandardKeyring{
+		OPBaseKeyring: OPBaseKeyring{
+			VaultID:         "vaultID",
+			ItemTitlePrefix: "itemTitlePrefix",
+			ItemTag:         "itemTag",
+			ItemFieldTitle:  "itemFieldTitle",
+		},
+	}
+	// The vault may only be listed once for the whole batch.
+	NewOPStandardKeyringMock_GetItem(t, keyring, []onepassword.Item{newOPStandardTestItem(t, "id1", "a"), newOPStandardTestItem(t, "id2", "b")})
+
+	items, err := GetMany(keyring, []string{"a", "b", "missing"})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors["missing"], ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound for the missing key only, got %v", err)
+	}
+	assert.Len(t, items, 2)
+	assert.Equal(t, []byte("data a"), items["a"].Data)
+	assert.Equal(t, []byte("data b"), items["b"].Data)
+}
+
+func TestOPStandardKeyring_KeysWithPrefix(t *testing.T) {
+	keyring := &OPStandardKeyring{
+		OPBaseKeyring: OPBaseKeyring{
+			VaultID:         "vaultID",
+			ItemTitlePrefix: "itemTitlePrefix",
+			ItemTag:         "itemTag",
+			ItemFieldTitle:  "itemFieldTitle",
+		},
+	}
+	opClientMock := NewOPStandardKeyringMock_GetItem(t, keyring, []onepassword.Item{
+		newOPStandardTestItem(t, "id1", "team/a"),
+		newOPStandardTestItem(t, "id2", "other/b"),
+	})
+
+	keys, err := KeysWithPrefix(keyring, "team/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"team/a"}, keys)
+	// Only the matching item is fetched.
+	opClientMock.AssertNumberOfCalls(t, "Get", 1)
+}
+
+func TestOPStandardKeyring_RemoveMany(t *testing.T) {
+	keyring := &OPStandardKeyring{
+		OPBaseKeyring: OPBaseKeyring{
+			VaultID:         "vaultID",
+			ItemTitlePrefix: "itemTitlePrefix",
+			ItemTag:         "itemTag",
+			ItemFieldTitle:  "itemFieldTitle",
+		},
+	}
+	var opItemIDRemoved string
+	NewOPStandardKeyringMock_RemoveItem(t, keyring, []onepassword.Item{{
+		ID:       "itemID",
+		Title:    "itemTitlePrefix: key",
+		Category: onepassword.ItemCategoryAPICredentials,
+		VaultID:  "vaultID",
+		Fields: []onepassword.ItemField{{
+			ID:        "itemFieldID",
+			Title:     "itemFieldTitle",
+			FieldType: onepassword.ItemFieldTypeConcealed,
+			Value:     NewOPItemFieldValue(t, "key", []byte(`data`)),
+		}},
+		Tags: []string{"itemTag"},
+	}}, &opItemIDRemoved)
+
+	err := RemoveMany(keyring, []string{"key", "missing"})
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound for the missing key, got %v", err)
+	}
+	assert.Equal(t, "itemID", opItemIDRemoved)
+}
+
+func TestOPStandardKeyring_CompareAndSwap(t *testing.T) {
+	newKeyring := func() *OPStandardKeyring {
+		return &OPStandardKeyring{
+			OPBaseKeyring: OPBaseKeyring{
+				VaultID:         "vaultID",
+				ItemTitlePrefix: "itemTitlePrefix",
+				ItemTag:         "itemTag",
+				ItemFieldTitle:  "itemFieldTitle",
+			},
+		}
+	}
+	opItem := newOPStandardTestItem(t, "itemID", "key")
+	opItem.Version = 3
+
+	var opItemSet onepassword.Item
+	keyring := newKeyring()
+	opClientMock := NewOPStandardKeyringMock_SetItem(t, keyring, "", "", time.Now(), []onepassword.Item{opItem}, &opItemSet)
+	err := CompareAndSwap(keyring, "key", "2", Item{Data: []byte("new")})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict for a stale version, got %v", err)
+	}
+	opClientMock.AssertNotCalled(t, "Put", mock.Anything, mock.Anything)
+
+	keyring = newKeyring()
+	NewOPStandardKeyringMock_SetItem(t, keyring, "", "", time.Now(), []onepassword.Item{opItem}, &opItemSet)
+	if err := CompareAndSwap(keyring, "key", "3", Item{Data: []byte("new")}); err != nil {
+		t.Fatal(err)
+	}
+	// The item goes back with the version it was read at, for 1Password to check.
+	assert.Equal(t, "itemID", opItemSet.ID)
+	assert.Equal(t, uint32(3), opItemSet.Version)
+}
+
 func NewOPStandardKeyringMock_GetItem(
 	t *testing.T,
 	keyring *OPStandardKeyring,
@@ -684,3 +863,28 @@ func NewOPStandardKeyringMock_RemoveItem(
 
 	return opClientMock
 }
+
+func TestOPError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"rateLimited", &onepassword.RateLimitExceededError{}, ErrRateLimited},
+		{"desktopSessionExpired", &onepassword.DesktopSessionExpiredError{}, ErrLocked},
+		{"connectUnauthorized", &connectop.Error{StatusCode: 401}, ErrAccessDenied},
+		{"connectTooManyRequests", &connectop.Error{StatusCode: 429}, ErrRateLimited},
+		{"connectUnavailable", &connectop.Error{StatusCode: 503}, ErrBackendUnavailable},
+		{"connectNotFound", &connectop.Error{StatusCode: 404}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := opError(fmt.Errorf("wrapped: %w", tc.err))
+			assert.ErrorIs(t, err, tc.err)
+			if tc.kind != nil {
+				assert.ErrorIs(t, err, tc.kind)
+			}
+		})
+	}
+}
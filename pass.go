@@ -3,6 +3,7 @@
 package keyring
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,16 +12,28 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 func init() {
+	backendCapabilities[PassBackend] = Capabilities{
+		Metadata:    MetadataSupported,
+		Label:       true,
+		Description: true,
+		Interactive: true, // gpg may ask pinentry for a passphrase
+		// The metadata is kept in a plaintext sidecar.
+		PlaintextMetadata: true,
+	}
+
 	supportedBackends[PassBackend] = opener(func(cfg Config) (Keyring, error) {
 		var err error
 
 		pass := &passKeyring{
-			passcmd: cfg.PassCmd,
-			dir:     cfg.PassDir,
-			prefix:  cfg.PassPrefix,
+			passcmd:     cfg.PassCmd,
+			dir:         cfg.PassDir,
+			prefix:      cfg.PassPrefix,
+			lockTimeout: cfg.LockTimeout,
+			log:         newCallLogger(cfg, PassBackend),
 		}
 
 		if pass.passcmd == "" {
@@ -47,21 +60,32 @@ func init() {
 		// fail if the pass program is not available
 		_, err = exec.LookPath(pass.passcmd)
 		if err != nil {
-			return nil, errors.New("the pass program is not available")
+			return nil, fmt.Errorf("%w: %w", newKindError(ErrBackendUnavailable, "the pass program is not available"), err)
 		}
 
 		return pass, nil
 	})
+	backendProbes[PassBackend] = func(_ context.Context, cfg Config) error {
+		ring, err := supportedBackends[PassBackend](cfg)
+		if err != nil {
+			return err
+		}
+		return ring.(*passKeyring).probe()
+	}
 }
 
 type passKeyring struct {
-	dir     string
-	passcmd string
-	prefix  string
+	dir         string
+	passcmd     string
+	prefix      string
+	lockTimeout time.Duration
+	log         callLogger
 }
 
-func (k *passKeyring) pass(args ...string) *exec.Cmd {
-	cmd := exec.CommandContext(context.Background(), k.passcmd, args...)
+// pass builds a command for the password store. Cancelling ctx kills the
+// process.
+func (k *passKeyring) pass(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, k.passcmd, args...)
 	if k.dir != "" {
 		cmd.Env = append(os.Environ(), fmt.Sprintf("PASSWORD_STORE_DIR=%s", k.dir))
 	}
@@ -70,97 +94,405 @@ func (k *passKeyring) pass(args ...string) *exec.Cmd {
 	return cmd
 }
 
+// lock takes the lock on the store, shared for reads and exclusive for
+// writes, and returns the function that releases it. Reads hold it only
+// while reading the encrypted file, which is decrypted once it is released,
+// as gpg may wait on a passphrase prompt.
+func (k *passKeyring) lock(ctx context.Context, exclusive bool) (func(), error) {
+	return lockDir(ctx, k.dir, exclusive, k.lockTimeout)
+}
+
 func (k *passKeyring) Get(key string) (Item, error) {
-	if !k.itemExists(key) {
-		return Item{}, ErrKeyNotFound
+	return k.GetContext(context.Background(), key)
+}
+
+func (k *passKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
+	ciphertext, err := k.read(ctx, key)
+	if err != nil {
+		return Item{}, err
+	}
+	return k.decrypt(ctx, key, ciphertext)
+}
+
+// read returns the encrypted file of the item for key, holding the shared
+// lock only while reading it.
+func (k *passKeyring) read(ctx context.Context, key string) ([]byte, error) {
+	release, err := k.lock(ctx, false)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
+
+	ciphertext, err := os.ReadFile(k.itemFile(key))
+	if os.IsNotExist(err) {
+		return nil, ErrKeyNotFound
+	}
+	return ciphertext, err
+}
+
+// decrypt decrypts ciphertext, the encrypted file of the item for key. pass
+// only shows items in a store, so it is run on a temporary one holding just
+// this file, leaving the real store to writers while gpg waits on a
+// passphrase prompt.
+func (k *passKeyring) decrypt(ctx context.Context, key string, ciphertext []byte) (Item, error) {
+	store, err := os.MkdirTemp("", "keyring-pass-")
+	if err != nil {
+		return Item{}, err
+	}
+	defer os.RemoveAll(store)
 
 	name := filepath.Join(k.prefix, key)
-	cmd := k.pass("show", name)
-	output, err := cmd.Output()
+	file := filepath.Join(store, name+".gpg")
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return Item{}, err
+	}
+	if err := os.WriteFile(file, ciphertext, 0o600); err != nil {
+		return Item{}, err
+	}
+
+	cmd := k.pass(ctx, "show", name)
+	cmd.Env = append(os.Environ(), "PASSWORD_STORE_DIR="+store)
+	output, err := runCommand(ctx, cmd, passError)
 	if err != nil {
 		return Item{}, err
 	}
+	defer zeroBytes(output)
 
 	var decoded Item
-	err = json.Unmarshal(output, &decoded)
+	if err = json.Unmarshal(output, &decoded); err != nil {
+		return Item{}, err
+	}
+
+	return unexpired(decoded)
+}
+
+func (k *passKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+// GetMetadataContext returns the metadata this backend keeps in a plaintext sidecar,
+// so no decryption is needed.
+func (k *passKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
 
-	return decoded, err
+	release, err := k.lock(ctx, false)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer release()
+
+	if !k.itemExists(key) {
+		return Metadata{}, ErrKeyNotFound
+	}
+
+	return k.sidecar().metadata(ctx, key)
+}
+
+func (k *passKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *passKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if !k.itemExists(key) {
+		return ErrKeyNotFound
+	}
+
+	return k.sidecar().setFields(ctx, key, fields)
 }
 
-func (k *passKeyring) GetMetadata(_ string) (Metadata, error) {
-	return Metadata{}, nil
+// sidecar holds the metadata of the items in a hidden directory of the store,
+// which pass ls does not show.
+func (k *passKeyring) sidecar() metadataSidecar {
+	return metadataSidecar{store: dirSidecar(filepath.Join(k.dir, ".keyring-metadata", k.prefix))}
 }
 
 func (k *passKeyring) Set(i Item) error {
-	bytes, err := json.Marshal(i)
+	return k.SetContext(context.Background(), i)
+}
+
+func (k *passKeyring) SetContext(ctx context.Context, i Item) (err error) {
+	defer k.log.op(ctx, OpSet, i.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return k.set(ctx, i)
+}
+
+// set encrypts and stores i. The caller holds the exclusive lock.
+func (k *passKeyring) set(ctx context.Context, i Item) error {
+	data, err := json.Marshal(i)
 	if err != nil {
 		return err
 	}
+	defer zeroBytes(data)
 
 	name := filepath.Join(k.prefix, i.Key)
-	cmd := k.pass("insert", "-m", "-f", name)
-	cmd.Stdin = strings.NewReader(string(bytes))
+	cmd := k.pass(ctx, "insert", "-m", "-f", name)
+	cmd.Stdin = bytes.NewReader(data)
+
+	if _, err := runCommand(ctx, cmd, passError); err != nil {
+		return err
+	}
 
-	err = cmd.Run()
+	return k.sidecar().itemSet(ctx, i)
+}
+
+// GetVersionedContext returns the item for key and its version, a hash of the
+// encrypted file it was decrypted from.
+func (k *passKeyring) GetVersionedContext(ctx context.Context, key string) (_ Item, _ string, err error) {
+	defer k.log.op(ctx, OpGetVersioned, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, "", err
+	}
+
+	ciphertext, err := k.read(ctx, key)
+	if err != nil {
+		return Item{}, "", err
+	}
+	item, err := k.decrypt(ctx, key, ciphertext)
+	if err != nil {
+		return Item{}, "", err
+	}
+	return item, contentVersion(ciphertext), nil
+}
+
+// SetIfAbsentContext stores item unless the store has an entry for its key.
+// pass cannot insert conditionally, so the store is checked under the lock
+// just before running it.
+func (k *passKeyring) SetIfAbsentContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSetIfAbsent, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if k.itemExists(item.Key) {
+		return ErrConflict
+	}
+	return k.set(ctx, item)
+}
+
+// CompareAndSwapContext stores item under key if the entry's encrypted file
+// still has expectedVersion, checked under the lock just before running
+// pass.
+func (k *passKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) (err error) {
+	defer k.log.op(ctx, OpCompareAndSwap, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := k.lock(ctx, true)
 	if err != nil {
 		return err
 	}
+	defer release()
 
-	return nil
+	version, err := fileVersion(k.itemFile(key))
+	if errors.Is(err, ErrKeyNotFound) || (err == nil && version != expectedVersion) {
+		return ErrConflict
+	} else if err != nil {
+		return err
+	}
+	item.Key = key
+	return k.set(ctx, item)
 }
 
 func (k *passKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *passKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	if !k.itemExists(key) {
 		return ErrKeyNotFound
 	}
 
 	name := filepath.Join(k.prefix, key)
-	cmd := k.pass("rm", "-f", name)
-	err := cmd.Run()
-	if err != nil {
+	if _, err := runCommand(ctx, k.pass(ctx, "rm", "-f", name), passError); err != nil {
 		return err
 	}
 
-	return nil
+	return k.sidecar().remove(ctx, key)
+}
+
+// passError maps what gpg reports on a failed pass invocation onto the
+// package's sentinel errors.
+func passError(stderr string) error {
+	stderr = strings.ToLower(stderr)
+	switch {
+	case strings.Contains(stderr, "operation cancelled"):
+		// Pinentry was dismissed.
+		return ErrUserCancelled
+	case strings.Contains(stderr, "bad passphrase"):
+		return ErrWrongPassphrase
+	case strings.Contains(stderr, "no secret key"):
+		// The store is encrypted to a key we do not hold.
+		return ErrAccessDenied
+	case strings.Contains(stderr, "inappropriate ioctl for device"), strings.Contains(stderr, "no pinentry"):
+		// The agent needs a passphrase but has no way to ask for it.
+		return ErrLocked
+	default:
+		return nil
+	}
+}
+
+// probe checks that the password store has been initialised. pass looks for
+// .gpg-id in the item's folder and each parent up to the store root.
+func (k *passKeyring) probe() error {
+	root := filepath.Clean(k.dir)
+	for dir := filepath.Join(root, k.prefix); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".gpg-id")); err == nil {
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		if dir == root || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	return fmt.Errorf("the password store %s is not initialised: no .gpg-id found, run \"pass init\"", k.dir)
 }
 
 func (k *passKeyring) itemExists(key string) bool {
-	var path = filepath.Join(k.dir, k.prefix, key+".gpg")
-	_, err := os.Stat(path)
+	_, err := os.Stat(k.itemFile(key))
 
 	return err == nil
 }
 
+// itemFile returns the path of the encrypted file for key.
+func (k *passKeyring) itemFile(key string) string {
+	return filepath.Join(k.dir, k.prefix, key+".gpg")
+}
+
 func (k *passKeyring) Keys() ([]string, error) {
-	var keys = []string{}
-	var path = filepath.Join(k.dir, k.prefix)
+	return k.KeysContext(context.Background())
+}
+
+func (k *passKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	return walkStoreKeys(ctx, filepath.Join(k.dir, k.prefix), "", ".gpg")
+}
 
-	info, err := os.Stat(path)
+// KeysWithPrefixContext lists the keys starting with prefix, walking only the
+// subdirectory that holds them.
+func (k *passKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeysWithPrefix, "")(&err)
+	return walkStoreKeys(ctx, filepath.Join(k.dir, k.prefix), prefix, ".gpg")
+}
+
+// HistoryContext returns the earlier versions of the item for key from the
+// store's git history, newest first, or ErrHistoryNotSupported if the store
+// is not kept in git. Removed items keep their history.
+func (k *passKeyring) HistoryContext(ctx context.Context, key string) (_ []Revision, err error) {
+	defer k.log.op(ctx, OpHistory, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	release, err := k.lock(ctx, false)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return keys, nil
-		}
-		return keys, err
+		return nil, err
+	}
+	defer release()
+
+	return gitHistory(ctx, k.git, k.dir, k.itemFile(key))
+}
+
+// RestoreContext checks the item's encrypted file out of the commit version
+// and commits it, then updates the item's metadata, which needs the file
+// decrypted. It is decrypted without the lock, as in GetContext, and the
+// metadata is left alone if another writer has replaced the item meanwhile.
+func (k *passKeyring) RestoreContext(ctx context.Context, key, version string) (err error) {
+	defer k.log.op(ctx, OpRestore, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ciphertext, err := k.restore(ctx, key, version)
+	if err != nil {
+		return err
 	}
-	if !info.IsDir() {
-		return keys, fmt.Errorf("%s is not a directory", path)
+	item, err := k.decrypt(ctx, key, ciphertext)
+	if err != nil {
+		return err
 	}
 
-	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-		if !info.IsDir() && filepath.Ext(p) == ".gpg" {
-			name := strings.TrimPrefix(p, path)
-			if name[0] == os.PathSeparator {
-				name = name[1:]
-			}
-			keys = append(keys, name[:len(name)-4])
-		}
+	current, err := fileVersion(k.itemFile(key))
+	if errors.Is(err, ErrKeyNotFound) || (err == nil && current != contentVersion(ciphertext)) {
 		return nil
-	})
+	} else if err != nil {
+		return err
+	}
+	return k.sidecar().itemSet(ctx, item)
+}
+
+// restore checks the item's encrypted file out of the commit version, under
+// the exclusive lock, and returns it.
+func (k *passKeyring) restore(ctx context.Context, key, version string) ([]byte, error) {
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if err := gitRestore(ctx, k.git, k.dir, k.itemFile(key), version); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(k.itemFile(key))
+}
+
+// git runs git in the store with pass git and returns its output.
+func (k *passKeyring) git(ctx context.Context, args ...string) ([]byte, error) {
+	return runCommand(ctx, k.pass(ctx, append([]string{"git"}, args...)...), gitStoreError(passError))
+}
 
-	return keys, err
+// Watch reports changes to the items in the store as the files under it
+// change, including those made with pass itself.
+func (k *passKeyring) Watch(ctx context.Context) (<-chan Event, error) {
+	return watchStore(ctx, k, filepath.Join(k.dir, k.prefix), ".gpg")
 }
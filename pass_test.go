@@ -4,10 +4,14 @@ package keyring
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func setup(t *testing.T) (*passKeyring, func(t *testing.T)) {
@@ -43,7 +47,7 @@ func setup(t *testing.T) (*passKeyring, func(t *testing.T)) {
 		prefix:  "keyring",
 	}
 
-	cmd := k.pass("init", "test@example.com")
+	cmd := k.pass(t.Context(), "init", "test@example.com")
 	cmd.Stderr = os.Stderr
 	err = cmd.Run()
 	if err != nil {
@@ -234,3 +238,93 @@ func TestPassKeyringKeysWithSymlink(t *testing.T) {
 		t.Fatalf("Expected keys %v, got %v", expectedKeys, keys)
 	}
 }
+
+func TestPassKeyringClassifiesGPGErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "llamas.gpg"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(t.TempDir(), "pass")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho 'gpg: decryption failed: No secret key' >&2\nexit 2\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	k := &passKeyring{dir: dir, passcmd: script}
+	if _, err := k.Get("llamas"); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("Get err = %v, want ErrAccessDenied", err)
+	}
+
+	k.passcmd = filepath.Join(dir, "missing")
+	if _, err := k.Get("llamas"); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("Get err = %v, want ErrBackendUnavailable", err)
+	}
+}
+
+func TestOpenReportsMissingPass(t *testing.T) {
+	_, err := Open(Config{AllowedBackends: []BackendType{PassBackend}, PassCmd: "llamas-are-not-pass"})
+	if !errors.Is(err, ErrNoAvailImpl) || !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("Open err = %v, want ErrNoAvailImpl and ErrBackendUnavailable", err)
+	}
+	if !strings.Contains(err.Error(), "pass: the pass program is not available") {
+		t.Fatalf("error does not explain the failure: %v", err)
+	}
+}
+
+func TestPassKeyringGetDecryptsWithoutTheLock(t *testing.T) {
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+	proceed := filepath.Join(dir, "proceed")
+
+	// A stand-in for pass whose show waits, as gpg would on pinentry, and
+	// prints the file as it is.
+	passcmd := filepath.Join(dir, "pass")
+	script := fmt.Sprintf(`#!/bin/sh
+touch %q
+while [ ! -e %q ]; do sleep 0.01; done
+cat "$PASSWORD_STORE_DIR/$2.gpg"
+`, started, proceed)
+	if err := os.WriteFile(passcmd, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	k := &passKeyring{dir: filepath.Join(dir, "store"), passcmd: passcmd, prefix: "keyring"}
+	if err := os.MkdirAll(filepath.Join(k.dir, k.prefix), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(k.itemFile("llamas"), []byte(`{"Key":"llamas","Data":"bGxhbWFz"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		item Item
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		item, err := k.Get("llamas")
+		done <- result{item, err}
+	}()
+	for {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	release, err := lockDir(t.Context(), k.dir, true, time.Second)
+	if err != nil {
+		t.Fatalf("A writer could not lock the store while pass show ran: %v", err)
+	}
+	release()
+
+	if err := os.WriteFile(proceed, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if string(r.item.Data) != "llamas" {
+		t.Fatalf("Unexpected data %q", r.item.Data)
+	}
+}
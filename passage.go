@@ -3,6 +3,7 @@
 package keyring
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,16 +12,28 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 func init() {
+	backendCapabilities[PassageBackend] = Capabilities{
+		Metadata:    MetadataSupported,
+		Label:       true,
+		Description: true,
+		Interactive: true, // age may ask for the identity's passphrase
+		// The metadata is kept in a plaintext sidecar.
+		PlaintextMetadata: true,
+	}
+
 	supportedBackends[PassageBackend] = opener(func(cfg Config) (Keyring, error) {
 		var err error
 
 		passage := &passageKeyring{
-			passcmd: cfg.PassCmd,
-			dir:     cfg.PassDir,
-			prefix:  cfg.PassPrefix,
+			passcmd:     cfg.PassCmd,
+			dir:         cfg.PassDir,
+			prefix:      cfg.PassPrefix,
+			lockTimeout: cfg.LockTimeout,
+			log:         newCallLogger(cfg, PassageBackend),
 		}
 
 		if passage.passcmd == "" {
@@ -47,21 +60,32 @@ func init() {
 		// fail if the pass program is not available
 		_, err = exec.LookPath(passage.passcmd)
 		if err != nil {
-			return nil, errors.New("the passage program is not available")
+			return nil, fmt.Errorf("%w: %w", newKindError(ErrBackendUnavailable, "the passage program is not available"), err)
 		}
 
 		return passage, nil
 	})
+	backendProbes[PassageBackend] = func(_ context.Context, cfg Config) error {
+		ring, err := supportedBackends[PassageBackend](cfg)
+		if err != nil {
+			return err
+		}
+		return ring.(*passageKeyring).probe()
+	}
 }
 
 type passageKeyring struct {
-	dir     string
-	passcmd string
-	prefix  string
+	dir         string
+	passcmd     string
+	prefix      string
+	lockTimeout time.Duration
+	log         callLogger
 }
 
-func (k *passageKeyring) pass(args ...string) *exec.Cmd {
-	cmd := exec.CommandContext(context.Background(), k.passcmd, args...)
+// pass builds a command for the password store. Cancelling ctx kills the
+// process.
+func (k *passageKeyring) pass(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, k.passcmd, args...)
 	if k.dir != "" {
 		cmd.Env = append(os.Environ(), fmt.Sprintf("PASSAGE_DIR=%s", k.dir))
 	}
@@ -70,97 +94,398 @@ func (k *passageKeyring) pass(args ...string) *exec.Cmd {
 	return cmd
 }
 
+// lock takes the lock on the store, shared for reads and exclusive for
+// writes, and returns the function that releases it. Reads hold it only
+// while reading the encrypted file, which is decrypted once it is released,
+// as age may wait on a passphrase prompt.
+func (k *passageKeyring) lock(ctx context.Context, exclusive bool) (func(), error) {
+	return lockDir(ctx, k.dir, exclusive, k.lockTimeout)
+}
+
 func (k *passageKeyring) Get(key string) (Item, error) {
-	if !k.itemExists(key) {
-		return Item{}, ErrKeyNotFound
+	return k.GetContext(context.Background(), key)
+}
+
+func (k *passageKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
+	ciphertext, err := k.read(ctx, key)
+	if err != nil {
+		return Item{}, err
 	}
+	return k.decrypt(ctx, key, ciphertext)
+}
+
+// read returns the encrypted file of the item for key, holding the shared
+// lock only while reading it.
+func (k *passageKeyring) read(ctx context.Context, key string) ([]byte, error) {
+	release, err := k.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ciphertext, err := os.ReadFile(k.itemFile(key))
+	if os.IsNotExist(err) {
+		return nil, ErrKeyNotFound
+	}
+	return ciphertext, err
+}
+
+// decrypt decrypts ciphertext, the encrypted file of the item for key. passage
+// only shows items in a store, so it is run on a temporary one holding just
+// this file, leaving the real store to writers while age waits on a
+// passphrase prompt.
+func (k *passageKeyring) decrypt(ctx context.Context, key string, ciphertext []byte) (Item, error) {
+	store, err := os.MkdirTemp("", "keyring-passage-")
+	if err != nil {
+		return Item{}, err
+	}
+	defer os.RemoveAll(store)
 
 	name := filepath.Join(k.prefix, key)
-	cmd := k.pass("show", name)
-	output, err := cmd.Output()
+	file := filepath.Join(store, name+".age")
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return Item{}, err
+	}
+	if err := os.WriteFile(file, ciphertext, 0o600); err != nil {
+		return Item{}, err
+	}
+
+	cmd := k.pass(ctx, "show", name)
+	cmd.Env = append(os.Environ(), "PASSAGE_DIR="+store)
+	output, err := runCommand(ctx, cmd, passageError)
 	if err != nil {
 		return Item{}, err
 	}
+	defer zeroBytes(output)
 
 	var decoded Item
-	err = json.Unmarshal(output, &decoded)
+	if err = json.Unmarshal(output, &decoded); err != nil {
+		return Item{}, err
+	}
 
-	return decoded, err
+	return unexpired(decoded)
 }
 
-func (k *passageKeyring) GetMetadata(_ string) (Metadata, error) {
-	return Metadata{}, nil
+func (k *passageKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+// GetMetadataContext returns the metadata this backend keeps in a plaintext sidecar,
+// so no decryption is needed.
+func (k *passageKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
+
+	release, err := k.lock(ctx, false)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer release()
+
+	if !k.itemExists(key) {
+		return Metadata{}, ErrKeyNotFound
+	}
+
+	return k.sidecar().metadata(ctx, key)
+}
+
+func (k *passageKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *passageKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if !k.itemExists(key) {
+		return ErrKeyNotFound
+	}
+
+	return k.sidecar().setFields(ctx, key, fields)
+}
+
+// sidecar holds the metadata of the items in a hidden directory of the store,
+// which passage ls does not show.
+func (k *passageKeyring) sidecar() metadataSidecar {
+	return metadataSidecar{store: dirSidecar(filepath.Join(k.dir, ".keyring-metadata", k.prefix))}
 }
 
 func (k *passageKeyring) Set(i Item) error {
-	bytes, err := json.Marshal(i)
+	return k.SetContext(context.Background(), i)
+}
+
+func (k *passageKeyring) SetContext(ctx context.Context, i Item) (err error) {
+	defer k.log.op(ctx, OpSet, i.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return k.set(ctx, i)
+}
+
+// set encrypts and stores i. The caller holds the exclusive lock.
+func (k *passageKeyring) set(ctx context.Context, i Item) error {
+	data, err := json.Marshal(i)
 	if err != nil {
 		return err
 	}
+	defer zeroBytes(data)
 
 	name := filepath.Join(k.prefix, i.Key)
-	cmd := k.pass("insert", "-m", "-f", name)
-	cmd.Stdin = strings.NewReader(string(bytes))
+	cmd := k.pass(ctx, "insert", "-m", "-f", name)
+	cmd.Stdin = bytes.NewReader(data)
+
+	if _, err := runCommand(ctx, cmd, passageError); err != nil {
+		return err
+	}
+
+	return k.sidecar().itemSet(ctx, i)
+}
+
+// GetVersionedContext returns the item for key and its version, a hash of the
+// encrypted file it was decrypted from.
+func (k *passageKeyring) GetVersionedContext(ctx context.Context, key string) (_ Item, _ string, err error) {
+	defer k.log.op(ctx, OpGetVersioned, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, "", err
+	}
 
-	err = cmd.Run()
+	ciphertext, err := k.read(ctx, key)
+	if err != nil {
+		return Item{}, "", err
+	}
+	item, err := k.decrypt(ctx, key, ciphertext)
 	if err != nil {
+		return Item{}, "", err
+	}
+	return item, contentVersion(ciphertext), nil
+}
+
+// SetIfAbsentContext stores item unless the store has an entry for its key.
+// passage cannot insert conditionally, so the store is checked under the lock
+// just before running it.
+func (k *passageKeyring) SetIfAbsentContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSetIfAbsent, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	return nil
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if k.itemExists(item.Key) {
+		return ErrConflict
+	}
+	return k.set(ctx, item)
+}
+
+// CompareAndSwapContext stores item under key if the entry's encrypted file
+// still has expectedVersion, checked under the lock just before running
+// passage.
+func (k *passageKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) (err error) {
+	defer k.log.op(ctx, OpCompareAndSwap, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	version, err := fileVersion(k.itemFile(key))
+	if errors.Is(err, ErrKeyNotFound) || (err == nil && version != expectedVersion) {
+		return ErrConflict
+	} else if err != nil {
+		return err
+	}
+	item.Key = key
+	return k.set(ctx, item)
 }
 
 func (k *passageKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *passageKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	if !k.itemExists(key) {
 		return ErrKeyNotFound
 	}
 
 	name := filepath.Join(k.prefix, key)
-	cmd := k.pass("rm", "-f", name)
-	err := cmd.Run()
-	if err != nil {
+	if _, err := runCommand(ctx, k.pass(ctx, "rm", "-f", name), passageError); err != nil {
 		return err
 	}
 
+	return k.sidecar().remove(ctx, key)
+}
+
+// passageError maps what age reports on a failed passage invocation onto the
+// package's sentinel errors.
+func passageError(stderr string) error {
+	stderr = strings.ToLower(stderr)
+	switch {
+	case strings.Contains(stderr, "incorrect passphrase"):
+		// The identity file is passphrase protected.
+		return ErrWrongPassphrase
+	case strings.Contains(stderr, "no identity matched any of the recipients"):
+		return ErrAccessDenied
+	default:
+		return nil
+	}
+}
+
+// probe checks that passage has an identities file to decrypt with.
+func (k *passageKeyring) probe() error {
+	identities := os.Getenv("PASSAGE_IDENTITIES_FILE")
+	if identities == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		identities = filepath.Join(homeDir, ".passage", "identities")
+	}
+	if _, err := os.Stat(identities); err != nil {
+		return fmt.Errorf("passage identities file is not usable: %w", err)
+	}
 	return nil
 }
 
 func (k *passageKeyring) itemExists(key string) bool {
-	var path = filepath.Join(k.dir, k.prefix, key+".age")
-	_, err := os.Stat(path)
+	_, err := os.Stat(k.itemFile(key))
 
 	return err == nil
 }
 
+// itemFile returns the path of the encrypted file for key.
+func (k *passageKeyring) itemFile(key string) string {
+	return filepath.Join(k.dir, k.prefix, key+".age")
+}
+
 func (k *passageKeyring) Keys() ([]string, error) {
-	var keys = []string{}
-	var path = filepath.Join(k.dir, k.prefix)
+	return k.KeysContext(context.Background())
+}
+
+func (k *passageKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	return walkStoreKeys(ctx, filepath.Join(k.dir, k.prefix), "", ".age")
+}
+
+// KeysWithPrefixContext lists the keys starting with prefix, walking only the
+// subdirectory that holds them.
+func (k *passageKeyring) KeysWithPrefixContext(ctx context.Context, prefix string) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeysWithPrefix, "")(&err)
+	return walkStoreKeys(ctx, filepath.Join(k.dir, k.prefix), prefix, ".age")
+}
 
-	info, err := os.Stat(path)
+// HistoryContext returns the earlier versions of the item for key from the
+// store's git history, newest first, or ErrHistoryNotSupported if the store
+// is not kept in git. Removed items keep their history.
+func (k *passageKeyring) HistoryContext(ctx context.Context, key string) (_ []Revision, err error) {
+	defer k.log.op(ctx, OpHistory, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	release, err := k.lock(ctx, false)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return keys, nil
-		}
-		return keys, err
+		return nil, err
 	}
-	if !info.IsDir() {
-		return keys, fmt.Errorf("%s is not a directory", path)
+	defer release()
+
+	return gitHistory(ctx, k.git, k.dir, k.itemFile(key))
+}
+
+// RestoreContext checks the item's encrypted file out of the commit version
+// and commits it, then updates the item's metadata, which needs the file
+// decrypted. It is decrypted without the lock, as in GetContext, and the
+// metadata is left alone if another writer has replaced the item meanwhile.
+func (k *passageKeyring) RestoreContext(ctx context.Context, key, version string) (err error) {
+	defer k.log.op(ctx, OpRestore, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ciphertext, err := k.restore(ctx, key, version)
+	if err != nil {
+		return err
+	}
+	item, err := k.decrypt(ctx, key, ciphertext)
+	if err != nil {
+		return err
 	}
 
-	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-		if !info.IsDir() && filepath.Ext(p) == ".age" {
-			name := strings.TrimPrefix(p, path)
-			if name[0] == os.PathSeparator {
-				name = name[1:]
-			}
-			keys = append(keys, name[:len(name)-4])
-		}
+	current, err := fileVersion(k.itemFile(key))
+	if errors.Is(err, ErrKeyNotFound) || (err == nil && current != contentVersion(ciphertext)) {
 		return nil
-	})
+	} else if err != nil {
+		return err
+	}
+	return k.sidecar().itemSet(ctx, item)
+}
+
+// restore checks the item's encrypted file out of the commit version, under
+// the exclusive lock, and returns it.
+func (k *passageKeyring) restore(ctx context.Context, key, version string) ([]byte, error) {
+	release, err := k.lock(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if err := gitRestore(ctx, k.git, k.dir, k.itemFile(key), version); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(k.itemFile(key))
+}
+
+// git runs git in the store with passage git and returns its output.
+func (k *passageKeyring) git(ctx context.Context, args ...string) ([]byte, error) {
+	return runCommand(ctx, k.pass(ctx, append([]string{"git"}, args...)...), gitStoreError(passageError))
+}
 
-	return keys, err
+// Watch reports changes to the items in the store as the files under it
+// change, including those made with passage itself.
+func (k *passageKeyring) Watch(ctx context.Context) (<-chan Event, error) {
+	return watchStore(ctx, k, filepath.Join(k.dir, k.prefix), ".age")
 }
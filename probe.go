@@ -0,0 +1,67 @@
+package keyring
+
+import (
+	"context"
+	"time"
+)
+
+// ProbeResult is the health of a single backend as reported by Probe.
+type ProbeResult struct {
+	Backend BackendType
+	// OK is true if nothing was found that would stop the backend working.
+	OK bool
+	// Latency is how long the probe took.
+	Latency time.Duration
+	// Err is the reason the backend is unusable, or nil if OK.
+	Err error
+}
+
+// prober checks that a backend is usable without storing anything and, where
+// possible, without prompting the user.
+type prober func(ctx context.Context, cfg Config) error
+
+// backendProbes holds a prober for each backend whose opener alone is not a
+// sufficient, or not a side-effect free, health check.
+var backendProbes = map[BackendType]prober{}
+
+// Probe checks each of cfg.AllowedBackends, or every backend in this build if
+// that is nil, and reports whether it is usable. No secrets are written.
+func Probe(cfg Config) []ProbeResult {
+	return ProbeContext(context.Background(), cfg)
+}
+
+// ProbeContext is Probe with a context bounding the network and D-Bus calls
+// made by the probes.
+func ProbeContext(ctx context.Context, cfg Config) []ProbeResult {
+	logger := cfg.logger()
+	results := []ProbeResult{}
+	for _, backend := range candidateBackends(cfg) {
+		start := time.Now()
+		err := probeBackend(ctx, backend, cfg)
+		if err != nil {
+			logger.Debug("Probe of backend failed", "backend", backend, "error", err)
+		}
+		results = append(results, ProbeResult{
+			Backend: backend,
+			OK:      err == nil,
+			Latency: time.Since(start),
+			Err:     err,
+		})
+	}
+	return results
+}
+
+func probeBackend(ctx context.Context, backend BackendType, cfg Config) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	opener, ok := supportedBackends[backend]
+	if !ok {
+		return unsupportedReason(backend)
+	}
+	if probe, ok := backendProbes[backend]; ok {
+		return probe(ctx, cfg)
+	}
+	_, err := opener(cfg)
+	return err
+}
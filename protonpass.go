@@ -6,9 +6,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"net"
 	"net/http"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,7 +39,9 @@ const (
 	protonCodeHumanVerification = 9001 // human-verification / CAPTCHA challenge required
 )
 
-// Errors returned by the Proton Pass backend.
+// Errors returned by the Proton Pass backend. The failures below also match
+// the package's generic errors, e.g. errors.Is(ErrProtonPassRateLimited,
+// ErrRateLimited) is true.
 var (
 	errProtonPassKeyring = errors.New("unable to create a Proton Pass keyring")
 	// errEnvUnsetOrEmpty is Proton-local so the backend does not depend on a
@@ -47,31 +52,44 @@ var (
 
 	// ErrProtonPassShareNotAccessible is returned when the configured Share ID is
 	// not among the shares the PAT can access (usually a missing access grant).
-	ErrProtonPassShareNotAccessible = errors.New("proton-pass backend: configured share id is not accessible to this PAT (grant it access)")
+	ErrProtonPassShareNotAccessible = newKindError(ErrAccessDenied, "proton-pass backend: configured share id is not accessible to this PAT (grant it access)")
 
 	// ErrProtonPassRateLimited wraps Proton's "too many recent logins" response.
 	// Session caching should keep operations well under the limit; hitting this
 	// usually means a burst of fresh logins, so wait a few minutes before retrying.
-	ErrProtonPassRateLimited = errors.New("proton-pass backend: rate limited by Proton (too many recent logins); wait a few minutes and retry")
+	ErrProtonPassRateLimited = newKindError(ErrRateLimited, "proton-pass backend: rate limited by Proton (too many recent logins); wait a few minutes and retry")
 
 	// ErrProtonPassSessionExpired is returned when a session is rejected as expired
 	// or revoked and re-exchanging the PAT did not recover it.
-	ErrProtonPassSessionExpired = errors.New("proton-pass backend: Proton session expired or revoked")
+	ErrProtonPassSessionExpired = newKindError(ErrAccessDenied, "proton-pass backend: Proton session expired or revoked")
 
 	// ErrProtonPassPATRejected is returned when Proton rejects the PAT itself
 	// (invalid, expired, or revoked); mint or re-grant a personal access token.
-	ErrProtonPassPATRejected = errors.New("proton-pass backend: personal access token rejected (invalid, expired, or revoked)")
+	ErrProtonPassPATRejected = newKindError(ErrAccessDenied, "proton-pass backend: personal access token rejected (invalid, expired, or revoked)")
 
 	// ErrProtonPassHumanVerification is returned when Proton demands human
 	// verification (CAPTCHA / 2FA), which this headless client cannot satisfy;
 	// re-authenticate with the Proton Pass app or CLI to clear it.
-	ErrProtonPassHumanVerification = errors.New("proton-pass backend: Proton requires human verification, which this client cannot satisfy; re-authenticate with the Proton Pass app or CLI")
+	ErrProtonPassHumanVerification = newKindError(ErrAccessDenied, "proton-pass backend: Proton requires human verification, which this client cannot satisfy; re-authenticate with the Proton Pass app or CLI")
 )
 
 func init() {
+	backendCapabilities[ProtonPassBackend] = Capabilities{
+		Metadata:           MetadataSupported,
+		ListRequiresUnlock: true,
+		Remote:             true,
+	}
+
 	supportedBackends[ProtonPassBackend] = opener(func(cfg Config) (Keyring, error) {
 		return NewProtonPassKeyring(&cfg)
 	})
+	backendProbes[ProtonPassBackend] = func(ctx context.Context, cfg Config) error {
+		k, err := NewProtonPassKeyring(&cfg)
+		if err != nil {
+			return err
+		}
+		return k.probe(ctx)
+	}
 }
 
 // ProtonPassKeyring implements the Keyring interface backed by Proton Pass over
@@ -88,6 +106,7 @@ type ProtonPassKeyring struct {
 	cache   protonSessionStore
 	timeout time.Duration
 	nowFunc func() time.Time // overridable in tests; nil means time.Now
+	log     callLogger
 }
 
 // NewProtonPassKeyring builds a Proton Pass keyring from config + environment.
@@ -120,19 +139,21 @@ func NewProtonPassKeyring(cfg *Config) (*ProtonPassKeyring, error) {
 		pat:             cfg.ProtonPassPAT,
 		tokenFunc:       cfg.ProtonPassTokenFunc,
 		apiBase:         apiBase,
-		cache:           newKeychainSessionStore(),
+		cache:           newKeychainSessionStore(cfg.logger()),
 		timeout:         cfg.ProtonPassTimeout,
+		log:             newCallLogger(*cfg, ProtonPassBackend),
 	}, nil
 }
 
-// opContext derives a per-operation context carrying the configured timeout, so
-// every Proton API call an operation makes shares one deadline.
-func (k ProtonPassKeyring) opContext() (context.Context, context.CancelFunc) {
+// opContext derives a per-operation context from parent carrying the configured
+// timeout, so every Proton API call an operation makes shares one deadline and
+// is aborted when the caller cancels parent.
+func (k ProtonPassKeyring) opContext(parent context.Context) (context.Context, context.CancelFunc) {
 	timeout := k.timeout
 	if timeout <= 0 {
 		timeout = protonPassDefaultTimeout
 	}
-	return context.WithTimeout(context.Background(), timeout)
+	return context.WithTimeout(parent, timeout)
 }
 
 // now returns the current time, allowing tests to control session-cache aging.
@@ -211,7 +232,8 @@ func isSessionExpired(err error) bool {
 }
 
 // classifyProtonErr maps a raw Proton API error to an actionable backend sentinel,
-// keeping the original error unwrappable for diagnostics. Non-API errors (e.g.
+// keeping the original error unwrappable for diagnostics. Network failures and
+// 5xx responses become ErrBackendUnavailable; other non-API errors (e.g.
 // ErrProtonPassShareNotAccessible, ErrKeyNotFound) pass through unchanged.
 func classifyProtonErr(err error) error {
 	if err == nil {
@@ -219,6 +241,10 @@ func classifyProtonErr(err error) error {
 	}
 	var apiErr *protonpass.APIError
 	if !errors.As(err, &apiErr) {
+		var netErr net.Error
+		if errors.As(err, &netErr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			return classify(ErrBackendUnavailable, err)
+		}
 		return err
 	}
 	switch {
@@ -232,6 +258,11 @@ func classifyProtonErr(err error) error {
 		return fmt.Errorf("%w: %w", ErrProtonPassPATRejected, err)
 	case isUnauthorized(apiErr):
 		return fmt.Errorf("%w: %w", ErrProtonPassSessionExpired, err)
+	case apiErr.Status == http.StatusConflict:
+		// The item's revision moved on since LastRevision was read.
+		return classify(ErrConflict, err)
+	case apiErr.Status >= http.StatusInternalServerError:
+		return classify(ErrBackendUnavailable, err)
 	default:
 		return err
 	}
@@ -243,6 +274,11 @@ func classifyProtonErr(err error) error {
 type decryptedItem struct {
 	key         string
 	note        string
+	fields      map[string]string // text extra fields, set with SetMetadata
+	attributes  map[string]string // text extra fields named with protonPassAttributePrefix
+	expires     time.Time
+	created     time.Time
+	modified    time.Time
 	itemID      string
 	revision    int
 	keyRotation int
@@ -276,6 +312,45 @@ func (k ProtonPassKeyring) openVault(ctx context.Context, session *protonpass.Se
 	return vaultKeys, nil
 }
 
+// probe authenticates and checks that the configured share is accessible and
+// its keys decrypt, without listing any items. It never prompts: a PAT that is
+// only available from the token function is not checked.
+func (k ProtonPassKeyring) probe(ctx context.Context) error {
+	pat := k.pat
+	if pat == "" {
+		pat = os.Getenv(ProtonPassEnvPAT)
+	}
+	if pat == "" {
+		if k.tokenFunc != nil {
+			return nil
+		}
+		return ErrProtonPassNoPAT
+	}
+	encKey, err := protonpass.PATKey(pat)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+
+	open := func(forceFresh bool) error {
+		session, err := k.authSession(ctx, pat, forceFresh)
+		if err != nil {
+			return err
+		}
+		vaultKeys, err := k.openVault(ctx, session, encKey)
+		zeroVaultKeys(vaultKeys)
+		return err
+	}
+	err = open(false)
+	if err != nil && k.cache != nil && isSessionExpired(err) {
+		err = open(true)
+	}
+	return classifyProtonErr(err)
+}
+
 // loadVaultOnce authenticates (cache-aware), decrypts the share keys, then lists
 // and decrypts every aws-vault item into its key, note, and the identifiers +
 // content key the write path needs. Items whose title lacks this keyring's
@@ -325,9 +400,15 @@ func (k ProtonPassKeyring) loadVaultOnce(ctx context.Context, pat string, encKey
 		if !ok {
 			continue // not an aws-vault item
 		}
+		fields, attrs, expires := splitProtonPassFields(meta.Fields)
 		items = append(items, decryptedItem{
 			key:         key,
 			note:        meta.Note,
+			fields:      fields,
+			attributes:  attrs,
+			expires:     expires,
+			created:     time.Unix(rev.CreateTime, 0),
+			modified:    time.Unix(rev.ModifyTime, 0),
 			itemID:      rev.ItemID,
 			revision:    rev.Revision,
 			keyRotation: rev.KeyRotation,
@@ -399,6 +480,51 @@ func (k ProtonPassKeyring) itemTitle(key string) string {
 	return k.ItemTitlePrefix + "/" + key
 }
 
+// Names of the text extra fields that hold an item's Attributes and expiry,
+// to tell them apart from the metadata fields set with SetMetadata.
+const (
+	protonPassAttributePrefix = "keyring-attribute:"
+	protonPassExpiresField    = "keyring-expires"
+)
+
+// splitProtonPassFields splits an item's text extra fields into its metadata
+// fields, its attributes and its expiry.
+func splitProtonPassFields(extra map[string]string) (fields, attrs map[string]string, expires time.Time) {
+	for name, value := range extra {
+		if name == protonPassExpiresField {
+			expires, _ = time.Parse(time.RFC3339Nano, value)
+			continue
+		}
+		if attr, ok := strings.CutPrefix(name, protonPassAttributePrefix); ok {
+			if attrs == nil {
+				attrs = map[string]string{}
+			}
+			attrs[attr] = value
+			continue
+		}
+		if fields == nil {
+			fields = map[string]string{}
+		}
+		fields[name] = value
+	}
+	return fields, attrs, expires
+}
+
+// joinProtonPassFields is the inverse of splitProtonPassFields.
+func joinProtonPassFields(fields, attrs map[string]string, expires time.Time) map[string]string {
+	extra := maps.Clone(fields)
+	if extra == nil && (len(attrs) > 0 || !expires.IsZero()) {
+		extra = map[string]string{}
+	}
+	for name, value := range attrs {
+		extra[protonPassAttributePrefix+name] = value
+	}
+	if !expires.IsZero() {
+		extra[protonPassExpiresField] = expires.UTC().Format(time.RFC3339Nano)
+	}
+	return extra
+}
+
 // keyFromTitle is the inverse of itemTitle: it strips the namespace prefix,
 // reporting false for titles that do not belong to this keyring.
 func (k ProtonPassKeyring) keyFromTitle(title string) (string, bool) {
@@ -411,13 +537,23 @@ func (k ProtonPassKeyring) keyFromTitle(title string) (string, bool) {
 // Keys lists the aws-vault item keys in the configured vault. Titles live inside
 // the encrypted item content, so this fetches and decrypts every item.
 func (k ProtonPassKeyring) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+// KeysContext lists the aws-vault item keys in the configured vault.
+func (k ProtonPassKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	pat, encKey, err := k.patAndKey()
 	if err != nil {
 		return nil, err
 	}
 	defer zeroBytes(encKey)
 
-	ctx, cancel := k.opContext()
+	ctx, cancel := k.opContext(ctx)
 	defer cancel()
 
 	var keys []string
@@ -442,13 +578,23 @@ func (k ProtonPassKeyring) Keys() ([]string, error) {
 
 // Get returns the Item for key, decrypting its stored blob, or ErrKeyNotFound.
 func (k ProtonPassKeyring) Get(key string) (Item, error) {
+	return k.GetContext(context.Background(), key)
+}
+
+// GetContext returns the Item for key, decrypting its stored blob, or ErrKeyNotFound.
+func (k ProtonPassKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
 	pat, encKey, err := k.patAndKey()
 	if err != nil {
 		return Item{}, err
 	}
 	defer zeroBytes(encKey)
 
-	ctx, cancel := k.opContext()
+	ctx, cancel := k.opContext(ctx)
 	defer cancel()
 
 	var found bool
@@ -456,7 +602,7 @@ func (k ProtonPassKeyring) Get(key string) (Item, error) {
 	err = k.withVault(ctx, pat, encKey, func(_ *protonpass.Session, _ map[int][]byte, items []decryptedItem) error {
 		for _, it := range items {
 			if it.key == key {
-				out, found = Item{Key: key, Data: []byte(it.note)}, true
+				out, found = Item{Key: key, Data: []byte(it.note), Attributes: it.attributes, Expires: it.expires}, true
 				return nil
 			}
 		}
@@ -468,12 +614,94 @@ func (k ProtonPassKeyring) Get(key string) (Item, error) {
 	if !found {
 		return Item{}, ErrKeyNotFound
 	}
-	return out, nil
+	return unexpired(out)
+}
+
+// GetMetadata returns the item's metadata fields, kept as text extra fields,
+// and the times Proton Pass records for it. Titles and fields are encrypted
+// like the note, so this needs the PAT just as Get does: without one it
+// returns an error matching ErrMetadataNeedsCredentials.
+func (k ProtonPassKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+// GetMetadataContext returns the item's metadata fields and timestamps.
+func (k ProtonPassKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return Metadata{}, classify(ErrMetadataNeedsCredentials, err)
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+
+	var found bool
+	var md Metadata
+	err = k.withVault(ctx, pat, encKey, func(_ *protonpass.Session, _ map[int][]byte, items []decryptedItem) error {
+		for _, it := range items {
+			if it.key == key {
+				md = Metadata{
+					Item:             &Item{Key: key, Attributes: it.attributes, Expires: it.expires},
+					ModificationTime: it.modified,
+					CreationTime:     it.created,
+					Fields:           it.fields,
+				}
+				found = true
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Metadata{}, classifyProtonErr(err)
+	}
+	if !found {
+		return Metadata{}, ErrKeyNotFound
+	}
+	return md, nil
+}
+
+// SetMetadata replaces the item's text extra fields with fields.
+func (k ProtonPassKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
 }
 
-// GetMetadata reports that Proton requires credentials even for metadata.
-func (k ProtonPassKeyring) GetMetadata(_ string) (Metadata, error) {
-	return Metadata{}, ErrMetadataNeedsCredentials
+// SetMetadataContext replaces the item's text extra fields with fields.
+func (k ProtonPassKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+	return classifyProtonErr(k.withVault(ctx, pat, encKey, func(session *protonpass.Session, _ map[int][]byte, items []decryptedItem) error {
+		existing, ok, err := findItem(items, key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrKeyNotFound
+		}
+		return k.updateItem(ctx, session, existing,
+			protonpass.ItemMetadata{
+				Name:   k.itemTitle(key),
+				Note:   existing.note,
+				Fields: joinProtonPassFields(fields, existing.attributes, existing.expires),
+			})
+	}))
 }
 
 // Set creates or updates the aws-vault item for item.Key. The blob (item.Data) is
@@ -481,13 +709,23 @@ func (k ProtonPassKeyring) GetMetadata(_ string) (Metadata, error) {
 // with the same key is updated in place (re-encrypted under its current per-item
 // key); otherwise a new item is created under the current share-key rotation.
 func (k ProtonPassKeyring) Set(item Item) error {
+	return k.SetContext(context.Background(), item)
+}
+
+// SetContext creates or updates the aws-vault item for item.Key.
+func (k ProtonPassKeyring) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	pat, encKey, err := k.patAndKey()
 	if err != nil {
 		return err
 	}
 	defer zeroBytes(encKey)
 
-	ctx, cancel := k.opContext()
+	ctx, cancel := k.opContext(ctx)
 	defer cancel()
 	return classifyProtonErr(k.withVault(ctx, pat, encKey, func(session *protonpass.Session, vaultKeys map[int][]byte, items []decryptedItem) error {
 		return k.setItem(ctx, session, vaultKeys, items, item)
@@ -496,29 +734,24 @@ func (k ProtonPassKeyring) Set(item Item) error {
 
 // setItem performs the create-or-update against an already-loaded vault.
 func (k ProtonPassKeyring) setItem(ctx context.Context, session *protonpass.Session, vaultKeys map[int][]byte, items []decryptedItem, item Item) error {
-	uuid, err := protonpass.NewItemUUID()
-	if err != nil {
-		return err
-	}
-	plaintext := protonpass.EncodeItem(
-		protonpass.ItemMetadata{Name: k.itemTitle(item.Key), Note: string(item.Data)}, uuid)
+	meta := protonpass.ItemMetadata{Name: k.itemTitle(item.Key), Note: string(item.Data)}
 
 	existing, ok, err := findItem(items, item.Key)
 	if err != nil {
 		return err
 	}
 	if ok {
-		content, err := protonpass.SealItemContent(existing.contentKey, plaintext)
-		if err != nil {
-			return err
-		}
-		_, err = k.Client.UpdateItem(ctx, session, k.ShareID, existing.itemID, protonpass.UpdateItemRequest{
-			KeyRotation:  existing.keyRotation,
-			LastRevision: existing.revision,
-			Content:      content,
-		})
+		// Keep the metadata fields of the item being replaced.
+		meta.Fields = joinProtonPassFields(existing.fields, item.Attributes, item.Expires)
+		return k.updateItem(ctx, session, existing, meta)
+	}
+	meta.Fields = joinProtonPassFields(nil, item.Attributes, item.Expires)
+
+	uuid, err := protonpass.NewItemUUID()
+	if err != nil {
 		return err
 	}
+	plaintext := protonpass.EncodeItem(meta, uuid)
 
 	rotation, shareKey, ok := currentRotation(vaultKeys)
 	if !ok {
@@ -545,16 +778,45 @@ func (k ProtonPassKeyring) setItem(ctx context.Context, session *protonpass.Sess
 	return err
 }
 
+// updateItem replaces the content of an existing item with meta, re-encrypted
+// under the item's current key.
+func (k ProtonPassKeyring) updateItem(ctx context.Context, session *protonpass.Session, existing decryptedItem, meta protonpass.ItemMetadata) error {
+	uuid, err := protonpass.NewItemUUID()
+	if err != nil {
+		return err
+	}
+	content, err := protonpass.SealItemContent(existing.contentKey, protonpass.EncodeItem(meta, uuid))
+	if err != nil {
+		return err
+	}
+	_, err = k.Client.UpdateItem(ctx, session, k.ShareID, existing.itemID, protonpass.UpdateItemRequest{
+		KeyRotation:  existing.keyRotation,
+		LastRevision: existing.revision,
+		Content:      content,
+	})
+	return err
+}
+
 // Remove permanently deletes the item with the matching key, or returns
 // ErrKeyNotFound if no aws-vault item carries that key.
 func (k ProtonPassKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext permanently deletes the item with the matching key.
+func (k ProtonPassKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	pat, encKey, err := k.patAndKey()
 	if err != nil {
 		return err
 	}
 	defer zeroBytes(encKey)
 
-	ctx, cancel := k.opContext()
+	ctx, cancel := k.opContext(ctx)
 	defer cancel()
 	return classifyProtonErr(k.withVault(ctx, pat, encKey, func(session *protonpass.Session, _ map[int][]byte, items []decryptedItem) error {
 		existing, ok, err := findItem(items, key)
@@ -568,13 +830,330 @@ func (k ProtonPassKeyring) Remove(key string) error {
 	}))
 }
 
-// zeroBytes overwrites b with zeros. Best-effort: Go's GC may already have copied
-// the bytes elsewhere, but clearing the live copy shrinks the window in which
-// derived key material sits in process memory.
-func zeroBytes(b []byte) {
-	for i := range b {
-		b[i] = 0
+// GetVersionedContext returns the Item for key and its Proton Pass revision.
+func (k ProtonPassKeyring) GetVersionedContext(ctx context.Context, key string) (_ Item, _ string, err error) {
+	defer k.log.op(ctx, OpGetVersioned, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, "", err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return Item{}, "", err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+
+	var out Item
+	var revision int
+	err = k.withVault(ctx, pat, encKey, func(_ *protonpass.Session, _ map[int][]byte, items []decryptedItem) error {
+		existing, ok, err := findItem(items, key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrKeyNotFound
+		}
+		out = Item{Key: key, Data: []byte(existing.note), Attributes: existing.attributes, Expires: existing.expires}
+		revision = existing.revision
+		return nil
+	})
+	if err != nil {
+		return Item{}, "", classifyProtonErr(err)
+	}
+	out, err = unexpired(out)
+	return out, strconv.Itoa(revision), err
+}
+
+// SetIfAbsentContext creates an item for item.Key unless the vault already
+// holds one. Proton Pass does not keep titles unique, so two writers creating
+// the same key at the same moment can both succeed.
+func (k ProtonPassKeyring) SetIfAbsentContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSetIfAbsent, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+	return classifyProtonErr(k.withVault(ctx, pat, encKey, func(session *protonpass.Session, vaultKeys map[int][]byte, items []decryptedItem) error {
+		_, ok, err := findItem(items, item.Key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return ErrConflict
+		}
+		return k.setItem(ctx, session, vaultKeys, items, item)
+	}))
+}
+
+// CompareAndSwapContext updates the item for key if it is still at the
+// revision expectedVersion. The update names that revision as its
+// LastRevision, so Proton Pass rejects it if another writer got in first.
+func (k ProtonPassKeyring) CompareAndSwapContext(ctx context.Context, key, expectedVersion string, item Item) (err error) {
+	defer k.log.op(ctx, OpCompareAndSwap, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+	return classifyProtonErr(k.withVault(ctx, pat, encKey, func(session *protonpass.Session, _ map[int][]byte, items []decryptedItem) error {
+		existing, ok, err := findItem(items, key)
+		if err != nil {
+			return err
+		}
+		if !ok || strconv.Itoa(existing.revision) != expectedVersion {
+			return ErrConflict
+		}
+		meta := protonpass.ItemMetadata{
+			Name:   k.itemTitle(key),
+			Note:   string(item.Data),
+			Fields: joinProtonPassFields(existing.fields, item.Attributes, item.Expires),
+		}
+		return k.updateItem(ctx, session, existing, meta)
+	}))
+}
+
+// HistoryContext returns the earlier revisions Proton Pass keeps of the item
+// for key, newest first. Items are removed outright, so their history goes
+// with them.
+func (k ProtonPassKeyring) HistoryContext(ctx context.Context, key string) (_ []Revision, err error) {
+	defer k.log.op(ctx, OpHistory, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+
+	var revisions []Revision
+	err = k.withVault(ctx, pat, encKey, func(session *protonpass.Session, _ map[int][]byte, items []decryptedItem) error {
+		existing, ok, err := findItem(items, key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrKeyNotFound
+		}
+		revs, err := k.Client.ListItemRevisions(ctx, session, k.ShareID, existing.itemID)
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(revs, func(a, b protonpass.ItemRevision) int { return b.Revision - a.Revision })
+
+		revisions = []Revision{}
+		for _, rev := range revs {
+			if rev.Revision >= existing.revision {
+				continue // the current revision
+			}
+			r := Revision{Version: strconv.Itoa(rev.Revision)}
+			if rev.RevisionTime != 0 {
+				r.Modified = time.Unix(rev.RevisionTime, 0)
+			}
+			revisions = append(revisions, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, classifyProtonErr(err)
+	}
+	return revisions, nil
+}
+
+// RestoreContext decrypts an earlier revision of the item for key and stores
+// its note and fields as a new revision.
+func (k ProtonPassKeyring) RestoreContext(ctx context.Context, key, version string) (err error) {
+	defer k.log.op(ctx, OpRestore, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+	return classifyProtonErr(k.withVault(ctx, pat, encKey, func(session *protonpass.Session, vaultKeys map[int][]byte, items []decryptedItem) error {
+		existing, ok, err := findItem(items, key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrKeyNotFound
+		}
+		revs, err := k.Client.ListItemRevisions(ctx, session, k.ShareID, existing.itemID)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(revs, func(rev protonpass.ItemRevision) bool {
+			return strconv.Itoa(rev.Revision) == version && rev.Revision < existing.revision
+		})
+		if i < 0 {
+			return ErrRevisionNotFound
+		}
+
+		shareKey, ok := vaultKeys[revs[i].KeyRotation]
+		if !ok {
+			return fmt.Errorf("proton-pass backend: revision %s of %q is encrypted under a share key rotation we did not fetch", version, key)
+		}
+		content, contentKey, err := k.openContent(shareKey, revs[i])
+		if err != nil {
+			return fmt.Errorf("revision %s: %w", version, err)
+		}
+		if revs[i].ItemKey != "" {
+			defer zeroBytes(contentKey) // not the share key, which zeroVaultKeys clears
+		}
+		meta, err := protonpass.ParseItemMetadata(content)
+		if err != nil {
+			return fmt.Errorf("revision %s: parse: %w", version, err)
+		}
+		meta.Name = k.itemTitle(key)
+		return k.updateItem(ctx, session, existing, meta)
+	}))
+}
+
+// GetManyContext returns the Items for keys, decrypting the vault once.
+func (k ProtonPassKeyring) GetManyContext(ctx context.Context, keys []string) (_ map[string]Item, err error) {
+	defer k.log.op(ctx, OpGetMany, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+
+	out := make(map[string]Item, len(keys))
+	errs := map[string]error{}
+	err = k.withVault(ctx, pat, encKey, func(_ *protonpass.Session, _ map[int][]byte, items []decryptedItem) error {
+		byKey := make(map[string]decryptedItem, len(items))
+		for _, it := range slices.Backward(items) {
+			byKey[it.key] = it // the first item with a key wins, as in Get
+		}
+		for _, key := range keys {
+			it, ok := byKey[key]
+			if !ok {
+				errs[key] = ErrKeyNotFound
+				continue
+			}
+			item, err := unexpired(Item{Key: key, Data: []byte(it.note), Attributes: it.attributes, Expires: it.expires})
+			if err != nil {
+				errs[key] = err
+				continue
+			}
+			out[key] = item
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, classifyProtonErr(err)
+	}
+	return out, batchError(errs)
+}
+
+// SetManyContext creates or updates the aws-vault items for items, decrypting
+// the vault once.
+func (k ProtonPassKeyring) SetManyContext(ctx context.Context, items []Item) (err error) {
+	defer k.log.op(ctx, OpSetMany, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+	return classifyProtonBatchErr(k.withVault(ctx, pat, encKey, func(session *protonpass.Session, vaultKeys map[int][]byte, existing []decryptedItem) error {
+		errs := map[string]error{}
+		for _, item := range lastByKey(items) {
+			if err := k.setItem(ctx, session, vaultKeys, existing, item); err != nil {
+				errs[item.Key] = err
+			}
+		}
+		return batchError(errs)
+	}))
+}
+
+// RemoveManyContext permanently deletes the items matching keys, decrypting
+// the vault once.
+func (k ProtonPassKeyring) RemoveManyContext(ctx context.Context, keys []string) (err error) {
+	defer k.log.op(ctx, OpRemoveMany, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pat, encKey, err := k.patAndKey()
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(encKey)
+
+	ctx, cancel := k.opContext(ctx)
+	defer cancel()
+	return classifyProtonBatchErr(k.withVault(ctx, pat, encKey, func(session *protonpass.Session, _ map[int][]byte, items []decryptedItem) error {
+		errs := map[string]error{}
+		for _, key := range uniqueKeys(keys) {
+			existing, ok, err := findItem(items, key)
+			switch {
+			case err != nil:
+				errs[key] = err
+			case !ok:
+				errs[key] = ErrKeyNotFound
+			default:
+				if err := k.Client.DeleteItem(ctx, session, k.ShareID, existing.itemID, existing.revision); err != nil {
+					errs[key] = err
+				}
+			}
+		}
+		return batchError(errs)
+	}))
+}
+
+// classifyProtonBatchErr is classifyProtonErr for the errors of a batch,
+// classifying each key's error in a *BatchError.
+func classifyProtonBatchErr(err error) error {
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		return classifyProtonErr(err)
+	}
+	for key, keyErr := range batchErr.Errors {
+		batchErr.Errors[key] = classifyProtonErr(keyErr)
 	}
+	return batchErr
 }
 
 // zeroVaultKeys clears every decrypted share key in m.
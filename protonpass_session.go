@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"log/slog"
 	"time"
 
 	"github.com/byteness/keyring/internal/protonpass"
@@ -107,7 +108,8 @@ func (cs cachedSession) fresh(now time.Time) bool {
 // keyringSessionStore stores the cached session as a JSON item in an underlying
 // (OS-protected) keyring.
 type keyringSessionStore struct {
-	kr Keyring
+	kr     Keyring
+	logger *slog.Logger
 }
 
 func (s *keyringSessionStore) load(account string) (cachedSession, bool) {
@@ -117,7 +119,7 @@ func (s *keyringSessionStore) load(account string) (cachedSession, bool) {
 	}
 	var cs cachedSession
 	if err := json.Unmarshal(item.Data, &cs); err != nil {
-		debugf("proton-pass: discarding unreadable session cache entry: %v", err)
+		loggerOr(s.logger).Debug("proton-pass: discarding unreadable session cache entry", "error", err)
 		return cachedSession{}, false
 	}
 	return cs, true
@@ -163,16 +165,16 @@ func (s *keyringSessionStore) invalidate(account string) {
 // session. It returns nil when no secure backend is available (for example a
 // headless host with no Secret Service): the backend then re-exchanges the PAT on
 // every operation, so calls in quick succession may hit Proton's login rate limit.
-func newKeychainSessionStore() protonSessionStore {
+func newKeychainSessionStore(logger *slog.Logger) protonSessionStore {
 	kr, err := Open(Config{
 		ServiceName:     protonSessionServiceName,
 		AllowedBackends: protonSecureSessionBackends,
 	})
 	if err != nil {
-		debugf("proton-pass: no secure backend for session cache (%v); each operation will re-exchange the PAT", err)
+		loggerOr(logger).Debug("proton-pass: no secure backend for session cache; each operation will re-exchange the PAT", "error", err)
 		return nil
 	}
-	return &keyringSessionStore{kr: kr}
+	return &keyringSessionStore{kr: kr, logger: logger}
 }
 
 // protonSessionAccount derives a stable, non-secret keychain account id from the
@@ -107,13 +107,15 @@ func TestClassifyProtonErr(t *testing.T) {
 		name   string
 		apiErr *protonpass.APIError
 		want   error
+		kind   error
 	}{
-		{"rate limit by HTTP 429", &protonpass.APIError{Status: 429}, ErrProtonPassRateLimited},
-		{"rate limit by code 2028", &protonpass.APIError{Status: 200, Code: 2028}, ErrProtonPassRateLimited},
-		{"human verification code 9001", &protonpass.APIError{Status: 422, Code: 9001}, ErrProtonPassHumanVerification},
-		{"session expired HTTP 401", &protonpass.APIError{Status: 401}, ErrProtonPassSessionExpired},
-		{"pat rejected by message", &protonpass.APIError{Status: 400, Message: "Invalid or expired personal access token"}, ErrProtonPassPATRejected},
-		{"401 carrying a PAT message is pat-rejected", &protonpass.APIError{Status: 401, Message: "Invalid or expired personal access token"}, ErrProtonPassPATRejected},
+		{"rate limit by HTTP 429", &protonpass.APIError{Status: 429}, ErrProtonPassRateLimited, ErrRateLimited},
+		{"rate limit by code 2028", &protonpass.APIError{Status: 200, Code: 2028}, ErrProtonPassRateLimited, ErrRateLimited},
+		{"human verification code 9001", &protonpass.APIError{Status: 422, Code: 9001}, ErrProtonPassHumanVerification, ErrAccessDenied},
+		{"session expired HTTP 401", &protonpass.APIError{Status: 401}, ErrProtonPassSessionExpired, ErrAccessDenied},
+		{"pat rejected by message", &protonpass.APIError{Status: 400, Message: "Invalid or expired personal access token"}, ErrProtonPassPATRejected, ErrAccessDenied},
+		{"401 carrying a PAT message is pat-rejected", &protonpass.APIError{Status: 401, Message: "Invalid or expired personal access token"}, ErrProtonPassPATRejected, ErrAccessDenied},
+		{"server error HTTP 503", &protonpass.APIError{Status: 503}, ErrBackendUnavailable, ErrBackendUnavailable},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -121,6 +123,9 @@ func TestClassifyProtonErr(t *testing.T) {
 			if !errors.Is(got, tt.want) {
 				t.Errorf("classifyProtonErr = %v, want it to wrap %v", got, tt.want)
 			}
+			if !errors.Is(got, tt.kind) {
+				t.Errorf("classifyProtonErr = %v, want it to match %v", got, tt.kind)
+			}
 			var ae *protonpass.APIError
 			if !errors.As(got, &ae) {
 				t.Error("classified error must still unwrap to the original APIError")
@@ -151,7 +156,7 @@ func TestProtonPassRateLimitSurfaced(t *testing.T) {
 
 func TestProtonPassOpContextDeadline(t *testing.T) {
 	k := ProtonPassKeyring{timeout: 5 * time.Second}
-	ctx, cancel := k.opContext()
+	ctx, cancel := k.opContext(context.Background())
 	defer cancel()
 	dl, ok := ctx.Deadline()
 	if !ok {
@@ -162,7 +167,7 @@ func TestProtonPassOpContextDeadline(t *testing.T) {
 	}
 
 	// A zero timeout falls back to the built-in default.
-	dctx, dcancel := ProtonPassKeyring{}.opContext()
+	dctx, dcancel := ProtonPassKeyring{}.opContext(context.Background())
 	defer dcancel()
 	dl2, ok := dctx.Deadline()
 	if !ok || time.Until(dl2) <= 5*time.Second {
@@ -242,6 +247,22 @@ func TestProtonPassTimeoutCancels(t *testing.T) {
 	}
 }
 
+func TestProtonPassContextCancels(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	m := mockProtonAPI{
+		auth: func(ctx context.Context, _ string) (*protonpass.Session, error) {
+			cancel() // the caller gives up while the exchange is in flight
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	k := ProtonPassKeyring{Client: m, ShareID: "target", pat: "pst_x::AAAA", timeout: time.Minute}
+
+	if _, err := k.KeysContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("KeysContext err = %v, want context.Canceled", err)
+	}
+}
+
 func TestProtonSessionAccount(t *testing.T) {
 	a := protonSessionAccount("https://api", "pst_one::k")
 	if a != protonSessionAccount("https://api", "pst_one::different-crypto-half") {
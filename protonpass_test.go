@@ -7,8 +7,11 @@ import (
 	"context"
 	"encoding/base64"
 	"errors"
+	"maps"
+	"net/http"
 	"slices"
 	"testing"
+	"time"
 
 	"google.golang.org/protobuf/encoding/protowire"
 
@@ -20,6 +23,7 @@ type mockProtonAPI struct {
 	auth      func(ctx context.Context, pat string) (*protonpass.Session, error)
 	shares    func(ctx context.Context, s *protonpass.Session) ([]protonpass.Share, error)
 	items     func(ctx context.Context, s *protonpass.Session, shareID string) ([]protonpass.ItemRevision, error)
+	revisions func(ctx context.Context, s *protonpass.Session, shareID, itemID string) ([]protonpass.ItemRevision, error)
 	shareKeys func(ctx context.Context, s *protonpass.Session, shareID string) ([]protonpass.ShareKey, error)
 	create    func(ctx context.Context, s *protonpass.Session, shareID string, req protonpass.CreateItemRequest) (*protonpass.ItemRevision, error)
 	update    func(ctx context.Context, s *protonpass.Session, shareID, itemID string, req protonpass.UpdateItemRequest) (*protonpass.ItemRevision, error)
@@ -38,6 +42,13 @@ func (m mockProtonAPI) ListItems(ctx context.Context, s *protonpass.Session, sha
 	return m.items(ctx, s, shareID)
 }
 
+func (m mockProtonAPI) ListItemRevisions(ctx context.Context, s *protonpass.Session, shareID, itemID string) ([]protonpass.ItemRevision, error) {
+	if m.revisions != nil {
+		return m.revisions(ctx, s, shareID, itemID)
+	}
+	return nil, errors.New("ListItemRevisions not stubbed")
+}
+
 func (m mockProtonAPI) GetShareKeys(ctx context.Context, s *protonpass.Session, shareID string) ([]protonpass.ShareKey, error) {
 	if m.shareKeys != nil {
 		return m.shareKeys(ctx, s, shareID)
@@ -288,6 +299,26 @@ func TestProtonPassRemove(t *testing.T) {
 	}
 }
 
+func TestProtonPassRemoveMany(t *testing.T) {
+	fx := buildVaultFixture(t, map[string]string{"aws-vault/dev": "blob", "aws-vault/prod": "blob"})
+	m := readMock(fx)
+	var deleted []string
+	m.del = func(_ context.Context, _ *protonpass.Session, _ string, itemID string, _ int) error {
+		deleted = append(deleted, itemID)
+		return nil
+	}
+	k := ProtonPassKeyring{Client: *m, ShareID: "target", ItemTitlePrefix: "aws-vault", pat: fx.pat}
+
+	err := RemoveMany(k, []string{"dev", "missing", "prod", "dev"})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors["missing"], ErrKeyNotFound) {
+		t.Fatalf("RemoveMany err = %v, want ErrKeyNotFound for the missing key only", err)
+	}
+	if want := []string{"itemaws-vault/dev", "itemaws-vault/prod"}; !slices.Equal(deleted, want) {
+		t.Fatalf("deleted %v, want %v", deleted, want)
+	}
+}
+
 // vaultFixture is a fully-encrypted Proton Pass vault (one share-key rotation and a
 // set of items) so backend tests exercise the real symmetric decryption chain:
 // PAT enc-key -> share key -> item key -> content -> protobuf.
@@ -419,6 +450,26 @@ func TestProtonPassKeyringReadPath(t *testing.T) {
 	}
 }
 
+func TestProtonPassGetMany(t *testing.T) {
+	fx := buildVaultFixture(t, map[string]string{
+		"aws-vault/dev":  "dev-blob",
+		"aws-vault/prod": "prod-blob",
+	})
+	var calls []string
+	k := newFixtureKeyring(fx, ProtonPassDefaultItemTitlePrefix, &calls)
+
+	items, err := GetMany(k, []string{"dev", "prod", "missing"})
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("GetMany err = %v, want ErrKeyNotFound for the missing key", err)
+	}
+	if len(items) != 2 || string(items["dev"].Data) != "dev-blob" || string(items["prod"].Data) != "prod-blob" {
+		t.Fatalf("GetMany = %+v", items)
+	}
+	if n := slices.Index(calls, "items"); n < 0 || slices.Index(calls[n+1:], "items") >= 0 {
+		t.Fatalf("call order = %v, want the vault listed once", calls)
+	}
+}
+
 func TestProtonPassKeyringReadPathNoPrefix(t *testing.T) {
 	// With an empty prefix every item title is a key verbatim.
 	fx := buildVaultFixture(t, map[string]string{"raw-title": "blob"})
@@ -537,6 +588,46 @@ func TestProtonPassKeyringGetMetadata(t *testing.T) {
 	}
 }
 
+func TestProtonPassSetMetadata(t *testing.T) {
+	fx := buildVaultFixture(t, map[string]string{"aws-vault/dev": "blob"})
+	fx.revisions[0].CreateTime, fx.revisions[0].ModifyTime = 1700000000, 1700000100
+	m := readMock(fx)
+	var gotReq protonpass.UpdateItemRequest
+	m.update = func(_ context.Context, _ *protonpass.Session, _ string, _ string, req protonpass.UpdateItemRequest) (*protonpass.ItemRevision, error) {
+		gotReq = req
+		return &protonpass.ItemRevision{}, nil
+	}
+	k := ProtonPassKeyring{Client: *m, ShareID: "target", ItemTitlePrefix: "aws-vault", pat: fx.pat}
+
+	md, err := k.GetMetadata("dev")
+	if err != nil {
+		t.Fatalf("GetMetadata: %v", err)
+	}
+	if !md.CreationTime.Equal(time.Unix(1700000000, 0)) || !md.ModificationTime.Equal(time.Unix(1700000100, 0)) {
+		t.Fatalf("GetMetadata times = %v, %v", md.CreationTime, md.ModificationTime)
+	}
+
+	fields := map[string]string{"owner": "ops"}
+	if err := k.SetMetadata("dev", fields); err != nil {
+		t.Fatalf("SetMetadata: %v", err)
+	}
+	plain, err := protonpass.OpenItemContent(fixtureItemKey(1), gotReq.Content)
+	if err != nil {
+		t.Fatalf("open updated content: %v", err)
+	}
+	meta, err := protonpass.ParseItemMetadata(plain)
+	if err != nil {
+		t.Fatalf("parse updated item: %v", err)
+	}
+	if meta.Note != "blob" || !maps.Equal(meta.Fields, fields) {
+		t.Fatalf("updated item = %+v, want note kept and fields %v", meta, fields)
+	}
+
+	if err := k.SetMetadata("missing", fields); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("SetMetadata(missing) err = %v, want ErrKeyNotFound", err)
+	}
+}
+
 func TestFindItem(t *testing.T) {
 	items := []decryptedItem{
 		{key: "a", itemID: "1"},
@@ -557,3 +648,156 @@ func TestFindItem(t *testing.T) {
 		t.Fatalf("duplicate key: ok=%v err=%v, want ok=false err!=nil", ok, err)
 	}
 }
+
+func TestProtonPassProbe(t *testing.T) {
+	fx := buildVaultFixture(t, nil)
+	m := readMock(fx)
+	m.items = func(context.Context, *protonpass.Session, string) ([]protonpass.ItemRevision, error) {
+		t.Error("probe must not list items")
+		return nil, nil
+	}
+	k := ProtonPassKeyring{Client: *m, ShareID: "target", ItemTitlePrefix: "aws-vault", pat: fx.pat}
+	if err := k.probe(t.Context()); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+
+	k.ShareID = "elsewhere"
+	if err := k.probe(t.Context()); !errors.Is(err, ErrProtonPassShareNotAccessible) {
+		t.Fatalf("probe err = %v, want ErrProtonPassShareNotAccessible", err)
+	}
+
+	prompted := false
+	k = ProtonPassKeyring{Client: *m, ShareID: "target", tokenFunc: func(string) (string, error) {
+		prompted = true
+		return fx.pat, nil
+	}}
+	t.Setenv(ProtonPassEnvPAT, "")
+	if err := k.probe(t.Context()); err != nil || prompted {
+		t.Fatalf("probe err = %v, prompted = %v; want no error and no prompt", err, prompted)
+	}
+}
+
+func TestProtonPassFieldsRoundTrip(t *testing.T) {
+	fields := map[string]string{"owner": "ops"}
+	attrs := map[string]string{"env": "prod"}
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	extra := joinProtonPassFields(fields, attrs, expires)
+	if extra["keyring-attribute:env"] != "prod" || extra["owner"] != "ops" || extra["keyring-expires"] == "" {
+		t.Fatalf("joinProtonPassFields = %v", extra)
+	}
+	gotFields, gotAttrs, gotExpires := splitProtonPassFields(extra)
+	if !maps.Equal(gotFields, fields) || !maps.Equal(gotAttrs, attrs) || !gotExpires.Equal(expires) {
+		t.Fatalf("splitProtonPassFields = %v, %v, %v", gotFields, gotAttrs, gotExpires)
+	}
+	if extra := joinProtonPassFields(nil, nil, time.Time{}); extra != nil {
+		t.Fatalf("joinProtonPassFields(nil, nil, zero) = %v, want nil", extra)
+	}
+}
+
+func TestProtonPassCompareAndSwap(t *testing.T) {
+	fx := buildVaultFixture(t, map[string]string{"aws-vault/dev": "old-blob"})
+	m := readMock(fx)
+	var updates []protonpass.UpdateItemRequest
+	m.update = func(_ context.Context, _ *protonpass.Session, _ string, itemID string, req protonpass.UpdateItemRequest) (*protonpass.ItemRevision, error) {
+		updates = append(updates, req)
+		if len(updates) > 1 {
+			return nil, &protonpass.APIError{Status: http.StatusConflict, Message: "revision conflict"}
+		}
+		return &protonpass.ItemRevision{ItemID: itemID, Revision: req.LastRevision + 1}, nil
+	}
+	k := ProtonPassKeyring{Client: *m, ShareID: "target", ItemTitlePrefix: "aws-vault", pat: fx.pat}
+
+	item, version, err := k.GetVersionedContext(context.Background(), "dev")
+	if err != nil {
+		t.Fatalf("GetVersioned: %v", err)
+	}
+	if string(item.Data) != "old-blob" || version != "1" {
+		t.Fatalf("GetVersioned = %q at %q, want old-blob at 1", item.Data, version)
+	}
+
+	if err := CompareAndSwap(k, "dev", "0", Item{Data: []byte("stale")}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("CompareAndSwap at a stale version: got %v, want ErrConflict", err)
+	}
+	if len(updates) != 0 {
+		t.Fatalf("CompareAndSwap at a stale version sent %d updates", len(updates))
+	}
+
+	if err := CompareAndSwap(k, "dev", version, Item{Data: []byte("new-blob")}); err != nil {
+		t.Fatalf("CompareAndSwap: %v", err)
+	}
+	if len(updates) != 1 || updates[0].LastRevision != 1 {
+		t.Fatalf("updates = %+v, want one with LastRevision=1", updates)
+	}
+
+	// The service rejecting the update, because another writer got in after
+	// the vault was read, is a conflict too.
+	if err := CompareAndSwap(k, "dev", version, Item{Data: []byte("late")}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("CompareAndSwap rejected by the service: got %v, want ErrConflict", err)
+	}
+
+	m.create = func(context.Context, *protonpass.Session, string, protonpass.CreateItemRequest) (*protonpass.ItemRevision, error) {
+		t.Error("SetIfAbsent on an existing key must not create an item")
+		return nil, nil
+	}
+	k.Client = *m
+	if err := SetIfAbsent(k, Item{Key: "dev", Data: []byte("x")}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("SetIfAbsent on an existing key: got %v, want ErrConflict", err)
+	}
+}
+
+func TestProtonPassHistory(t *testing.T) {
+	old := buildVaultFixture(t, map[string]string{"aws-vault/dev": "old-blob"}).revisions[0]
+	old.RevisionTime = 1700000000
+	fx := buildVaultFixture(t, map[string]string{"aws-vault/dev": "new-blob"})
+	fx.revisions[0].Revision = 2
+
+	m := readMock(fx)
+	m.revisions = func(_ context.Context, _ *protonpass.Session, _ string, itemID string) ([]protonpass.ItemRevision, error) {
+		if itemID != fx.revisions[0].ItemID {
+			t.Errorf("revisions listed for item %q", itemID)
+		}
+		return []protonpass.ItemRevision{old, fx.revisions[0]}, nil
+	}
+	var updates []protonpass.UpdateItemRequest
+	m.update = func(_ context.Context, _ *protonpass.Session, _ string, itemID string, req protonpass.UpdateItemRequest) (*protonpass.ItemRevision, error) {
+		updates = append(updates, req)
+		return &protonpass.ItemRevision{ItemID: itemID, Revision: req.LastRevision + 1}, nil
+	}
+	k := ProtonPassKeyring{Client: *m, ShareID: "target", ItemTitlePrefix: "aws-vault", pat: fx.pat}
+
+	revisions, err := History(k, "dev")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	want := []Revision{{Version: "1", Modified: time.Unix(1700000000, 0)}}
+	if !slices.EqualFunc(revisions, want, func(a, b Revision) bool { return a.Version == b.Version && a.Modified.Equal(b.Modified) }) {
+		t.Fatalf("History = %+v, want %+v", revisions, want)
+	}
+
+	if err := Restore(k, "dev", "2"); !errors.Is(err, ErrRevisionNotFound) {
+		t.Fatalf("Restore to the current revision: got %v, want ErrRevisionNotFound", err)
+	}
+	if err := Restore(k, "dev", "1"); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(updates) != 1 || updates[0].LastRevision != 2 {
+		t.Fatalf("updates = %+v, want one with LastRevision=2", updates)
+	}
+	itemKey := bytes.Repeat([]byte{0x31}, 32) // the fixture's key for its first item
+	content, err := protonpass.OpenItemContent(itemKey, updates[0].Content)
+	if err != nil {
+		t.Fatalf("open restored content: %v", err)
+	}
+	meta, err := protonpass.ParseItemMetadata(content)
+	if err != nil {
+		t.Fatalf("parse restored content: %v", err)
+	}
+	if meta.Note != "old-blob" || meta.Name != "aws-vault/dev" {
+		t.Fatalf("restored item = %+v, want the old note under the item's title", meta)
+	}
+
+	if _, err := History(k, "missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("History of a missing key: got %v, want ErrKeyNotFound", err)
+	}
+}
@@ -0,0 +1,150 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// RotatedField is the metadata field in which Rotate records when it last
+// rotated an item, in RFC 3339 format.
+const RotatedField = "keyring-rotated"
+
+// DefaultBackupSuffix is appended to an item's key to make the key its old
+// value is kept under while Rotate verifies the new one.
+const DefaultBackupSuffix = ".rotate-backup"
+
+// RotateOptions control how Rotate replaces an item.
+type RotateOptions struct {
+	// BackupKey is the key the old item is kept under until the new one has
+	// been verified. It defaults to the item's key with DefaultBackupSuffix
+	// appended.
+	BackupKey string
+
+	// Verify checks that the new item works, for example by calling the
+	// service it is a credential for. If it returns an error the old item is
+	// restored. If it is nil the new item is taken to work once stored.
+	Verify func(ctx context.Context, item Item) error
+}
+
+// Rotate replaces the item for key in k with the one fn makes from it, such
+// as a freshly issued access key, and rolls back to the old item if any step
+// fails, so that a failed rotation never leaves the key without a
+// credential.
+//
+// The old item is first copied to the backup key. The new item is then
+// stored under key, with CompareAndSwap where k supports it so that a
+// concurrent change is not overwritten, and passed to opts.Verify. Once it is
+// verified the rotation time is recorded in the item's RotatedField, where k
+// supports metadata, and the backup is removed. If fn fails nothing has
+// changed; if storing or verifying the new item fails the old item is stored
+// again. Should that fail too, the backup is left in place and named in the
+// error, and Rotate refuses to rotate the item again until it is removed.
+func Rotate(ctx context.Context, k Keyring, key string, fn func(old Item) (Item, error), opts RotateOptions) error {
+	ck := AsContextKeyring(k)
+	backupKey := opts.BackupKey
+	if backupKey == "" {
+		backupKey = key + DefaultBackupSuffix
+	}
+
+	old, version, err := GetVersionedContext(ctx, k, key)
+	conditional := err == nil
+	if errors.Is(err, ErrConditionalNotSupported) {
+		old, err = ck.GetContext(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := ck.GetContext(ctx, backupKey); err == nil {
+		return fmt.Errorf("keyring: %q is left from an earlier rotation of %q; restore or remove it first", backupKey, key)
+	} else if !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	backup := old
+	backup.Key = backupKey
+	if err := ck.SetContext(ctx, backup); err != nil {
+		return fmt.Errorf("keyring: backing up %q: %w", key, err)
+	}
+
+	// Rolling back must not be cut short by the context that failed the
+	// rotation.
+	rollback := func(cause error) error {
+		rctx := context.WithoutCancel(ctx)
+		old.Key = key
+		if err := ck.SetContext(rctx, old); err != nil {
+			return errors.Join(cause, fmt.Errorf("keyring: rolling back %q failed, its old item is kept under %q: %w", key, backupKey, err))
+		}
+		if err := ck.RemoveContext(rctx, backupKey); err != nil {
+			return errors.Join(cause, fmt.Errorf("keyring: removing the backup %q: %w", backupKey, err))
+		}
+		return cause
+	}
+	removeBackup := func(cause error) error {
+		if err := ck.RemoveContext(context.WithoutCancel(ctx), backupKey); err != nil {
+			return errors.Join(cause, fmt.Errorf("keyring: removing the backup %q: %w", backupKey, err))
+		}
+		return cause
+	}
+
+	next, err := fn(old)
+	if err != nil {
+		return removeBackup(err)
+	}
+	next.Key = key
+	if conditional {
+		err = CompareAndSwapContext(ctx, k, key, version, next)
+	} else {
+		err = ck.SetContext(ctx, next)
+	}
+	if errors.Is(err, ErrConflict) {
+		// Another writer changed the item; rolling back would undo that.
+		return removeBackup(err)
+	} else if err != nil {
+		return rollback(fmt.Errorf("keyring: storing the new %q: %w", key, err))
+	}
+
+	if opts.Verify != nil {
+		if err := opts.Verify(ctx, next); err != nil {
+			return rollback(fmt.Errorf("keyring: verifying the new %q: %w", key, err))
+		}
+	}
+
+	// The rotation has taken effect, so failing to record it is not
+	// reported as a failure to rotate.
+	if err := recordRotation(ctx, k, key, time.Now()); err != nil && !errors.Is(err, ErrMetadataNotSupported) {
+		debugf("Recording the rotation of %q: %v", key, err)
+	}
+	return removeBackup(nil)
+}
+
+// recordRotation sets the RotatedField of the item for key to now, keeping
+// its other fields.
+func recordRotation(ctx context.Context, k Keyring, key string, now time.Time) error {
+	mk, ok := k.(MetadataKeyring)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	md, err := AsContextKeyring(k).GetMetadataContext(ctx, key)
+	if err != nil {
+		return err
+	}
+	fields := maps.Clone(md.Fields)
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	fields[RotatedField] = now.UTC().Format(time.RFC3339)
+	return mk.SetMetadataContext(ctx, key, fields)
+}
+
+// LastRotated returns when the item described by md was last rotated by
+// Rotate or, if it never was, last modified. It returns the zero time if the
+// backend records neither.
+func LastRotated(md Metadata) time.Time {
+	if t, err := time.Parse(time.RFC3339, md.Fields[RotatedField]); err == nil {
+		return t
+	}
+	return md.ModificationTime
+}
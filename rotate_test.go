@@ -0,0 +1,75 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRotateRollback(t *testing.T) {
+	k := NewArrayKeyring([]Item{{Key: "token", Data: []byte("old")}})
+	verifyErr := errors.New("the new token is rejected")
+
+	err := Rotate(t.Context(), k, "token", func(Item) (Item, error) {
+		return Item{Data: []byte("new")}, nil
+	}, RotateOptions{
+		BackupKey: "token.bak",
+		Verify:    func(context.Context, Item) error { return verifyErr },
+	})
+	if !errors.Is(err, verifyErr) {
+		t.Fatalf("Expected the verification error, got %v", err)
+	}
+	if item, _ := k.Get("token"); string(item.Data) != "old" {
+		t.Fatalf("Expected the old item to be restored, got %q", item.Data)
+	}
+	if keys, _ := k.Keys(); len(keys) != 1 {
+		t.Fatalf("Expected the backup to be removed, got keys %v", keys)
+	}
+
+	fnErr := errors.New("could not issue a token")
+	err = Rotate(t.Context(), k, "token", func(Item) (Item, error) { return Item{}, fnErr }, RotateOptions{})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("Expected the error making the new item, got %v", err)
+	}
+	if item, _ := k.Get("token"); string(item.Data) != "old" {
+		t.Fatalf("Expected the old item to be kept, got %q", item.Data)
+	}
+}
+
+func TestRotateFailedRollback(t *testing.T) {
+	inner := NewArrayKeyring([]Item{{Key: "token", Data: []byte("old")}})
+	storeErr := errors.New("the backend went away")
+	sets := 0
+	// Storing the new item and then rolling back both fail.
+	k := Wrap(inner, func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			if call.Op == OpSet && call.Key == "token" {
+				sets++
+				return storeErr
+			}
+			return next(ctx, call)
+		}
+	})
+
+	err := Rotate(t.Context(), k, "token", func(Item) (Item, error) {
+		return Item{Data: []byte("new")}, nil
+	}, RotateOptions{})
+	if !errors.Is(err, storeErr) {
+		t.Fatalf("Expected the store error, got %v", err)
+	}
+	if sets != 2 {
+		t.Fatalf("Expected storing and rolling back to be tried, got %d sets", sets)
+	}
+	if backup, err := inner.Get("token" + DefaultBackupSuffix); err != nil || string(backup.Data) != "old" {
+		t.Fatalf("Expected the backup to be kept, got %q, %v", backup.Data, err)
+	}
+
+	// The backup holds the last item known to work, so it is not overwritten.
+	err = Rotate(t.Context(), k, "token", func(Item) (Item, error) {
+		t.Error("Expected no second rotation while a backup is left")
+		return Item{}, nil
+	}, RotateOptions{})
+	if err == nil {
+		t.Fatal("Expected an error rotating with a backup left")
+	}
+}
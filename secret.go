@@ -0,0 +1,134 @@
+package keyring
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"unsafe"
+)
+
+// SecretBytes holds a secret, such as an item's Data, in memory of its own
+// that Wipe zeroes. Where the system allows it the memory is also locked, so
+// that the secret is not written to swap. It is not safe for concurrent use.
+type SecretBytes struct {
+	b       []byte
+	mem     secretMemory
+	cleanup runtime.Cleanup
+}
+
+// secretMemory is the whole pages a SecretBytes keeps its secret in, so that
+// locking and unlocking them affects nothing else.
+type secretMemory struct {
+	pages  []byte
+	locked bool
+}
+
+// NewSecretBytes moves data into a new SecretBytes and zeroes data. If the
+// SecretBytes is garbage collected without being wiped, it is wiped then.
+func NewSecretBytes(data []byte) *SecretBytes {
+	// Allocate a page more than needed, and use the whole pages within.
+	page := os.Getpagesize()
+	size := (max(len(data), 1) + page - 1) / page * page
+	buf := make([]byte, size+page)
+	offset := 0
+	if rem := int(uintptr(unsafe.Pointer(&buf[0])) % uintptr(page)); rem != 0 {
+		offset = page - rem
+	}
+	pages := buf[offset : offset+size : offset+size]
+
+	s := &SecretBytes{
+		b:   pages[:len(data):len(data)],
+		mem: secretMemory{pages: pages, locked: lockMemory(pages)},
+	}
+	copy(s.b, data)
+	zeroBytes(data)
+	s.cleanup = runtime.AddCleanup(s, secretMemory.release, s.mem)
+	return s
+}
+
+// Bytes returns the secret. The slice shares the SecretBytes' memory, so
+// it is zeroed by Wipe and must not be used after it. It returns nil once the
+// SecretBytes has been wiped.
+func (s *SecretBytes) Bytes() []byte {
+	return s.b
+}
+
+// Len returns the length of the secret.
+func (s *SecretBytes) Len() int {
+	return len(s.b)
+}
+
+// Locked reports whether the secret's memory is locked against being
+// swapped out. Locking fails where the system has no memory locking, or
+// when the process has reached its limit of locked memory.
+func (s *SecretBytes) Locked() bool {
+	return s.mem.locked
+}
+
+// Wipe zeroes the secret and unlocks its memory. It does nothing if the
+// SecretBytes has already been wiped.
+func (s *SecretBytes) Wipe() {
+	if s.mem.pages == nil {
+		return
+	}
+	s.cleanup.Stop()
+	s.mem.release()
+	s.b = nil
+	s.mem = secretMemory{}
+}
+
+// String keeps the secret out of logs and error messages.
+func (s *SecretBytes) String() string {
+	return "[secret]"
+}
+
+// release zeroes the memory and unlocks it.
+func (m secretMemory) release() {
+	zeroBytes(m.pages)
+	if m.locked {
+		unlockMemory(m.pages)
+	}
+}
+
+// Secret moves the item's Data into a new SecretBytes, zeroing it and
+// leaving Data nil. The Data must be the caller's own, such as that of an
+// item returned by Get, and not shared with a keyring.
+func (i *Item) Secret() *SecretBytes {
+	s := NewSecretBytes(i.Data)
+	i.Data = nil
+	return s
+}
+
+// GetSecret returns the Data of the item for key in k. See GetSecretContext.
+func GetSecret(k Keyring, key string) (*SecretBytes, error) {
+	return GetSecretContext(context.Background(), k, key)
+}
+
+// GetSecretContext returns the Data of the item for key in k as a
+// SecretBytes, zeroing the copy it was read into. The caller wipes it once
+// done with it.
+func GetSecretContext(ctx context.Context, k Keyring, key string) (*SecretBytes, error) {
+	item, err := AsContextKeyring(k).GetContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return item.Secret(), nil
+}
+
+// Wipe zeroes the item's Data and drops it, for callers that are done with
+// the secret. Go may have copied the bytes elsewhere, for example while
+// decoding them, so this limits rather than prevents their exposure; backends
+// wipe the copies they make themselves.
+func (i *Item) Wipe() {
+	zeroBytes(i.Data)
+	i.Data = nil
+}
+
+// zeroBytes overwrites b with zeros. Best-effort: Go's GC may already have copied
+// the bytes elsewhere, but clearing the live copy shrinks the window in which
+// secrets and derived key material sit in process memory.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
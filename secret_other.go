@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package keyring
+
+// lockMemory reports that b could not be locked, as the system has no
+// memory locking.
+func lockMemory([]byte) bool {
+	return false
+}
+
+func unlockMemory([]byte) {}
@@ -0,0 +1,84 @@
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSecretBytes(t *testing.T) {
+	data := []byte("llamas are great")
+	s := NewSecretBytes(data)
+
+	if !bytes.Equal(data, make([]byte, len(data))) {
+		t.Fatalf("Expected the source to be zeroed, got %q", data)
+	}
+	b := s.Bytes()
+	if string(b) != "llamas are great" || s.Len() != len(b) {
+		t.Fatalf("Bytes returned %q", b)
+	}
+	if got := fmt.Sprint(s); got != "[secret]" {
+		t.Fatalf("Expected the secret to be redacted when printed, got %q", got)
+	}
+	t.Logf("Locked: %v", s.Locked())
+
+	s.Wipe()
+	if !bytes.Equal(b, make([]byte, len(b))) {
+		t.Fatalf("Expected Wipe to zero the secret, got %q", b)
+	}
+	if s.Bytes() != nil || s.Locked() {
+		t.Fatalf("Expected a wiped SecretBytes to hold nothing")
+	}
+	s.Wipe()
+
+	if empty := NewSecretBytes(nil); empty.Len() != 0 {
+		t.Fatalf("Expected an empty secret, got %q", empty.Bytes())
+	}
+}
+
+func TestItemWipe(t *testing.T) {
+	item := Item{Key: "llamas", Data: []byte("llamas are great")}
+	data := item.Data
+
+	item.Wipe()
+	if item.Data != nil || !bytes.Equal(data, make([]byte, len(data))) {
+		t.Fatalf("Expected Wipe to zero and drop Data, got %q", data)
+	}
+}
+
+func TestGetSecret(t *testing.T) {
+	k := NewArrayKeyring([]Item{{Key: "llamas", Data: []byte("llamas are great")}})
+
+	s, err := GetSecret(k, "llamas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(s.Bytes()) != "llamas are great" {
+		t.Fatalf("GetSecret returned %q", s.Bytes())
+	}
+	s.Wipe()
+	got, err := k.Get("llamas")
+	if err != nil || string(got.Data) != "llamas are great" {
+		t.Fatalf("Expected the stored data to be intact after wiping the secret, got %q, %v", got.Data, err)
+	}
+	got.Wipe()
+	if got, err := k.Get("llamas"); err != nil || string(got.Data) != "llamas are great" {
+		t.Fatalf("Expected the stored data to be intact after wiping the item, got %q, %v", got.Data, err)
+	}
+
+	if _, err := GetSecret(k, "alpacas"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	item := Item{Key: "llamas", Data: []byte("llamas are great")}
+	data := item.Data
+	s = item.Secret()
+	defer s.Wipe()
+	if item.Data != nil || !bytes.Equal(data, make([]byte, len(data))) {
+		t.Fatalf("Expected Secret to zero and drop the item's Data, got %q", data)
+	}
+	if string(s.Bytes()) != "llamas are great" {
+		t.Fatalf("Secret returned %q", s.Bytes())
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package keyring
+
+import "golang.org/x/sys/unix"
+
+// lockMemory locks b into RAM, reporting whether it could.
+func lockMemory(b []byte) bool {
+	return unix.Mlock(b) == nil
+}
+
+func unlockMemory(b []byte) {
+	_ = unix.Munlock(b)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package keyring
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockMemory locks b into the process's working set, reporting whether it
+// could.
+func lockMemory(b []byte) bool {
+	return windows.VirtualLock(uintptr(unsafe.Pointer(&b[0])), uintptr(len(b))) == nil
+}
+
+func unlockMemory(b []byte) {
+	_ = windows.VirtualUnlock(uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)))
+}
@@ -4,20 +4,33 @@
 package keyring
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
-
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/byteness/go-libsecret"
 	"github.com/godbus/dbus/v5"
 )
 
 func init() {
-	// silently fail if dbus isn't available
+	backendCapabilities[SecretServiceBackend] = Capabilities{
+		// Metadata is kept in the items' attributes, which the service only
+		// exposes once the collection is unlocked.
+		Metadata:           MetadataSupported,
+		Label:              true,
+		Description:        true,
+		ListRequiresUnlock: true,
+		Interactive:        true,
+	}
+
+	// don't register if dbus isn't available, but remember why for Open
 	_, err := dbus.SessionBus()
 	if err != nil {
+		unavailableBackends[SecretServiceBackend] = classify(ErrBackendUnavailable, err)
 		return
 	}
 
@@ -31,12 +44,13 @@ func init() {
 
 		service, err := libsecret.NewService()
 		if err != nil {
-			return &secretsKeyring{}, err
+			return &secretsKeyring{}, dbusError(err)
 		}
 
 		ring := &secretsKeyring{
 			name:    cfg.LibSecretCollectionName,
 			service: service,
+			log:     newCallLogger(cfg, SecretServiceBackend),
 		}
 
 		return ring, ring.openSecrets()
@@ -48,6 +62,7 @@ type secretsKeyring struct {
 	service    *libsecret.Service
 	collection *libsecret.Collection
 	session    *libsecret.Session
+	log        callLogger
 }
 
 var errCollectionNotFound = errors.New("the collection does not exist, please add a key first")
@@ -76,14 +91,14 @@ func decodeKeyringString(src string) string {
 func (k *secretsKeyring) openSecrets() error {
 	session, err := k.service.Open()
 	if err != nil {
-		return err
+		return dbusError(err)
 	}
 	k.session = session
 
 	// get the collection if it already exists
 	collections, err := k.service.Collections()
 	if err != nil {
-		return err
+		return dbusError(err)
 	}
 
 	path := libsecret.DBusPath + "/collection/" + k.name
@@ -116,6 +131,18 @@ func (k *secretsKeyring) openCollection() error {
 }
 
 func (k *secretsKeyring) Get(key string) (Item, error) {
+	return k.GetContext(context.Background(), key)
+}
+
+// GetContext returns the item for key. go-libsecret does not accept a context,
+// so cancellation is observed between D-Bus calls, including after an unlock
+// prompt returns.
+func (k *secretsKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
 	if err := k.openCollection(); err != nil {
 		if err == errCollectionNotFound {
 			return Item{}, ErrKeyNotFound
@@ -125,7 +152,7 @@ func (k *secretsKeyring) Get(key string) (Item, error) {
 
 	items, err := k.collection.SearchItems(key)
 	if err != nil {
-		return Item{}, err
+		return Item{}, dbusError(err)
 	}
 
 	if len(items) == 0 {
@@ -136,45 +163,135 @@ func (k *secretsKeyring) Get(key string) (Item, error) {
 	// with the same profile name
 	item := items[0]
 
-	locked, err := item.Locked()
-	if err != nil {
+	if err := k.ensureUnlocked(&item); err != nil {
 		return Item{}, err
 	}
-
-	if locked {
-		if err := k.service.Unlock(item); err != nil {
-			return Item{}, err
-		}
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
 	}
 
 	secret, err := item.GetSecret(k.session)
 	if err != nil {
-		return Item{}, err
+		return Item{}, dbusError(err)
 	}
 
+	defer zeroBytes(secret.Value)
+
 	// pack the secret into the item
 	var ret Item
 	if err = json.Unmarshal(secret.Value, &ret); err != nil {
 		return Item{}, err
 	}
 
-	return ret, err
+	return unexpired(ret)
+}
+
+// GetMetadata returns the item's metadata from its Secret Attributes and the
+// timestamps the service maintains. It does not prompt: if the item is locked
+// it returns ErrMetadataNeedsCredentials.
+func (k *secretsKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+func (k *secretsKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
+
+	item, err := k.findItem(key)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	locked, err := item.Locked()
+	if err != nil {
+		return Metadata{}, dbusError(err)
+	}
+	if locked {
+		return Metadata{}, ErrMetadataNeedsCredentials
+	}
+
+	attributes, err := item.Attributes()
+	if err != nil {
+		return Metadata{}, dbusError(err)
+	}
+	md := secretsMetadata(key, attributes)
+	if md.CreationTime, err = item.Created(); err != nil {
+		return Metadata{}, dbusError(err)
+	}
+	if md.ModificationTime, err = item.Modified(); err != nil {
+		return Metadata{}, dbusError(err)
+	}
+
+	return md, nil
+}
+
+func (k *secretsKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *secretsKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	item, err := k.findItem(key)
+	if err != nil {
+		return err
+	}
+
+	if err := k.ensureUnlocked(&item); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	attributes, err := item.Attributes()
+	if err != nil {
+		return dbusError(err)
+	}
+	md := secretsMetadata(key, attributes)
+
+	return k.setAttributes(&item, secretsAttributes(md.Item, fields))
 }
 
-// GetMetadata for libsecret returns an error indicating that it's unsupported
-// for this backend.
-//
-// libsecret actually implements a metadata system which we could use, "Secret
-// Attributes"; I found no indication in documentation of anything like an
-// automatically maintained last-modification timestamp, so to use this we'd
-// need to have a SetMetadata API too.  Which we're not yet doing, but feel
-// free to contribute patches.
-func (k *secretsKeyring) GetMetadata(_ string) (Metadata, error) {
-	return Metadata{}, ErrMetadataNeedsCredentials
+// findItem returns the first item for key in the collection.
+func (k *secretsKeyring) findItem(key string) (libsecret.Item, error) {
+	if err := k.openCollection(); err != nil {
+		if err == errCollectionNotFound {
+			return libsecret.Item{}, ErrKeyNotFound
+		}
+		return libsecret.Item{}, err
+	}
+
+	items, err := k.collection.SearchItems(key)
+	if err != nil {
+		return libsecret.Item{}, dbusError(err)
+	}
+	if len(items) == 0 {
+		return libsecret.Item{}, ErrKeyNotFound
+	}
+
+	return items[0], nil
 }
 
 func (k *secretsKeyring) Set(item Item) error {
-	err := k.openSecrets()
+	return k.SetContext(context.Background(), item)
+}
+
+func (k *secretsKeyring) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := secretsCheckAttributes(item.Attributes); err != nil {
+		return err
+	}
+
+	err = k.openSecrets()
 	if err != nil {
 		return err
 	}
@@ -183,32 +300,247 @@ func (k *secretsKeyring) Set(item Item) error {
 	if k.collection == nil {
 		collection, err := k.service.CreateCollection(k.name)
 		if err != nil {
-			return err
+			return dbusError(err)
 		}
 
 		k.collection = collection
 	}
 
-	if err := k.ensureCollectionUnlocked(); err != nil {
+	if err := k.ensureUnlocked(k.collection); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	// create the new item
 	data, err := json.Marshal(item)
 	if err != nil {
 		return err
 	}
+	defer zeroBytes(data)
 
 	secret := libsecret.NewSecret(k.session, []byte{}, data, "application/json")
 
-	if _, err := k.collection.CreateItem(item.Key, secret, true); err != nil {
+	// The service only replaces an item whose attributes all match, and the
+	// metadata is part of them, so an existing item is updated in place.
+	items, err := k.collection.SearchItems(item.Key)
+	if err != nil {
+		return dbusError(err)
+	}
+	if len(items) == 0 {
+		return k.createItem(item.Key, secretsAttributes(&item, nil), secret)
+	}
+
+	existing := items[0]
+	if err := k.ensureUnlocked(&existing); err != nil {
+		return err
+	}
+	attributes, err := existing.Attributes()
+	if err != nil {
+		return dbusError(err)
+	}
+	fields := secretsMetadata(item.Key, attributes).Fields
+
+	obj, err := secretsObject(&existing)
+	if err != nil {
 		return err
 	}
+	if err := obj.Call("org.freedesktop.Secret.Item.SetSecret", 0, secret).Err; err != nil {
+		return dbusError(err)
+	}
+	return k.setAttributes(&existing, secretsAttributes(&item, fields))
+}
+
+// FindByAttributesContext returns the keys of the items whose attributes
+// include attrs, using the service's own search.
+func (k *secretsKeyring) FindByAttributesContext(ctx context.Context, attrs map[string]string) (_ []string, err error) {
+	defer k.log.op(ctx, OpFindByAttributes, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := secretsCheckAttributes(attrs); err != nil {
+		return nil, err
+	}
+
+	if err := k.openCollection(); err != nil {
+		if err == errCollectionNotFound {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	// Labels, which hold the keys, are only readable once unlocked.
+	if err := k.ensureUnlocked(k.collection); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	obj, err := secretsObject(k.collection)
+	if err != nil {
+		return nil, err
+	}
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	var paths []dbus.ObjectPath
+	if err := obj.Call("org.freedesktop.Secret.Collection.SearchItems", 0, attrs).Store(&paths); err != nil {
+		return nil, dbusError(err)
+	}
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, dbusError(err)
+	}
+	keys := []string{}
+	for _, path := range paths {
+		label, err := libsecret.NewItem(conn, path).Label()
+		if err != nil {
+			return nil, dbusError(err)
+		}
+		keys = append(keys, label)
+	}
+	return keys, nil
+}
+
+// Attribute names for the metadata kept in an item's Secret Attributes.
+// go-libsecret finds items by the "profile" attribute. The item's own
+// Attributes are stored under their names, so that other tools can look
+// them up, and may not use these.
+const (
+	secretsAttrProfile     = "profile"
+	secretsAttrSchema      = "xdg:schema"
+	secretsAttrLabel       = "keyring:label"
+	secretsAttrDescription = "keyring:description"
+	secretsAttrExpires     = "keyring:expires"
+	secretsAttrFieldPrefix = "keyring:field:"
+	secretsAttrReserved    = "keyring:"
+)
+
+// secretsCheckAttributes returns an error if an item attribute name clashes
+// with one this backend uses itself.
+func secretsCheckAttributes(attrs map[string]string) error {
+	for name := range attrs {
+		if name == secretsAttrProfile || name == secretsAttrSchema || strings.HasPrefix(name, secretsAttrReserved) {
+			return fmt.Errorf("secret-service: attribute name %q is reserved", name)
+		}
+	}
+	return nil
+}
+
+// secretsAttributes returns the Secret Attributes for an item with the given
+// metadata fields.
+func secretsAttributes(item *Item, fields map[string]string) map[string]string {
+	attributes := map[string]string{secretsAttrProfile: item.Key}
+	for name, value := range item.Attributes {
+		attributes[name] = value
+	}
+	if item.Label != "" {
+		attributes[secretsAttrLabel] = item.Label
+	}
+	if item.Description != "" {
+		attributes[secretsAttrDescription] = item.Description
+	}
+	if !item.Expires.IsZero() {
+		attributes[secretsAttrExpires] = item.Expires.Format(time.RFC3339Nano)
+	}
+	for name, value := range fields {
+		attributes[secretsAttrFieldPrefix+name] = value
+	}
+	return attributes
+}
+
+// secretsMetadata is the inverse of secretsAttributes. The timestamps are
+// properties of the item rather than attributes, so they are left unset.
+func secretsMetadata(key string, attributes map[string]string) Metadata {
+	md := Metadata{
+		Item: &Item{
+			Key:         key,
+			Label:       attributes[secretsAttrLabel],
+			Description: attributes[secretsAttrDescription],
+		},
+	}
+	md.Expires, _ = time.Parse(time.RFC3339Nano, attributes[secretsAttrExpires])
+	for name, value := range attributes {
+		switch {
+		case name == secretsAttrProfile, name == secretsAttrSchema:
+		case strings.HasPrefix(name, secretsAttrFieldPrefix):
+			if md.Fields == nil {
+				md.Fields = map[string]string{}
+			}
+			md.Fields[strings.TrimPrefix(name, secretsAttrFieldPrefix)] = value
+		case strings.HasPrefix(name, secretsAttrReserved):
+		default:
+			if md.Attributes == nil {
+				md.Attributes = map[string]string{}
+			}
+			md.Attributes[name] = value
+		}
+	}
+	return md
+}
+
+// createItem adds an item to the collection. go-libsecret's CreateItem only
+// sets the "profile" attribute.
+func (k *secretsKeyring) createItem(label string, attributes map[string]string, secret *libsecret.Secret) error {
+	obj, err := secretsObject(k.collection)
+	if err != nil {
+		return err
+	}
+
+	properties := map[string]dbus.Variant{
+		"org.freedesktop.Secret.Item.Label":      dbus.MakeVariant(label),
+		"org.freedesktop.Secret.Item.Attributes": dbus.MakeVariant(attributes),
+	}
+
+	var path, prompt dbus.ObjectPath
+	err = obj.Call("org.freedesktop.Secret.Collection.CreateItem", 0, properties, secret, false).Store(&path, &prompt)
+	if err != nil {
+		return dbusError(err)
+	}
+
+	if prompt != "/" {
+		conn, err := dbus.SessionBus()
+		if err != nil {
+			return dbusError(err)
+		}
+		if _, err := libsecret.NewPrompt(conn, prompt).Prompt(); err != nil {
+			return dbusError(err)
+		}
+	}
 
 	return nil
 }
 
+// setAttributes replaces the Secret Attributes of an item.
+func (k *secretsKeyring) setAttributes(item *libsecret.Item, attributes map[string]string) error {
+	obj, err := secretsObject(item)
+	if err != nil {
+		return err
+	}
+	return dbusError(obj.SetProperty("org.freedesktop.Secret.Item.Attributes", dbus.MakeVariant(attributes)))
+}
+
+// secretsObject returns a D-Bus handle on a go-libsecret object, for the
+// calls go-libsecret does not wrap.
+func secretsObject(obj libsecret.DBusObject) (dbus.BusObject, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, dbusError(err)
+	}
+	return conn.Object(libsecret.DBusServiceName, obj.Path()), nil
+}
+
 func (k *secretsKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *secretsKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if err := k.openCollection(); err != nil {
 		if err == errCollectionNotFound {
 			return ErrKeyNotFound
@@ -218,49 +550,56 @@ func (k *secretsKeyring) Remove(key string) error {
 
 	items, err := k.collection.SearchItems(key)
 	if err != nil {
-		return err
+		return dbusError(err)
 	}
 
-	// nothing to delete
 	if len(items) == 0 {
-		return nil
+		return ErrKeyNotFound
 	}
 
 	// we dont want to delete more than one anyway
 	// so just get the first item found
 	item := items[0]
 
-	locked, err := item.Locked()
-	if err != nil {
+	if err := k.ensureUnlocked(&item); err != nil {
 		return err
 	}
-
-	if locked {
-		if err := k.service.Unlock(item); err != nil {
-			return err
-		}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
 	if err := item.Delete(); err != nil {
-		return err
+		return dbusError(err)
 	}
 
 	return nil
 }
 
 func (k *secretsKeyring) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+func (k *secretsKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	if err := k.openCollection(); err != nil {
 		if err == errCollectionNotFound {
 			return []string{}, nil
 		}
 		return nil, err
 	}
-	if err := k.ensureCollectionUnlocked(); err != nil {
+	if err := k.ensureUnlocked(k.collection); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 	items, err := k.collection.Items()
 	if err != nil {
-		return nil, err
+		return nil, dbusError(err)
 	}
 	keys := []string{}
 	for _, item := range items {
@@ -272,6 +611,92 @@ func (k *secretsKeyring) Keys() ([]string, error) {
 	return keys, nil
 }
 
+// secretCollectionInterface is the D-Bus interface of a Secret Service
+// collection, which signals changes to its items.
+const secretCollectionInterface = "org.freedesktop.Secret.Collection"
+
+// Watch reports changes to the items in the collection from its ItemCreated,
+// ItemChanged and ItemDeleted signals. The collection need not exist yet.
+func (k *secretsKeyring) Watch(ctx context.Context) (<-chan Event, error) {
+	conn, signals, stop, err := subscribeSignals(ctx, dbus.WithMatchInterface(secretCollectionInterface))
+	if err != nil {
+		return nil, err
+	}
+
+	// A deleted item can no longer be asked for its label, so the keys of
+	// the items are kept by their paths.
+	labels := map[dbus.ObjectPath]string{}
+	if err := k.openCollection(); err == nil {
+		items, err := k.collection.Items()
+		if err != nil {
+			stop()
+			return nil, dbusError(err)
+		}
+		for _, item := range items {
+			if label, err := item.Label(); err == nil {
+				labels[item.Path()] = label
+			}
+		}
+	} else if err != errCollectionNotFound {
+		stop()
+		return nil, err
+	}
+
+	path := libsecret.DBusPath + "/collection/" + k.name
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer stop()
+		for {
+			var sig *dbus.Signal
+			select {
+			case <-ctx.Done():
+				return
+			case s, ok := <-signals:
+				if !ok {
+					return
+				}
+				sig = s
+			}
+			if decodeKeyringString(string(sig.Path)) != path || len(sig.Body) == 0 {
+				continue
+			}
+			itemPath, ok := sig.Body[0].(dbus.ObjectPath)
+			if !ok {
+				continue
+			}
+
+			var evs []Event
+			old, known := labels[itemPath]
+			switch sig.Name {
+			case secretCollectionInterface + ".ItemCreated", secretCollectionInterface + ".ItemChanged":
+				label, err := libsecret.NewItem(conn, itemPath).Label()
+				if err != nil {
+					continue
+				}
+				labels[itemPath] = label
+				switch {
+				case !known:
+					evs = []Event{{Type: EventCreated, Key: label}}
+				case old != label:
+					evs = []Event{{Type: EventDeleted, Key: old}, {Type: EventCreated, Key: label}}
+				default:
+					evs = []Event{{Type: EventChanged, Key: label}}
+				}
+			case secretCollectionInterface + ".ItemDeleted":
+				if known {
+					delete(labels, itemPath)
+					evs = []Event{{Type: EventDeleted, Key: old}}
+				}
+			}
+			if !sendEvents(ctx, events, evs) {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
 // deleteCollection deletes the keyring's collection if it exists. This is mainly to support testing.
 func (k *secretsKeyring) deleteCollection() error {
 	if err := k.openCollection(); err != nil {
@@ -280,14 +705,30 @@ func (k *secretsKeyring) deleteCollection() error {
 	return k.collection.Delete()
 }
 
-// unlock the collection if it's locked
-func (k *secretsKeyring) ensureCollectionUnlocked() error {
-	locked, err := k.collection.Locked()
+// lockable is satisfied by both libsecret.Collection and libsecret.Item.
+type lockable interface {
+	libsecret.DBusObject
+	Locked() (bool, error)
+}
+
+// ensureUnlocked unlocks the collection or item if it's locked. go-libsecret
+// returns success when the unlock prompt is dismissed, so the lock state is
+// checked again afterwards to tell that apart from a real unlock.
+func (k *secretsKeyring) ensureUnlocked(obj lockable) error {
+	locked, err := obj.Locked()
 	if err != nil {
-		return err
+		return dbusError(err)
 	}
 	if !locked {
 		return nil
 	}
-	return k.service.Unlock(k.collection)
+	if err := k.service.Unlock(obj); err != nil {
+		return dbusError(err)
+	}
+	if locked, err = obj.Locked(); err != nil {
+		return dbusError(err)
+	} else if locked {
+		return fmt.Errorf("%w: the unlock prompt for %q was dismissed", ErrUserCancelled, k.name)
+	}
+	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/byteness/go-libsecret"
 )
@@ -166,3 +167,43 @@ func TestLibSpecialCharacters(t *testing.T) {
 		t.Fatal("incorrect decodeKeyringString")
 	}
 }
+
+func TestSecretsAttributesRoundTrip(t *testing.T) {
+	fields := map[string]string{"owner": "ops", "env": "prod"}
+	item := &Item{
+		Key:         "aws",
+		Label:       "AWS",
+		Description: "credentials",
+		Attributes:  map[string]string{"env": "dev"},
+		Expires:     time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	attributes := secretsAttributes(item, fields)
+	if attributes["profile"] != "aws" {
+		t.Fatalf("go-libsecret cannot find the item without its profile attribute: %v", attributes)
+	}
+	if attributes["env"] != "dev" {
+		t.Fatalf("item attributes should be stored under their own names: %v", attributes)
+	}
+
+	md := secretsMetadata("aws", attributes)
+	if md.Key != "aws" || md.Label != "AWS" || md.Description != "credentials" {
+		t.Fatalf("unexpected item %+v", *md.Item)
+	}
+	if len(md.Fields) != 2 || md.Fields["owner"] != "ops" || md.Fields["env"] != "prod" {
+		t.Fatalf("unexpected fields %v", md.Fields)
+	}
+	if len(md.Attributes) != 1 || md.Attributes["env"] != "dev" {
+		t.Fatalf("unexpected attributes %v", md.Attributes)
+	}
+	if !md.Expires.Equal(item.Expires) {
+		t.Fatalf("expiry %v did not round-trip, got %v", item.Expires, md.Expires)
+	}
+
+	if md := secretsMetadata("aws", map[string]string{"profile": "aws"}); md.Fields != nil || md.Label != "" {
+		t.Fatalf("an item stored without metadata has %+v", md)
+	}
+
+	if err := secretsCheckAttributes(map[string]string{"keyring:label": "x"}); err == nil {
+		t.Fatal("expected an error for a reserved attribute name")
+	}
+}
@@ -0,0 +1,235 @@
+package keyring
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EventType says how an item changed.
+type EventType string
+
+const (
+	EventCreated EventType = "created"
+	EventChanged EventType = "changed"
+	EventDeleted EventType = "deleted"
+)
+
+// Event reports a change to the item for Key, made through this package or
+// any other way, such as by the user in their password manager.
+type Event struct {
+	Type EventType
+	Key  string
+}
+
+// Watcher is implemented by keyrings that are notified of changes to their
+// items by the backend, rather than having to look for them.
+type Watcher interface {
+	// Returns a channel of the changes to items, closed once ctx is done
+	Watch(ctx context.Context) (<-chan Event, error)
+}
+
+// DefaultPollInterval is how often Watch looks for changes in keyrings that
+// are not notified of them.
+const DefaultPollInterval = time.Minute
+
+// Watch returns a channel reporting the items created, changed and deleted in
+// k from now on. It is closed once ctx is done. Keyrings that implement
+// Watcher are notified of changes by the backend; others are polled every
+// DefaultPollInterval.
+//
+// Events may be coalesced or, for a change made twice in quick succession,
+// repeated, so they are best taken as a cue to read the item again.
+func Watch(ctx context.Context, k Keyring) (<-chan Event, error) {
+	if w, ok := k.(Watcher); ok {
+		return w.Watch(ctx)
+	}
+	return Poll(ctx, k, DefaultPollInterval)
+}
+
+// Poll returns a channel reporting the changes to the items in k, found by
+// listing and reading them every interval. It is closed once ctx is done.
+//
+// Items are compared by their modification time where k can return it
+// without credentials, and otherwise by a hash of their contents; keyrings
+// that implement BatchKeyring are read in one pass. A round in which k
+// cannot be read is skipped.
+func Poll(ctx context.Context, k Keyring, interval time.Duration) (<-chan Event, error) {
+	if interval <= 0 {
+		return nil, errors.New("keyring: the poll interval must be positive")
+	}
+	ticker := time.NewTicker(interval)
+	return watchSnapshots(ctx, k, ticker.C, ticker.Stop)
+}
+
+// sendEvents sends evs on ch, returning false if ctx is done first.
+func sendEvents(ctx context.Context, ch chan<- Event, evs []Event) bool {
+	for _, ev := range evs {
+		select {
+		case ch <- ev:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot returns a fingerprint for each item in k, which differs whenever
+// the item does. Items that could not be read keep their fingerprint from
+// prev, so that a passing error is not reported as a change.
+func snapshot(ctx context.Context, k Keyring, prev map[string]string) (map[string]string, error) {
+	ck := AsContextKeyring(k)
+	keys, err := ck.KeysContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fps := make(map[string]string, len(keys))
+	unread := []string{}
+	if _, ok := k.(BatchKeyring); ok {
+		unread = keys
+	} else {
+		for _, key := range keys {
+			md, err := ck.GetMetadataContext(ctx, key)
+			switch {
+			case err == nil && !md.ModificationTime.IsZero():
+				fps[key] = md.ModificationTime.UTC().Format(time.RFC3339Nano)
+			case errors.Is(err, ErrKeyNotFound):
+				// removed since the keys were listed
+			case err != nil && ctx.Err() != nil:
+				return nil, ctx.Err()
+			default:
+				unread = append(unread, key)
+			}
+		}
+	}
+	if len(unread) == 0 {
+		return fps, nil
+	}
+
+	items, err := GetManyContext(ctx, k, unread)
+	var batchErr *BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		return nil, err
+	}
+	for _, key := range unread {
+		if item, ok := items[key]; ok {
+			fps[key] = itemFingerprint(item)
+		} else if batchErr != nil && errors.Is(batchErr.Errors[key], ErrKeyNotFound) {
+			continue
+		} else if fp, ok := prev[key]; ok {
+			fps[key] = fp
+		}
+	}
+	return fps, nil
+}
+
+// itemFingerprint returns a hash of item.
+func itemFingerprint(item Item) string {
+	b, _ := json.Marshal(item)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// diffSnapshots returns the events turning prev into next, ordered by key.
+func diffSnapshots(prev, next map[string]string) []Event {
+	var evs []Event
+	for key, fp := range next {
+		if old, ok := prev[key]; !ok {
+			evs = append(evs, Event{Type: EventCreated, Key: key})
+		} else if old != fp {
+			evs = append(evs, Event{Type: EventChanged, Key: key})
+		}
+	}
+	for key := range prev {
+		if _, ok := next[key]; !ok {
+			evs = append(evs, Event{Type: EventDeleted, Key: key})
+		}
+	}
+	slices.SortFunc(evs, func(a, b Event) int { return strings.Compare(a.Key, b.Key) })
+	return evs
+}
+
+// watchSnapshots returns a channel reporting the changes to the items in k,
+// found by comparing snapshots of k each time a value arrives on notify. It
+// suits backends whose notifications do not say exactly what changed. The
+// channel is closed once ctx is done or notify is closed, and stop is called
+// once it no longer reads notify.
+func watchSnapshots[T any](ctx context.Context, k Keyring, notify <-chan T, stop func()) (<-chan Event, error) {
+	prev, err := snapshot(ctx, k, nil)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-notify:
+				if !ok {
+					return
+				}
+			}
+			next, err := snapshot(ctx, k, prev)
+			if err != nil {
+				continue
+			}
+			if !sendEvents(ctx, events, diffSnapshots(prev, next)) {
+				return
+			}
+			prev = next
+		}
+	}()
+	return events, nil
+}
+
+// watchStore returns a channel reporting the changes to the items of k, a
+// store laid out as pass and passage lay out theirs under root, with keys
+// ending in ext. Where the tree cannot be watched, or does not exist yet,
+// k is polled instead.
+func watchStore(ctx context.Context, k Keyring, root, ext string) (<-chan Event, error) {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return Poll(ctx, k, DefaultPollInterval)
+	}
+	events, err := watchTree(ctx, root, true, func(rel string) (string, bool) {
+		key, ok := strings.CutSuffix(rel, ext)
+		return key, ok && key != "" && !strings.HasSuffix(key, string(os.PathSeparator))
+	})
+	if errors.Is(err, errors.ErrUnsupported) {
+		return Poll(ctx, k, DefaultPollInterval)
+	}
+	return events, err
+}
+
+// mapEvents returns a channel of the events from in passed through f, leaving
+// out those for which it returns false. Wrapping keyrings use it to watch the
+// keyrings they wrap.
+func mapEvents(ctx context.Context, in <-chan Event, f func(Event) (Event, bool)) <-chan Event {
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		for ev := range in {
+			ev, ok := f(ev)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,258 @@
+//go:build linux
+
+package keyring
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// treeMask is the inotify events a treeWatch follows. Files are reported once
+// written and closed, or moved into place, rather than when created empty.
+const treeMask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM |
+	unix.IN_DELETE | unix.IN_CREATE | unix.IN_DONT_FOLLOW
+
+// treeWatch follows the items stored as files under a directory with inotify.
+type treeWatch struct {
+	f         *os.File
+	root      string
+	recursive bool
+	keyOf     func(rel string) (string, bool)
+
+	dirs  map[int]string    // watch descriptor to directory
+	known map[string]string // path relative to root to key, for the items seen so far
+}
+
+// watchTree returns a channel reporting the changes to the items stored as
+// files in root, or in its subdirectories too if recursive is set. keyOf
+// returns the key for a file's path relative to root, or false if the file
+// does not hold an item. Hidden subdirectories, such as .git, are not
+// watched. The channel is closed once ctx is done or root is removed.
+//
+// If inotify cannot be used the error matches errors.ErrUnsupported.
+func watchTree(ctx context.Context, root string, recursive bool, keyOf func(rel string) (string, bool)) (<-chan Event, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		// Out of inotify instances, most likely; the caller can poll.
+		return nil, fmt.Errorf("%w: %w", errors.ErrUnsupported, os.NewSyscallError("inotify_init1", err))
+	}
+	w := &treeWatch{
+		f:         os.NewFile(uintptr(fd), "inotify"),
+		root:      root,
+		recursive: recursive,
+		keyOf:     keyOf,
+		dirs:      map[int]string{},
+		known:     map[string]string{},
+	}
+	if _, err := w.add(root); err != nil {
+		w.f.Close()
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		// Closing the file ends a blocked Read.
+		stop := context.AfterFunc(ctx, func() { w.f.Close() })
+		defer func() {
+			if stop() {
+				w.f.Close()
+			}
+		}()
+
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			n, err := w.f.Read(buf)
+			if err != nil {
+				return
+			}
+			var evs []Event
+			gone := false
+			for off := 0; off+unix.SizeofInotifyEvent <= n; {
+				wd := int(int32(binary.NativeEndian.Uint32(buf[off:])))
+				mask := binary.NativeEndian.Uint32(buf[off+4:])
+				size := int(binary.NativeEndian.Uint32(buf[off+12:]))
+				off += unix.SizeofInotifyEvent
+				name := strings.TrimRight(string(buf[off:off+size]), "\x00")
+				off += size
+
+				if wd == -1 && mask&unix.IN_Q_OVERFLOW != 0 {
+					evs = append(evs, w.rescan()...)
+					continue
+				}
+				dir, ok := w.dirs[wd]
+				if !ok {
+					continue
+				}
+				if mask&unix.IN_IGNORED != 0 {
+					delete(w.dirs, wd)
+					if dir == w.root {
+						evs = append(evs, w.forget("")...)
+						gone = true
+					}
+					continue
+				}
+				evs = append(evs, w.handle(filepath.Join(dir, name), mask)...)
+			}
+			if !sendEvents(ctx, events, evs) || gone {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// handle returns the events for an inotify event with mask about path.
+func (w *treeWatch) handle(path string, mask uint32) []Event {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return nil
+	}
+
+	if mask&unix.IN_ISDIR != 0 {
+		if !w.recursive || strings.HasPrefix(filepath.Base(path), ".") {
+			return nil
+		}
+		switch {
+		case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+			// Files may have been written before the watch was added.
+			keys, _ := w.add(path)
+			evs := make([]Event, 0, len(keys))
+			for _, key := range keys {
+				evs = append(evs, Event{Type: EventCreated, Key: key})
+			}
+			return evs
+		case mask&unix.IN_MOVED_FROM != 0:
+			w.unwatch(path)
+			return w.forget(rel + string(os.PathSeparator))
+		}
+		return nil
+	}
+
+	key, ok := w.keyOf(rel)
+	if !ok {
+		return nil
+	}
+	_, known := w.known[rel]
+	switch {
+	case mask&(unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO) != 0:
+		if known {
+			return []Event{{Type: EventChanged, Key: key}}
+		}
+		w.known[rel] = key
+		return []Event{{Type: EventCreated, Key: key}}
+	case mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+		if known {
+			delete(w.known, rel)
+			return []Event{{Type: EventDeleted, Key: key}}
+		}
+	}
+	return nil
+}
+
+// add watches dir, and its subdirectories if the watch is recursive, and
+// returns the keys of the items found in them that were not known yet.
+func (w *treeWatch) add(dir string) ([]string, error) {
+	var added []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (!w.recursive || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return w.addWatch(path)
+		}
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return nil
+		}
+		if _, known := w.known[rel]; known {
+			return nil
+		}
+		if key, ok := w.keyOf(rel); ok {
+			w.known[rel] = key
+			added = append(added, key)
+		}
+		return nil
+	})
+	return added, err
+}
+
+func (w *treeWatch) addWatch(dir string) error {
+	rc, err := w.f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var wd int
+	cerr := rc.Control(func(fd uintptr) {
+		wd, err = unix.InotifyAddWatch(int(fd), dir, treeMask)
+	})
+	if cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	w.dirs[wd] = dir
+	return nil
+}
+
+// unwatch stops watching dir and the directories under it.
+func (w *treeWatch) unwatch(dir string) {
+	rc, err := w.f.SyscallConn()
+	if err != nil {
+		return
+	}
+	for wd, path := range w.dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator)) {
+			rc.Control(func(fd uintptr) {
+				unix.InotifyRmWatch(int(fd), uint32(wd))
+			})
+			delete(w.dirs, wd)
+		}
+	}
+}
+
+// forget returns deleted events for the known keys of the files whose path
+// relative to the root starts with prefix.
+func (w *treeWatch) forget(prefix string) []Event {
+	var evs []Event
+	for rel, key := range w.known {
+		if strings.HasPrefix(rel, prefix) {
+			delete(w.known, rel)
+			evs = append(evs, Event{Type: EventDeleted, Key: key})
+		}
+	}
+	return evs
+}
+
+// rescan compares the items under the root with the known ones, after the
+// kernel dropped events. Changes to items that were already known are lost.
+func (w *treeWatch) rescan() []Event {
+	old := w.known
+	w.known = map[string]string{}
+	w.add(w.root)
+
+	var evs []Event
+	for rel, key := range w.known {
+		if _, ok := old[rel]; !ok {
+			evs = append(evs, Event{Type: EventCreated, Key: key})
+		}
+	}
+	for rel, key := range old {
+		if _, ok := w.known[rel]; !ok {
+			evs = append(evs, Event{Type: EventDeleted, Key: key})
+		}
+	}
+	return evs
+}
@@ -0,0 +1,111 @@
+//go:build linux && !keyring_nofile
+
+package keyring
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// skipWithoutInotify skips the test if no inotify instance can be had, which
+// happens when other processes hold the user's whole allowance.
+func skipWithoutInotify(t *testing.T) {
+	t.Helper()
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		t.Skipf("inotify is not available: %v", err)
+	}
+	unix.Close(fd)
+}
+
+func TestFileKeyringWatch(t *testing.T) {
+	skipWithoutInotify(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	k := &fileKeyring{
+		dir:          t.TempDir(),
+		passwordFunc: FixedStringPrompt("no more secrets"),
+	}
+	if err := k.Set(Item{Key: "existing", Data: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := k.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := k.Set(Item{Key: "a/b", Data: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(t, events); ev != (Event{EventCreated, "a/b"}) {
+		t.Fatalf("Expected a/b to be created, got %+v", ev)
+	}
+	if err := k.Set(Item{Key: "existing", Data: []byte("2")}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(t, events); ev != (Event{EventChanged, "existing"}) {
+		t.Fatalf("Expected existing to be changed, got %+v", ev)
+	}
+	if err := k.Remove("a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(t, events); ev != (Event{EventDeleted, "a/b"}) {
+		t.Fatalf("Expected a/b to be deleted, got %+v", ev)
+	}
+}
+
+func TestWatchTree(t *testing.T) {
+	skipWithoutInotify(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	root := t.TempDir()
+	write := func(rel string) {
+		t.Helper()
+		p := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("old.gpg")
+
+	events, err := watchTree(ctx, root, true, func(rel string) (string, bool) {
+		return strings.CutSuffix(rel, ".gpg")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(".git/objects/ab")
+	write("notes.txt")
+	write("team/app/new.gpg")
+	if ev := nextEvent(t, events); ev != (Event{EventCreated, "team/app/new"}) {
+		t.Fatalf("Expected team/app/new to be created, got %+v", ev)
+	}
+	write("team/app/new.gpg")
+	if ev := nextEvent(t, events); ev != (Event{EventChanged, "team/app/new"}) {
+		t.Fatalf("Expected team/app/new to be changed, got %+v", ev)
+	}
+	if err := os.Rename(filepath.Join(root, "team"), filepath.Join(t.TempDir(), "team")); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(t, events); ev != (Event{EventDeleted, "team/app/new"}) {
+		t.Fatalf("Expected team/app/new to be deleted, got %+v", ev)
+	}
+	if err := os.Remove(filepath.Join(root, "old.gpg")); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextEvent(t, events); ev != (Event{EventDeleted, "old"}) {
+		t.Fatalf("Expected old to be deleted, got %+v", ev)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package keyring
+
+import (
+	"context"
+	"errors"
+)
+
+// watchTree is only implemented with inotify, so elsewhere the keyrings
+// stored as files fall back to polling.
+func watchTree(context.Context, string, bool, func(string) (string, bool)) (<-chan Event, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,59 @@
+package keyring
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// nextEvent returns the next event on events, failing the test if none comes.
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("The events channel was closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return Event{}
+}
+
+func TestWatchSnapshots(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	k := NewArrayKeyring([]Item{{Key: "a", Data: []byte("1")}})
+	notify := make(chan struct{})
+	stopped := make(chan struct{})
+	events, err := watchSnapshots(ctx, k, notify, func() { close(stopped) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		change func() error
+		want   Event
+	}{
+		{func() error { return k.Set(Item{Key: "b", Data: []byte("1")}) }, Event{EventCreated, "b"}},
+		{func() error { return k.Set(Item{Key: "a", Data: []byte("2")}) }, Event{EventChanged, "a"}},
+		{func() error { return k.Remove("b") }, Event{EventDeleted, "b"}},
+	}
+	for _, step := range steps {
+		if err := step.change(); err != nil {
+			t.Fatal(err)
+		}
+		notify <- struct{}{}
+		if ev := nextEvent(t, events); ev != step.want {
+			t.Fatalf("Expected %+v, got %+v", step.want, ev)
+		}
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("Expected the events channel to be closed")
+	}
+	<-stopped
+}
@@ -4,8 +4,11 @@
 package keyring
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/danieljoos/wincred"
 )
@@ -13,12 +16,29 @@ import (
 // ERROR_NOT_FOUND from https://docs.microsoft.com/en-us/windows/win32/debug/system-error-codes--1000-1299-
 const errElementNotFound = syscall.Errno(1168)
 
+// ERROR_ACCESS_DENIED and ERROR_NO_SUCH_LOGON_SESSION; the latter is returned
+// when there is no logon session to hold credentials, e.g. for a service.
+const (
+	errAccessDenied       = syscall.Errno(5)
+	errNoSuchLogonSession = syscall.Errno(1312)
+)
+
 type windowsKeyring struct {
 	name   string
 	prefix string
+	log    callLogger
 }
 
 func init() {
+	backendCapabilities[WinCredBackend] = Capabilities{
+		Metadata:    MetadataSupported,
+		Label:       true,
+		Description: true,
+		// The data is stored as the credential blob, which is limited to
+		// CRED_MAX_CREDENTIAL_BLOB_SIZE (5 * 512) bytes.
+		MaxItemSize: 2560,
+	}
+
 	supportedBackends[WinCredBackend] = opener(func(cfg Config) (Keyring, error) {
 		name := cfg.ServiceName
 		if name == "" {
@@ -33,52 +53,215 @@ func init() {
 		return &windowsKeyring{
 			name:   name,
 			prefix: prefix,
+			log:    newCallLogger(cfg, WinCredBackend),
 		}, nil
 	})
+	backendProbes[WinCredBackend] = func(_ context.Context, _ Config) error {
+		_, err := wincred.List()
+		return wincredError(err)
+	}
 }
 
 func (k *windowsKeyring) Get(key string) (Item, error) {
+	return k.GetContext(context.Background(), key)
+}
+
+// GetContext returns the credential for key. Credential Manager calls do not
+// block, so the context is only checked up front.
+func (k *windowsKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
 	cred, err := wincred.GetGenericCredential(k.credentialName(key))
 	if err != nil {
 		if err == errElementNotFound {
 			return Item{}, ErrKeyNotFound
 		}
-		return Item{}, err
+		return Item{}, wincredError(err)
+	}
+
+	md := wincredMetadata(key, &cred.Credential)
+	item := *md.Item
+	item.Data = cred.CredentialBlob
+
+	return unexpired(item)
+}
+
+// GetMetadata returns the metadata kept in the credential's attributes and
+// comment. Credential Manager does not need unlocking, so neither does this.
+func (k *windowsKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+func (k *windowsKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
 	}
 
-	item := Item{
-		Key:  key,
-		Data: cred.CredentialBlob,
+	cred, err := wincred.GetGenericCredential(k.credentialName(key))
+	if err != nil {
+		if err == errElementNotFound {
+			return Metadata{}, ErrKeyNotFound
+		}
+		return Metadata{}, wincredError(err)
 	}
 
-	return item, nil
+	return wincredMetadata(key, &cred.Credential), nil
 }
 
-// GetMetadata for pass returns an error indicating that it's unsupported
-// for this backend.
-// TODO: This is a stub. Look into whether pass would support metadata in a usable way for keyring.
-func (k *windowsKeyring) GetMetadata(_ string) (Metadata, error) {
-	return Metadata{}, ErrMetadataNotSupported
+func (k *windowsKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *windowsKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cred, err := wincred.GetGenericCredential(k.credentialName(key))
+	if err != nil {
+		if err == errElementNotFound {
+			return ErrKeyNotFound
+		}
+		return wincredError(err)
+	}
+
+	md := wincredMetadata(key, &cred.Credential)
+	cred.Attributes = wincredAttributes(md.Item, md.CreationTime, fields)
+	return wincredError(cred.Write())
 }
 
 func (k *windowsKeyring) Set(item Item) error {
+	return k.SetContext(context.Background(), item)
+}
+
+func (k *windowsKeyring) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Keep the creation time and fields of the credential being replaced.
+	created, fields := time.Now(), map[string]string(nil)
+	if existing, err := wincred.GetGenericCredential(k.credentialName(item.Key)); err == nil {
+		// Only the metadata is wanted, not the old secret read with it.
+		zeroBytes(existing.CredentialBlob)
+		md := wincredMetadata(item.Key, &existing.Credential)
+		if !md.CreationTime.IsZero() {
+			created = md.CreationTime
+		}
+		fields = md.Fields
+	}
+
 	cred := wincred.NewGenericCredential(k.credentialName(item.Key))
 	cred.CredentialBlob = item.Data
-	return cred.Write()
+	cred.Comment = item.Description
+	cred.Attributes = wincredAttributes(&item, created, fields)
+	return wincredError(cred.Write())
+}
+
+// Credential attribute keywords for the metadata wincred has no field for.
+// Credential Manager maintains the last written time itself.
+const (
+	wincredAttrLabel       = "keyring:label"
+	wincredAttrCreated     = "keyring:created"
+	wincredAttrExpires     = "keyring:expires"
+	wincredAttrFieldPrefix = "keyring:field:"
+	wincredAttrPrefix      = "keyring:attr:"
+)
+
+// wincredAttributes returns the credential attributes holding an item's
+// metadata and attributes. Credential Manager allows 64 attributes of 256
+// bytes each.
+func wincredAttributes(item *Item, created time.Time, fields map[string]string) []wincred.CredentialAttribute {
+	attributes := []wincred.CredentialAttribute{
+		{Keyword: wincredAttrCreated, Value: []byte(created.UTC().Format(time.RFC3339Nano))},
+	}
+	if item.Label != "" {
+		attributes = append(attributes, wincred.CredentialAttribute{Keyword: wincredAttrLabel, Value: []byte(item.Label)})
+	}
+	if !item.Expires.IsZero() {
+		attributes = append(attributes, wincred.CredentialAttribute{
+			Keyword: wincredAttrExpires,
+			Value:   []byte(item.Expires.UTC().Format(time.RFC3339Nano)),
+		})
+	}
+	for name, value := range fields {
+		attributes = append(attributes, wincred.CredentialAttribute{Keyword: wincredAttrFieldPrefix + name, Value: []byte(value)})
+	}
+	for name, value := range item.Attributes {
+		attributes = append(attributes, wincred.CredentialAttribute{Keyword: wincredAttrPrefix + name, Value: []byte(value)})
+	}
+	return attributes
+}
+
+// wincredMetadata reads an item's metadata back from its credential.
+func wincredMetadata(key string, cred *wincred.Credential) Metadata {
+	md := Metadata{
+		Item: &Item{
+			Key:         key,
+			Description: cred.Comment,
+		},
+		ModificationTime: cred.LastWritten,
+	}
+	fields, attrs := map[string]string{}, map[string]string{}
+	for _, attr := range cred.Attributes {
+		switch {
+		case attr.Keyword == wincredAttrLabel:
+			md.Label = string(attr.Value)
+		case attr.Keyword == wincredAttrCreated:
+			md.CreationTime, _ = time.Parse(time.RFC3339Nano, string(attr.Value))
+		case attr.Keyword == wincredAttrExpires:
+			md.Expires, _ = time.Parse(time.RFC3339Nano, string(attr.Value))
+		case strings.HasPrefix(attr.Keyword, wincredAttrFieldPrefix):
+			fields[strings.TrimPrefix(attr.Keyword, wincredAttrFieldPrefix)] = string(attr.Value)
+		case strings.HasPrefix(attr.Keyword, wincredAttrPrefix):
+			attrs[strings.TrimPrefix(attr.Keyword, wincredAttrPrefix)] = string(attr.Value)
+		}
+	}
+	if len(fields) > 0 {
+		md.Fields = fields
+	}
+	if len(attrs) > 0 {
+		md.Attributes = attrs
+	}
+	return md
 }
 
 func (k *windowsKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *windowsKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	cred, err := wincred.GetGenericCredential(k.credentialName(key))
 	if err != nil {
 		if err == errElementNotFound {
 			return ErrKeyNotFound
 		}
-		return err
+		return wincredError(err)
 	}
-	return cred.Delete()
+	return wincredError(cred.Delete())
 }
 
 func (k *windowsKeyring) Keys() ([]string, error) {
+	return k.KeysContext(context.Background())
+}
+
+func (k *windowsKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	results := []string{}
 
 	if creds, err := wincred.List(); err == nil {
@@ -93,6 +276,19 @@ func (k *windowsKeyring) Keys() ([]string, error) {
 	return results, nil
 }
 
+// wincredError classifies a Credential Manager failure so that it matches the
+// package's sentinel errors.
+func wincredError(err error) error {
+	switch {
+	case errors.Is(err, errAccessDenied):
+		return classify(ErrAccessDenied, err)
+	case errors.Is(err, errNoSuchLogonSession):
+		return classify(ErrBackendUnavailable, err)
+	default:
+		return err
+	}
+}
+
 func (k *windowsKeyring) credentialName(key string) string {
 	return k.prefix + ":" + k.name + ":" + key
 }
@@ -4,6 +4,7 @@
 package keyring
 
 import (
+	"context"
 	"errors"
 
 	winhelloimpl "github.com/byteness/keyring/winhello" //nolint:depguard
@@ -11,12 +12,13 @@ import (
 
 type winHelloKeyring struct {
 	backend *winhelloimpl.Backend
+	log     callLogger
 }
 
 func newWinHelloKeyring(serviceName string) (*winHelloKeyring, error) {
 	backend, err := winhelloimpl.New(serviceName)
 	if err != nil {
-		return nil, err
+		return nil, winHelloError(err)
 	}
 
 	return &winHelloKeyring{
@@ -25,42 +27,173 @@ func newWinHelloKeyring(serviceName string) (*winHelloKeyring, error) {
 }
 
 func init() {
+	backendCapabilities[WinHelloBackend] = Capabilities{
+		// Metadata is kept in plaintext credentials beside the encrypted
+		// ones, so reading it does not need Windows Hello.
+		Metadata:          MetadataSupported,
+		Label:             true,
+		Description:       true,
+		Interactive:       true,
+		PlaintextMetadata: true,
+	}
+
 	supportedBackends[WinHelloBackend] = opener(func(cfg Config) (Keyring, error) {
-		return newWinHelloKeyring(cfg.ServiceName)
+		k, err := newWinHelloKeyring(cfg.ServiceName)
+		if err != nil {
+			return nil, err
+		}
+		k.log = newCallLogger(cfg, WinHelloBackend)
+		return k, nil
 	})
 }
 
 func (k *winHelloKeyring) Get(key string) (Item, error) {
+	return k.GetContext(context.Background(), key)
+}
+
+// GetContext returns the item for key. The Windows Hello prompt cannot be
+// interrupted, so the context is checked before and after the backend call.
+func (k *winHelloKeyring) GetContext(ctx context.Context, key string) (_ Item, err error) {
+	defer k.log.op(ctx, OpGet, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
 	data, err := k.backend.Get(key)
 	if err != nil {
 		if errors.Is(err, winhelloimpl.ErrKeyNotFound) {
 			return Item{}, ErrKeyNotFound
 		}
+		return Item{}, winHelloError(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return Item{}, err
+	}
+
+	md, err := k.sidecar().metadata(ctx, key)
+	if err != nil {
 		return Item{}, err
 	}
 
-	return Item{Key: key, Data: data}, nil
+	return unexpired(Item{
+		Key:         key,
+		Data:        data,
+		Label:       md.Label,
+		Description: md.Description,
+		Attributes:  md.Attributes,
+		Expires:     md.Expires,
+	})
+}
+
+func (k *winHelloKeyring) GetMetadata(key string) (Metadata, error) {
+	return k.GetMetadataContext(context.Background(), key)
+}
+
+func (k *winHelloKeyring) GetMetadataContext(ctx context.Context, key string) (_ Metadata, err error) {
+	defer k.log.op(ctx, OpGetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return Metadata{}, err
+	}
+	if err := k.exists(key); err != nil {
+		return Metadata{}, err
+	}
+	return k.sidecar().metadata(ctx, key)
+}
+
+func (k *winHelloKeyring) SetMetadata(key string, fields map[string]string) error {
+	return k.SetMetadataContext(context.Background(), key, fields)
+}
+
+func (k *winHelloKeyring) SetMetadataContext(ctx context.Context, key string, fields map[string]string) (err error) {
+	defer k.log.op(ctx, OpSetMetadata, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := k.exists(key); err != nil {
+		return err
+	}
+	return k.sidecar().setFields(ctx, key, fields)
+}
+
+// exists returns ErrKeyNotFound if there is no item for key.
+func (k *winHelloKeyring) exists(key string) error {
+	ok, err := k.backend.Exists(key)
+	if err != nil {
+		return winHelloError(err)
+	} else if !ok {
+		return ErrKeyNotFound
+	}
+	return nil
 }
 
-func (k *winHelloKeyring) GetMetadata(_ string) (Metadata, error) {
-	return Metadata{}, ErrMetadataNotSupported
+// sidecar returns where the items' metadata is kept: plaintext wincred
+// credentials under a prefix of their own.
+func (k *winHelloKeyring) sidecar() metadataSidecar {
+	return metadataSidecar{store: &windowsKeyring{
+		name:   k.backend.ServiceName(),
+		prefix: "keyring-winhello-metadata",
+	}}
 }
 
 func (k *winHelloKeyring) Set(item Item) error {
-	return k.backend.Set(item.Key, item.Data)
+	return k.SetContext(context.Background(), item)
+}
+
+func (k *winHelloKeyring) SetContext(ctx context.Context, item Item) (err error) {
+	defer k.log.op(ctx, OpSet, item.Key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := k.backend.Set(item.Key, item.Data); err != nil {
+		return winHelloError(err)
+	}
+	return k.sidecar().itemSet(ctx, item)
 }
 
 func (k *winHelloKeyring) Remove(key string) error {
+	return k.RemoveContext(context.Background(), key)
+}
+
+func (k *winHelloKeyring) RemoveContext(ctx context.Context, key string) (err error) {
+	defer k.log.op(ctx, OpRemove, key)(&err)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if err := k.backend.Remove(key); err != nil {
 		if errors.Is(err, winhelloimpl.ErrKeyNotFound) {
 			return ErrKeyNotFound
 		}
-		return err
+		return winHelloError(err)
 	}
 
-	return nil
+	return k.sidecar().remove(ctx, key)
 }
 
 func (k *winHelloKeyring) Keys() ([]string, error) {
-	return k.backend.Keys()
+	return k.KeysContext(context.Background())
+}
+
+func (k *winHelloKeyring) KeysContext(ctx context.Context) (_ []string, err error) {
+	defer k.log.op(ctx, OpKeys, "")(&err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	keys, err := k.backend.Keys()
+	return keys, winHelloError(err)
+}
+
+// winHelloError classifies a Windows Hello failure so that it matches the
+// package's sentinel errors.
+func winHelloError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case winhelloimpl.IsUserCancelled(err):
+		return classify(ErrUserCancelled, err)
+	case winhelloimpl.IsSetupRequired(err):
+		return classify(ErrBackendUnavailable, err)
+	default:
+		return err
+	}
 }
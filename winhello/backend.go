@@ -80,6 +80,25 @@ func (b *Backend) Get(key string) ([]byte, error) {
 	return plaintext, nil
 }
 
+// Exists reports whether an item is stored under the given key. Unlike Get it
+// does not use the Windows Hello key, so it never prompts.
+func (b *Backend) Exists(key string) (bool, error) {
+	_, err := b.store.Read(key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("read winhello item %q: %w", key, err)
+	}
+
+	return true, nil
+}
+
+// ServiceName returns the service name items are stored under, which defaults
+// when New was given none.
+func (b *Backend) ServiceName() string {
+	return b.serviceName
+}
+
 // Set encrypts and stores data under the given key.
 func (b *Backend) Set(key string, data []byte) error {
 	wrapper, err := b.ensureWrapper()
@@ -76,3 +76,15 @@ func isWinHelloNCryptSetupRequired(err error) bool {
 	return errors.Is(err, errWinHelloNCryptNotSupported) ||
 		errors.Is(err, errWinHelloNCryptDeviceNotReady)
 }
+
+// IsUserCancelled reports whether err was caused by the user dismissing the
+// Windows Hello prompt.
+func IsUserCancelled(err error) bool {
+	return isWinHelloNCryptUserCancelled(err)
+}
+
+// IsSetupRequired reports whether err was caused by Windows Hello not being
+// set up or its key storage being unavailable.
+func IsSetupRequired(err error) bool {
+	return isWinHelloNCryptSetupRequired(err)
+}